@@ -0,0 +1,197 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/core/router"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 配置中被屏蔽的字段值
+const maskedValue = "******"
+
+// 字段名包含这些文本时会被屏蔽
+var secretFieldKeywords = []string{"password", "secret", "token", "credential", "privatekey"}
+
+// 路由信息
+type RouteInfo struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	HandlerName string `json:"handler_name"`
+}
+
+// 可以在运行时修改的日志配置, 为nil的字段不会修改
+type AdminLogConfig struct {
+	ReqLogLevelIsInfo             *bool  `json:"ReqLogLevelIsInfo"`
+	RspLogLevelIsInfo             *bool  `json:"RspLogLevelIsInfo"`
+	BindLogLevelIsInfo            *bool  `json:"BindLogLevelIsInfo"`
+	LogApiResultInDevelop         *bool  `json:"LogApiResultInDevelop"`
+	LogApiResultInProd            *bool  `json:"LogApiResultInProd"`
+	SendDetailedErrorInProduction *bool  `json:"SendDetailedErrorInProduction"`
+	AlwaysLogHeaders              *bool  `json:"AlwaysLogHeaders"`
+	AlwaysLogBody                 *bool  `json:"AlwaysLogBody"`
+	LogApiResultMaxSize           *int   `json:"LogApiResultMaxSize"`
+	LogBodyMaxSize                *int64 `json:"LogBodyMaxSize"`
+}
+
+// 从配置中获取日志配置
+func makeAdminLogConfig(conf *config.Config) *AdminLogConfig {
+	return &AdminLogConfig{
+		ReqLogLevelIsInfo:             &conf.ReqLogLevelIsInfo,
+		RspLogLevelIsInfo:             &conf.RspLogLevelIsInfo,
+		BindLogLevelIsInfo:            &conf.BindLogLevelIsInfo,
+		LogApiResultInDevelop:         &conf.LogApiResultInDevelop,
+		LogApiResultInProd:            &conf.LogApiResultInProd,
+		SendDetailedErrorInProduction: &conf.SendDetailedErrorInProduction,
+		AlwaysLogHeaders:              &conf.AlwaysLogHeaders,
+		AlwaysLogBody:                 &conf.AlwaysLogBody,
+		LogApiResultMaxSize:           &conf.LogApiResultMaxSize,
+		LogBodyMaxSize:                &conf.LogBodyMaxSize,
+	}
+}
+
+// 将日志配置应用到配置中
+func (a *AdminLogConfig) apply(conf *config.Config) {
+	setBool := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setBool(&conf.ReqLogLevelIsInfo, a.ReqLogLevelIsInfo)
+	setBool(&conf.RspLogLevelIsInfo, a.RspLogLevelIsInfo)
+	setBool(&conf.BindLogLevelIsInfo, a.BindLogLevelIsInfo)
+	setBool(&conf.LogApiResultInDevelop, a.LogApiResultInDevelop)
+	setBool(&conf.LogApiResultInProd, a.LogApiResultInProd)
+	setBool(&conf.SendDetailedErrorInProduction, a.SendDetailedErrorInProduction)
+	setBool(&conf.AlwaysLogHeaders, a.AlwaysLogHeaders)
+	setBool(&conf.AlwaysLogBody, a.AlwaysLogBody)
+	if a.LogApiResultMaxSize != nil && *a.LogApiResultMaxSize > 0 {
+		conf.LogApiResultMaxSize = *a.LogApiResultMaxSize
+	}
+	if a.LogBodyMaxSize != nil && *a.LogBodyMaxSize > 0 {
+		conf.LogBodyMaxSize = *a.LogBodyMaxSize
+	}
+}
+
+// 获取屏蔽了敏感字段的配置副本
+//
+// 字段名包含 password, secret, token 等文本或者带有 secret:"true" 标签的字段会被屏蔽, 非文本类型的敏感字段会被设为零值
+func maskConfig(conf *config.Config) *config.Config {
+	masked := *conf
+	val := reflect.ValueOf(&masked).Elem()
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || !isSecretField(field) || val.Field(i).IsZero() {
+			continue
+		}
+		if field.Type.Kind() == reflect.String {
+			val.Field(i).SetString(maskedValue)
+		} else {
+			val.Field(i).Set(reflect.Zero(field.Type))
+		}
+	}
+	return &masked
+}
+
+func isSecretField(field reflect.StructField) bool {
+	if field.Tag.Get("secret") == "true" {
+		return true
+	}
+	name := strings.ToLower(field.Name)
+	for _, keyword := range secretFieldKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取路由表, 按路径和方法排序
+func (a *ApiService) RouteInfos() []RouteInfo {
+	routes := a.GetRoutes()
+	infos := make([]RouteInfo, 0, len(routes))
+	for _, route := range routes {
+		infos = append(infos, RouteInfo{
+			Method:      route.Method,
+			Path:        route.Path,
+			HandlerName: routeHandlerName(route),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Path == infos[j].Path {
+			return infos[i].Method < infos[j].Method
+		}
+		return infos[i].Path < infos[j].Path
+	})
+	return infos
+}
+
+// 获取路由的处理程序名, 优先返回经过 Wrap 包装的处理程序的原始名称
+func routeHandlerName(route *router.Route) string {
+	var middlewareName string
+	for i := len(route.Handlers) - 1; i >= 0; i-- {
+		name, isMiddleware, ok := GetWrappedHandlerName(route.Handlers[i])
+		if !ok {
+			continue
+		}
+		if !isMiddleware {
+			return name
+		}
+		if middlewareName == "" {
+			middlewareName = name
+		}
+	}
+	if middlewareName != "" {
+		return middlewareName
+	}
+	return route.MainHandlerName
+}
+
+// 注册管理接口
+func (a *ApiService) registryAdminRouter(party Party) {
+	party.Get("/routes", Wrap(func(ctx *Context) interface{} {
+		return a.RouteInfos()
+	}))
+	party.Get("/config", Wrap(func(ctx *Context) interface{} {
+		return maskConfig(a.dynConf.Load())
+	}))
+	party.Get("/log", Wrap(func(ctx *Context) interface{} {
+		return makeAdminLogConfig(a.dynConf.Load())
+	}))
+	party.Post("/log", Wrap(func(ctx *Context, req *AdminLogConfig) interface{} {
+		conf := a.dynConf.Update(req.apply)
+		a.app.Warn("api日志配置已修改", zap.Any("config", makeAdminLogConfig(conf)))
+		return makeAdminLogConfig(conf)
+	}))
+
+	// pprof
+	party.Get("/debug/pprof", iris.FromStd(pprof.Index))
+	party.Get("/debug/pprof/cmdline", iris.FromStd(pprof.Cmdline))
+	party.Get("/debug/pprof/profile", iris.FromStd(pprof.Profile))
+	party.Any("/debug/pprof/symbol", iris.FromStd(pprof.Symbol))
+	party.Get("/debug/pprof/trace", iris.FromStd(pprof.Trace))
+	party.Get("/debug/pprof/{name:string}", func(ctx iris.Context) {
+		pprof.Handler(ctx.Params().Get("name")).ServeHTTP(ctx.ResponseWriter(), ctx.Request())
+	})
+}
+
+// 将 http.Handler 挂载到 party 的 path 和它的子路径上, 请求路径会去掉 party 和 path 的前缀后交给 h
+//
+// 挂载到 RegistryAdminRouter 的路由上时会使用管理接口的鉴权
+func MountStd(party Party, path string, h http.Handler) {
+	path = strings.TrimRight(path, "/")
+	prefix := strings.TrimRight(party.GetRelPath(), "/") + path
+	handler := iris.FromStd(http.StripPrefix(prefix, h))
+	if path != "" {
+		party.Any(path, handler)
+	}
+	party.Any(path+"/{p:path}", handler)
+}
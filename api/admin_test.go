@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zly-app/zapp/core"
+
+	"github.com/zly-app/service/api/config"
+)
+
+func TestMountStdOnAdminRouter(t *testing.T) {
+	conf := config.NewConfig()
+	conf.EnableAdmin = true
+	conf.Check()
+
+	s := NewApiService(getTestApp(), conf, WithAdminAuth(func(ctx *Context) error {
+		if ctx.GetHeader("X-Admin-Token") != "token" {
+			return AuthorizationError
+		}
+		return nil
+	}))
+	s.RegistryAdminRouter(func(_ core.IComponent, router Party) {
+		MountStd(router, "/cron/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.URL.EscapedPath()))
+		}))
+	})
+	if err := s.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 使用管理接口的鉴权
+	if rsp := serveTest(t, s, http.MethodGet, "/_admin/cron/tasks", nil); rsp.ErrCode != AuthorizationError.Code {
+		t.Fatalf("没有鉴权的请求应该返回 AuthorizationError, 实际为 %+v", rsp)
+	}
+
+	// 请求路径会去掉前缀, 保留转义
+	for url, path := range map[string]string{
+		"/_admin/cron":                     "",
+		"/_admin/cron/tasks":               "/tasks",
+		"/_admin/cron/tasks/a%2Fb/trigger": "/tasks/a%2Fb/trigger",
+	} {
+		r := httptest.NewRequest(http.MethodPost, url, nil)
+		r.Header.Set("X-Admin-Token", "token")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Body.String() != path {
+			t.Fatalf("%s 的请求路径应该为 %q, 实际为 %q", url, path, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/core/host"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 请求body超出限制时读取返回的错误
+var errBodyTooLarge = errors.New("http: request body too large")
+
+// 标记请求body超出了路由的限制
+const bodyTooLargeFieldKey = "_body_too_large"
+
+// 请求body超出限制的次数
+var bodyLimitViolations int64
+
+// 获取请求body超出限制的次数
+func BodyLimitViolations() int64 {
+	return atomic.LoadInt64(&bodyLimitViolations)
+}
+
+// 限制路由的请求body大小, 超出时返回 RequestBodyTooLarge, 限制不会超过 PostMaxMemory
+//
+//	router.Post("/upload/avatar", api.BodyLimit(1<<20), api.Wrap(UploadAvatar))
+func BodyLimit(maxSize int64) iris.Handler {
+	return WrapMiddleware(func(ctx *Context) error {
+		limit := maxSize
+		if limit < 1 || limit > ctx.conf.PostMaxMemory {
+			limit = ctx.conf.PostMaxMemory
+		}
+
+		// 声明的长度已经超出时不需要读取body
+		if ctx.GetContentLength() > limit {
+			onBodyTooLarge(ctx, limit)
+			return RequestBodyTooLarge
+		}
+
+		req := ctx.Request()
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = &limitedBody{
+				ReadCloser: req.Body,
+				remaining:  limit,
+				onExceed:   func() { onBodyTooLarge(ctx, limit) },
+			}
+		}
+		return nil
+	})
+}
+
+func onBodyTooLarge(ctx *Context, limit int64) {
+	atomic.AddInt64(&bodyLimitViolations, 1)
+	ctx.Values().Set(bodyTooLargeFieldKey, true)
+	ctx.Warn("api.body_too_large",
+		zap.Int64("limit", limit),
+		zap.Int64("content_length", ctx.GetContentLength()),
+		zap.Int64("violations", BodyLimitViolations()),
+	)
+}
+
+// 检查请求body是否超出了路由的限制
+func isBodyTooLarge(ctx *Context) bool {
+	return ctx.Values().GetBoolDefault(bodyTooLargeFieldKey, false)
+}
+
+// 限制读取大小的body, 超出后会一直返回 errBodyTooLarge
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	onExceed  func()
+	err       error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	// 多读一个字节用于判断是否超出
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+
+	n = int(b.remaining)
+	b.remaining = 0
+	b.err = errBodyTooLarge
+	b.onExceed()
+	return n, b.err
+}
+
+// 设置 http.Server 的超时和请求头限制
+func applyServerLimits(srv *http.Server, conf *config.Config) {
+	srv.ReadTimeout = millisecond(conf.ReadTimeout)
+	srv.ReadHeaderTimeout = millisecond(conf.ReadHeaderTimeout)
+	srv.WriteTimeout = millisecond(conf.WriteTimeout)
+	srv.IdleTimeout = millisecond(conf.IdleTimeout)
+	srv.MaxHeaderBytes = conf.MaxHeaderBytes
+}
+
+// 用于 iris.Addr 的主机配置
+func serverLimitsConfigurator(conf *config.Config) host.Configurator {
+	return func(su *host.Supervisor) {
+		applyServerLimits(su.Server, conf)
+	}
+}
+
+// 将毫秒转为 time.Duration, 负数表示不限制
+func millisecond(ms int) time.Duration {
+	if ms < 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
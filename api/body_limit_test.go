@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zly-app/service/api/config"
+)
+
+type bodyLimitTestReq struct {
+	Data string `json:"data"`
+}
+
+// 生成指定大小的json body
+func makeBodyLimitTestBody(size int) string {
+	return `{"data":"` + strings.Repeat("x", size-11) + `"}`
+}
+
+func TestBodyLimit(t *testing.T) {
+	conf := config.NewConfig()
+	conf.PostMaxMemory = 64
+	conf.Check()
+
+	s := NewApiService(getTestApp(), conf)
+	handler := Wrap(func(ctx *Context, req *bodyLimitTestReq) interface{} {
+		return len(req.Data)
+	})
+	s.Post("/route", BodyLimit(32), handler)
+	s.Post("/global", BodyLimit(0), handler)
+	s.Post("/over_global", BodyLimit(1<<20), handler)
+	if err := s.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		size int
+		code int
+	}{
+		{name: "route limit", url: "/route", size: 32, code: OK.Code},
+		{name: "route oversized", url: "/route", size: 33, code: RequestBodyTooLarge.Code},
+		{name: "route limit beats global", url: "/route", size: 64, code: RequestBodyTooLarge.Code},
+		{name: "global limit", url: "/global", size: 64, code: OK.Code},
+		{name: "global oversized", url: "/global", size: 65, code: RequestBodyTooLarge.Code},
+		{name: "route limit capped by global", url: "/over_global", size: 65, code: RequestBodyTooLarge.Code},
+	}
+	for _, test := range tests {
+		// 分别测试声明了长度和没有声明长度的body
+		for _, chunked := range []bool{false, true} {
+			violations := BodyLimitViolations()
+			r := httptest.NewRequest(http.MethodPost, test.url, strings.NewReader(makeBodyLimitTestBody(test.size)))
+			r.Header.Set("Content-Type", "application/json")
+			if chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+
+			var rsp Response
+			if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+				t.Fatalf("%s: 解析响应失败: %v, %s", test.name, err, w.Body.String())
+			}
+			if rsp.ErrCode != test.code {
+				t.Fatalf("%s(chunked=%v): 错误码应该为%d, 实际为%d, %s", test.name, chunked, test.code, rsp.ErrCode, rsp.ErrMsg)
+			}
+			if test.code == OK.Code && rsp.Data != float64(test.size-11) {
+				t.Fatalf("%s: 读取的数据错误: %v", test.name, rsp.Data)
+			}
+			if exceeded := BodyLimitViolations() > violations; exceeded != (test.code == RequestBodyTooLarge.Code) {
+				t.Fatalf("%s: 超出限制的次数错误", test.name)
+			}
+		}
+	}
+}
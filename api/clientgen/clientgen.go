@@ -0,0 +1,320 @@
+package clientgen
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/zly-app/service/api"
+)
+
+const (
+	// 默认包名
+	defaultPackage = "client"
+	// 默认客户端类型名
+	defaultClientName = "Client"
+)
+
+// 会生成方法的请求方法, 其它请求方法如 OPTIONS 会被忽略
+var generatedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// 匿名函数名, 如 func1
+var anonymousFuncRegexp = regexp.MustCompile(`^func\d+$`)
+
+// 路径参数, 如 {id} {id:uint64} {p:path}
+var pathParamRegexp = regexp.MustCompile(`\{([^:{}\s]+)(?::([a-zA-Z0-9_]+))?[^{}]*\}`)
+
+// 生成配置
+type Config struct {
+	Package    string   // 包名, 默认为 client
+	ClientName string   // 客户端类型名, 默认为 Client
+	Exclude    []string // 不生成的路径前缀, 如 /admin
+}
+
+func (conf *Config) check() {
+	if conf.Package == "" {
+		conf.Package = defaultPackage
+	}
+	if conf.ClientName == "" {
+		conf.ClientName = defaultClientName
+	}
+}
+
+// 生成的方法
+type method struct {
+	Name     string   // 方法名
+	Method   string   // 请求方法
+	Path     string   // 原始路由路径
+	Handler  string   // 处理程序名
+	Params   []string // 路径参数名
+	PathExpr string   // 构建请求路径的表达式
+	Req      string   // 请求类型, 为空表示没有请求参数
+	Rsp      string   // 响应类型, 为空表示只返回error
+}
+
+// 根据路由生成客户端代码, 每个路由生成一个方法, 请求和响应类型会生成对应的结构体
+//
+// GET 请求的参数会编码为url参数, 参数名为 url 标签或字段名, 其它请求的参数会编码为json
+func Generate(routes []api.RouteSpec, conf Config) ([]byte, error) {
+	conf.check()
+
+	g := newTypeGen()
+	g.reserve(conf.ClientName, "Option")
+
+	usedNames := make(map[string]bool)
+	methods := make([]*method, 0, len(routes))
+	for _, route := range routes {
+		if !inStrings(generatedMethods, route.Method) || hasPrefix(route.Path, conf.Exclude) {
+			continue
+		}
+
+		m := &method{
+			Method:  route.Method,
+			Path:    route.Path,
+			Handler: route.HandlerName,
+		}
+		m.Name = uniqueMethodName(usedNames, route)
+		m.Params, m.PathExpr = buildPath(route.Path)
+		if route.Req != nil {
+			m.Req = g.expr(route.Req)
+		}
+		if route.Rsp != nil {
+			m.Rsp = g.rspExpr(route.Rsp)
+		}
+		methods = append(methods, m)
+	}
+
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "io", "io/ioutil", "net/http", "net/url", "reflect", "strconv", "strings", "time"}
+	for pkg := range g.imports {
+		if !inStrings(imports, pkg) {
+			imports = append(imports, pkg)
+		}
+	}
+	sort.Strings(imports)
+
+	var buf bytes.Buffer
+	err := clientTemplate.Execute(&buf, map[string]interface{}{
+		"Package": conf.Package,
+		"Client":  conf.ClientName,
+		"Imports": imports,
+		"Types":   g.defs,
+		"Methods": methods,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成客户端失败: %v", err)
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化客户端代码失败: %v", err)
+	}
+	return code, nil
+}
+
+// 生成客户端代码并写入文件
+func GenerateFile(path string, routes []api.RouteSpec, conf Config) error {
+	code, err := Generate(routes, conf)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, code, 0644)
+}
+
+// 命令行入口, 用于在 go generate 中生成客户端
+//
+//	// cmd/gen-client/main.go
+//	func main() {
+//		clientgen.Main(api.CollectRouteSpecs(user.RegisterRouter))
+//	}
+//
+//	//go:generate go run ./cmd/gen-client -o ./client/client.go -pkg client
+func Main(routes []api.RouteSpec) {
+	var conf Config
+	output := flag.String("o", "client.go", "输出文件")
+	flag.StringVar(&conf.Package, "pkg", defaultPackage, "包名")
+	flag.StringVar(&conf.ClientName, "client", defaultClientName, "客户端类型名")
+	exclude := flag.String("exclude", "", "不生成的路径前缀, 多个用英文逗号分隔")
+	flag.Parse()
+
+	for _, prefix := range strings.Split(*exclude, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			conf.Exclude = append(conf.Exclude, prefix)
+		}
+	}
+
+	if err := GenerateFile(*output, routes, conf); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// 生成方法名, 优先使用处理程序的函数名, 匿名函数会根据请求方法和路径生成
+func uniqueMethodName(used map[string]bool, route api.RouteSpec) string {
+	name := handlerMethodName(route.HandlerName)
+	if name == "" || used[name] {
+		name = routeMethodName(route.Method, route.Path)
+	}
+	base := name
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	used[name] = true
+	return name
+}
+
+// 根据处理程序名生成方法名, 如 github.com/a/b.(*UserService).GetUser-fm 生成 GetUser
+func handlerMethodName(handlerName string) string {
+	name := handlerName[strings.LastIndex(handlerName, "/")+1:]
+	name = name[strings.LastIndex(name, ".")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	if name == "" || anonymousFuncRegexp.MatchString(name) {
+		return ""
+	}
+	return exportName(name)
+}
+
+// 根据请求方法和路径生成方法名, 如 GET /users/{id} 生成 GetUsersById
+func routeMethodName(httpMethod, path string) string {
+	var sb strings.Builder
+	sb.WriteString(exportName(strings.ToLower(httpMethod)))
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		if name, _, ok := parsePathParam(segment); ok {
+			sb.WriteString("By")
+			sb.WriteString(exportName(name))
+			continue
+		}
+		sb.WriteString(exportName(segment))
+	}
+	return sb.String()
+}
+
+// 解析路径参数, 支持 {id} {id:uint64} {p:path} 和 iris 转换后的 :id *p, wildcard 表示参数可以包含斜杠
+func parsePathParam(segment string) (name string, wildcard bool, ok bool) {
+	if m := pathParamRegexp.FindStringSubmatch(segment); m != nil && m[0] == segment {
+		return m[1], m[2] == "path", true
+	}
+	if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+		return segment[1:], segment[0] == '*', true
+	}
+	return "", false, false
+}
+
+// 解析路径参数, 返回参数名和构建请求路径的表达式
+func buildPath(path string) (params []string, expr string) {
+	var parts []string
+	var literal strings.Builder
+	for i, segment := range strings.Split(path, "/") {
+		if i > 0 {
+			literal.WriteByte('/')
+		}
+		name, wildcard, ok := parsePathParam(segment)
+		if !ok {
+			literal.WriteString(segment)
+			continue
+		}
+
+		if literal.Len() > 0 {
+			parts = append(parts, fmt.Sprintf("%q", literal.String()))
+			literal.Reset()
+		}
+		name = paramName(name, params)
+		params = append(params, name)
+		if wildcard { // 通配符参数可以包含斜杠
+			parts = append(parts, name)
+		} else {
+			parts = append(parts, "url.PathEscape("+name+")")
+		}
+	}
+	if literal.Len() > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", literal.String()))
+	}
+	return params, strings.Join(parts, " + ")
+}
+
+// 方法中已使用的变量名和go关键字不能作为参数名
+var reservedParamNames = []string{
+	"c", "ctx", "req", "rsp", "err", "path", "url", "api",
+	"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func",
+	"go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type", "var",
+}
+
+func paramName(name string, used []string) string {
+	name = identifier(name)
+	if name == "" {
+		name = "param"
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	name = string(runes)
+	for inStrings(reservedParamNames, name) || inStrings(used, name) {
+		name += "_"
+	}
+	return name
+}
+
+// 转为导出的标识符
+func exportName(name string) string {
+	name = identifier(name)
+	if name == "" {
+		return ""
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// 转为驼峰格式的标识符, 非字母和数字的字符会被去掉, 后面的字母转为大写
+func identifier(name string) string {
+	var sb strings.Builder
+	upper := false
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = sb.Len() > 0
+			continue
+		}
+		if sb.Len() == 0 && unicode.IsDigit(r) {
+			sb.WriteByte('_')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func inStrings(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var clientTemplate = template.Must(template.New("client").Parse(clientTemplateText))
@@ -0,0 +1,80 @@
+package clientgen
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zly-app/service/api"
+)
+
+type testUser struct {
+	ID      int64       `json:"id" bind:"required"`
+	Created time.Time   `json:"created"`
+	Friends []*testUser `json:"friends"`
+}
+
+type testListReq struct {
+	Page   int    `json:"page" url:"page" default:"1"`
+	Secret string `json:"-"`
+}
+
+func TestGenerate(t *testing.T) {
+	routes := []api.RouteSpec{
+		{RouteInfo: api.RouteInfo{Method: "GET", Path: "/users", HandlerName: "main.ListUsers"}, Req: reflect.TypeOf(testListReq{}), Rsp: reflect.TypeOf([]testUser{})},
+		{RouteInfo: api.RouteInfo{Method: "GET", Path: "/users/:id", HandlerName: "main.(*Service).GetUser-fm"}, Rsp: reflect.TypeOf(&testUser{})},
+		{RouteInfo: api.RouteInfo{Method: "DELETE", Path: "/users/{id:uint64}", HandlerName: "main.main.func1"}},
+		{RouteInfo: api.RouteInfo{Method: "OPTIONS", Path: "/users", HandlerName: "main.ListUsers"}},
+		{RouteInfo: api.RouteInfo{Method: "GET", Path: "/admin/routes", HandlerName: "main.Routes"}},
+	}
+	code, err := Generate(routes, Config{Package: "user", Exclude: []string{"/admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = parser.ParseFile(token.NewFileSet(), "client.go", code, 0); err != nil {
+		t.Fatalf("生成的代码无法解析: %v\n%s", err, code)
+	}
+
+	src := string(code)
+	for _, want := range []string{
+		"package user",
+		"func (c *Client) ListUsers(ctx context.Context, req *TestListReq) ([]TestUser, error)",
+		"func (c *Client) GetUser(ctx context.Context, id string) (*TestUser, error)",
+		`path := "/users/" + url.PathEscape(id)`,
+		"func (c *Client) DeleteUsersById(ctx context.Context, id string) error",
+		"Friends []*TestUser",
+		"Created time.Time",
+		"`json:\"page,omitempty\" url:\"page,omitempty\"`",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("生成的代码缺少 %s", want)
+		}
+	}
+	for _, unwanted := range []string{"Secret", "bind:", "Routes(", "func (c *Client) ListUsers2"} {
+		if strings.Contains(src, unwanted) {
+			t.Errorf("生成的代码不应该包含 %s", unwanted)
+		}
+	}
+}
+
+func TestBuildPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		params []string
+		expr   string
+	}{
+		{"/", nil, `"/"`},
+		{"/users/{id:uint64}/posts", []string{"id"}, `"/users/" + url.PathEscape(id) + "/posts"`},
+		{"/files/{p:path}", []string{"p"}, `"/files/" + p`},
+		{"/a/:type/*file", []string{"type_", "file"}, `"/a/" + url.PathEscape(type_) + "/" + file`},
+	}
+	for _, tt := range tests {
+		params, expr := buildPath(tt.path)
+		if !reflect.DeepEqual(params, tt.params) || expr != tt.expr {
+			t.Errorf("buildPath(%s) = %v, %s", tt.path, params, expr)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package clientgen
+
+const clientTemplateText = `// Code generated by clientgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{range .Imports}}	"{{.}}"
+{{end}}
+	"github.com/zly-app/service/api"
+)
+
+// 客户端选项
+type Option func(c *{{.Client}})
+
+// 设置http客户端, 默认为 http.DefaultClient
+func WithHttpClient(client *http.Client) Option {
+	return func(c *{{.Client}}) {
+		c.httpClient = client
+	}
+}
+
+// 设置每个请求都会附加的header
+func WithHeader(key, value string) Option {
+	return func(c *{{.Client}}) {
+		c.header.Add(key, value)
+	}
+}
+
+// 设置header函数, 每次发送请求前都会调用, 可以用于注入鉴权信息, 链路追踪等
+func WithHeaderFunc(fn func(ctx context.Context, header http.Header)) Option {
+	return func(c *{{.Client}}) {
+		c.headerFunc = fn
+	}
+}
+
+// 设置重试次数和重试间隔, 请求失败或服务端返回5xx状态码时会重试
+//
+// 注意非幂等的接口也会重试
+func WithRetry(retryCount int, interval time.Duration) Option {
+	return func(c *{{.Client}}) {
+		c.retryCount = retryCount
+		c.retryInterval = interval
+	}
+}
+
+// api客户端, 服务端返回的错误会解析为 *api.Error
+type {{.Client}} struct {
+	baseURL       string
+	httpClient    *http.Client
+	header        http.Header
+	headerFunc    func(ctx context.Context, header http.Header)
+	retryCount    int
+	retryInterval time.Duration
+}
+
+// 创建客户端, baseURL 为服务地址, 如 http://127.0.0.1:8080
+func New{{.Client}}(baseURL string, opts ...Option) *{{.Client}} {
+	c := &{{.Client}}{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+{{range .Types}}
+{{.}}
+{{end}}
+{{- range .Methods}}
+// {{.Name}} {{.Method}} {{.Path}}
+//
+// 处理程序: {{.Handler}}
+func (c *{{$.Client}}) {{.Name}}(ctx context.Context{{range .Params}}, {{.}} string{{end}}{{if .Req}}, req *{{.Req}}{{end}}) {{if .Rsp}}({{.Rsp}}, error){{else}}error{{end}} {
+	path := {{.PathExpr}}
+{{- if .Rsp}}
+	var rsp {{.Rsp}}
+	err := c.do(ctx, "{{.Method}}", path, {{if .Req}}req{{else}}nil{{end}}, &rsp)
+	return rsp, err
+{{- else}}
+	return c.do(ctx, "{{.Method}}", path, {{if .Req}}req{{else}}nil{{end}}, nil)
+{{- end}}
+}
+{{end}}
+// 发送请求并解析响应到 rsp 中
+func (c *{{.Client}}) do(ctx context.Context, method, path string, req interface{}, rsp interface{}) error {
+	u := c.baseURL + path
+	var body []byte
+	if req != nil && !reflect.ValueOf(req).IsNil() {
+		if method == http.MethodGet {
+			if query := encodeQuery(req); len(query) > 0 {
+				u += "?" + query.Encode()
+			}
+		} else {
+			var err error
+			body, err = json.Marshal(req)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = c.send(ctx, method, u, body, rsp)
+		if !retry || attempt >= c.retryCount {
+			return err
+		}
+
+		timer := time.NewTimer(c.retryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// 发送一次请求, retry 表示是否可以重试
+func (c *{{.Client}}) send(ctx context.Context, method, u string, body []byte, rsp interface{}) (retry bool, err error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return false, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for k, values := range c.header {
+		for _, v := range values {
+			httpReq.Header.Add(k, v)
+		}
+	}
+	if c.headerFunc != nil {
+		c.headerFunc(ctx, httpReq.Header)
+	}
+
+	httpRsp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer httpRsp.Body.Close()
+
+	data, err := ioutil.ReadAll(httpRsp.Body)
+	if err != nil {
+		return true, err
+	}
+	if httpRsp.StatusCode >= http.StatusInternalServerError {
+		return true, fmt.Errorf("%s %s 失败, 状态码: %d", method, u, httpRsp.StatusCode)
+	}
+
+	// 返回bytes的处理程序会直接写入数据
+	if !strings.HasPrefix(httpRsp.Header.Get("Content-Type"), "application/json") {
+		switch raw := rsp.(type) {
+		case *[]byte:
+			*raw = data
+			return false, nil
+		case *json.RawMessage:
+			*raw = data
+			return false, nil
+		}
+	}
+	return false, decodeResponse(httpRsp.StatusCode, data, rsp)
+}
+
+// 解析响应, err_code 不为0时返回 *api.Error
+func decodeResponse(statusCode int, data []byte, rsp interface{}) error {
+	var result struct {
+		ErrCode int             ` + "`json:\"err_code\"`" + `
+		ErrMsg  string          ` + "`json:\"err_msg\"`" + `
+		Data    json.RawMessage ` + "`json:\"data\"`" + `
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		if statusCode != http.StatusOK {
+			return fmt.Errorf("请求失败, 状态码: %d", statusCode)
+		}
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if result.ErrCode != api.OK.Code {
+		return &api.Error{Code: result.ErrCode, Message: result.ErrMsg}
+	}
+	if rsp == nil || len(result.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(result.Data, rsp)
+}
+
+// 将请求编码为url参数, 参数名为 url 标签或字段名
+func encodeQuery(req interface{}) url.Values {
+	values := make(url.Values)
+	encodeQueryValue(values, "", reflect.ValueOf(req))
+	return values
+}
+
+func encodeQueryValue(values url.Values, name string, v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			values.Add(name, t.Format(time.RFC3339Nano))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			tags := strings.Split(field.Tag.Get("url"), ",")
+			fieldName := tags[0]
+			if fieldName == "-" {
+				continue
+			}
+			if len(tags) > 1 && tags[1] == "omitempty" && v.Field(i).IsZero() {
+				continue
+			}
+			if fieldName == "" {
+				if field.Anonymous { // 嵌入的结构体
+					encodeQueryValue(values, name, v.Field(i))
+					continue
+				}
+				fieldName = field.Name
+			}
+			if name != "" {
+				fieldName = name + "." + fieldName
+			}
+			encodeQueryValue(values, fieldName, v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(name, string(v.Bytes()))
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			encodeQueryValue(values, name, v.Index(i))
+		}
+	case reflect.Bool:
+		values.Add(name, strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		values.Add(name, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		values.Add(name, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		values.Add(name, strconv.FormatFloat(v.Float(), 'f', -1, 64))
+	case reflect.String:
+		values.Add(name, v.String())
+	}
+}
+`
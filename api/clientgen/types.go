@@ -0,0 +1,160 @@
+package clientgen
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// 保留的标签, 其它标签如 bind, default 只在服务端有意义
+var keptTags = []string{"json", "url"}
+
+// 服务端的默认值标签
+const defaultTag = "default"
+
+// 类型生成器, 将请求和响应类型转为客户端中的类型定义
+//
+// 标准库中的类型会直接引用, 其它命名类型会在客户端中生成同名的类型定义
+type typeGen struct {
+	names   map[reflect.Type]string // 已生成定义的类型名
+	used    map[string]bool         // 已使用的类型名
+	defs    []string                // 类型定义
+	imports map[string]bool         // 引用的标准库包
+}
+
+func newTypeGen() *typeGen {
+	return &typeGen{
+		names:   make(map[reflect.Type]string),
+		used:    make(map[string]bool),
+		imports: make(map[string]bool),
+	}
+}
+
+// 保留类型名, 生成的类型不会使用这些名称
+func (g *typeGen) reserve(names ...string) {
+	for _, name := range names {
+		g.used[name] = true
+	}
+}
+
+// 获取响应类型的表达式, interface{} 会转为 json.RawMessage 由调用者自己解析
+func (g *typeGen) rspExpr(t reflect.Type) string {
+	if t.Kind() == reflect.Interface {
+		return "json.RawMessage"
+	}
+	return g.expr(t)
+}
+
+// 获取类型的表达式
+func (g *typeGen) expr(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		if isStdPkg(t.PkgPath()) {
+			g.imports[t.PkgPath()] = true
+			return t.String()
+		}
+		return g.named(t)
+	}
+	return g.underlying(t)
+}
+
+// 生成命名类型的定义并返回类型名
+func (g *typeGen) named(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := exportName(t.Name())
+	if name == "" {
+		name = "Type"
+	}
+	base := name
+	for i := 2; g.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.used[name] = true
+	g.names[t] = name // 先保存类型名, 支持递归类型
+
+	def := fmt.Sprintf("// %s 对应 %s\ntype %s %s", name, t.String(), name, g.underlying(t))
+	g.defs = append(g.defs, def)
+	return name
+}
+
+// 获取类型的底层表达式
+func (g *typeGen) underlying(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + g.expr(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.expr(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), g.expr(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", g.expr(t.Key()), g.expr(t.Elem()))
+	case reflect.Struct:
+		return g.structExpr(t)
+	case reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return "interface{}"
+	}
+	return t.Kind().String()
+}
+
+func (g *typeGen) structExpr(t reflect.Type) string {
+	var sb strings.Builder
+	sb.WriteString("struct {\n")
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous { // 未导出
+			continue
+		}
+		if field.Tag.Get("json") == "-" && field.Tag.Get("url") == "" { // 不会传输的字段
+			continue
+		}
+
+		typeExpr := g.expr(field.Type)
+		if field.Anonymous {
+			sb.WriteString(typeExpr)
+		} else {
+			sb.WriteString(field.Name)
+			sb.WriteByte(' ')
+			sb.WriteString(typeExpr)
+		}
+		if tag := keepTags(field.Tag); tag != "" {
+			sb.WriteString(" `")
+			sb.WriteString(tag)
+			sb.WriteByte('`')
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// 保留需要的标签, 有默认值的字段会添加 omitempty, 零值不会发送, 由服务端设置默认值
+func keepTags(tag reflect.StructTag) string {
+	_, hasDefault := tag.Lookup(defaultTag)
+	var tags []string
+	for _, key := range keptTags {
+		v, ok := tag.Lookup(key)
+		if !ok && !hasDefault {
+			continue
+		}
+		if hasDefault && v != "-" && !strings.Contains(v, ",omitempty") {
+			v += ",omitempty"
+		}
+		tags = append(tags, fmt.Sprintf("%s:%q", key, v))
+	}
+	return strings.Join(tags, " ")
+}
+
+// 是否为标准库的包, 标准库的包路径第一段不包含点并且在 GOROOT 中存在
+func isStdPkg(pkgPath string) bool {
+	first := strings.SplitN(pkgPath, "/", 2)[0]
+	if strings.Contains(first, ".") || pkgPath == "main" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(pkgPath)))
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	iris_context "github.com/kataras/iris/v12/context"
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/utils"
+)
+
+// 计数读取器, 用于统计压缩数据的大小
+type countReader struct {
+	io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// 计数写入器, 用于统计压缩后数据的大小
+type countWriter struct {
+	io.Writer
+	n int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.n += n
+	return n, err
+}
+
+// 将以英文逗号分隔的文本拆分为列表
+func splitConfValues(text string) []string {
+	var values []string
+	for _, s := range strings.Split(text, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func inStrings(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 压缩中间件
+//
+// 请求数据会根据 Content-Encoding 在读取body前解压, 响应数据会根据 Accept-Encoding 压缩
+func CompressMiddleware(app core.IApp, conf *config.Config) func(ctx *Context) error {
+	encodings := splitConfValues(conf.CompressEncodings)
+	excludeContentTypes := splitConfValues(conf.ResponseCompressExcludeContentTypes)
+	return func(ctx *Context) error {
+		if conf.RequestDecompress {
+			if err := decompressRequest(ctx, encodings, conf.RequestDecompressMaxSize); err != nil {
+				return err
+			}
+		}
+
+		if !conf.ResponseCompress {
+			return nil
+		}
+
+		// 替换底层的写入器, 边写边压缩
+		w := newCompressResponseWriter(ctx, encodings, excludeContentTypes, conf.ResponseCompressMinSize, conf.ResponseCompressLevel)
+		ctx.ResponseWriter().SetWriter(w)
+		ctx.Next()
+		w.finish()
+		return nil
+	}
+}
+
+// 解压请求数据
+func decompressRequest(ctx *Context, encodings []string, maxSize int64) error {
+	encoding := strings.ToLower(strings.TrimSpace(ctx.GetHeader(iris_context.ContentEncodingHeaderKey)))
+	if encoding == "" || encoding == iris_context.IDENTITY {
+		return nil
+	}
+	if !inStrings(encodings, encoding) {
+		return UnsupportedEncoding.WithMessage("unsupported content encoding: " + encoding)
+	}
+
+	req := ctx.Request()
+	src := &countReader{Reader: req.Body}
+	cr, err := iris_context.NewCompressReader(src, encoding)
+	if err != nil {
+		return ParamError.WithError(err)
+	}
+	defer req.Body.Close()
+	defer cr.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(cr, maxSize+1))
+	if err != nil {
+		return ParamError.WithError(err)
+	}
+	if int64(len(body)) > maxSize {
+		atomic.AddInt64(&bodyLimitViolations, 1)
+		return RequestBodyTooLarge.WithMessage("decompressed request body too large")
+	}
+
+	// 替换为解压后的body, 后续的 ReadBody 和日志都会读取到解压后的数据
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.Header.Del(iris_context.ContentEncodingHeaderKey)
+	req.Header.Set(iris_context.ContentLengthHeaderKey, strconv.Itoa(len(body)))
+	ctx.Values().Set(utils.RequestCompressedSizeFieldKey, src.n)
+	return nil
+}
+
+// 压缩响应写入器, 替换 iris 响应写入器底层的 http.ResponseWriter
+//
+// 写入的数据先缓存到达到最小压缩大小, 然后根据响应头决定是否压缩, 压缩时边写边压缩, 不会缓存整个响应.
+// 达到最小压缩大小之前调用了 Flush 的响应(如SSE)视为流式响应, 不会压缩
+type compressResponseWriter struct {
+	http.ResponseWriter
+	ctx                 *Context
+	encodings           []string
+	excludeContentTypes []string
+	minSize             int
+	level               int
+
+	status      int          // 缓存的状态码
+	wroteHeader bool         // 是否已经写入状态码
+	buff        bytes.Buffer // 决定是否压缩前缓存的数据
+	decided     bool         // 是否已经决定是否压缩
+	cw          iris_context.CompressWriter
+	dst         *countWriter // 压缩数据的写入目标
+	rawSize     int
+}
+
+func newCompressResponseWriter(ctx *Context, encodings, excludeContentTypes []string, minSize, level int) *compressResponseWriter {
+	return &compressResponseWriter{
+		ResponseWriter:      ctx.ResponseWriter().Naive(),
+		ctx:                 ctx,
+		encodings:           encodings,
+		excludeContentTypes: excludeContentTypes,
+		minSize:             minSize,
+		level:               level,
+		status:              http.StatusOK,
+	}
+}
+
+func (w *compressResponseWriter) WriteHeader(status int) {
+	w.status = status
+	if w.decided {
+		w.writeHeader()
+	}
+}
+
+func (w *compressResponseWriter) writeHeader() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	w.rawSize += len(p)
+	if !w.decided {
+		w.buff.Write(p)
+		if w.buff.Len() >= w.minSize {
+			if err := w.decide(false); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+
+	w.writeHeader()
+	if w.cw != nil {
+		return w.cw.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(true)
+	}
+	if w.cw != nil {
+		_ = w.cw.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// 决定是否压缩, 然后写入状态码和已缓存的数据
+func (w *compressResponseWriter) decide(stream bool) error {
+	w.decided = true
+	if encoding, ok := w.encoding(stream); ok {
+		w.dst = &countWriter{Writer: w.ResponseWriter}
+		cw, err := iris_context.NewCompressWriter(w.dst, encoding, w.level)
+		if err != nil {
+			w.ctx.Warn("api.compress create writer failed", zap.String("encoding", encoding), zap.Error(err))
+		} else {
+			w.cw = cw
+			header := w.Header()
+			iris_context.AddCompressHeaders(header, encoding)
+			header.Del(iris_context.ContentLengthHeaderKey)
+		}
+	}
+
+	w.writeHeader()
+	if w.buff.Len() == 0 {
+		return nil
+	}
+	body := w.buff.Bytes()
+	w.buff = bytes.Buffer{}
+	var err error
+	if w.cw != nil {
+		_, err = w.cw.Write(body)
+	} else {
+		_, err = w.ResponseWriter.Write(body)
+	}
+	return err
+}
+
+// 获取压缩使用的编码, 返回 false 表示不压缩
+func (w *compressResponseWriter) encoding(stream bool) (string, bool) {
+	if stream || w.buff.Len() < w.minSize {
+		return "", false
+	}
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return "", false
+	}
+
+	header := w.Header()
+	if header.Get(iris_context.ContentEncodingHeaderKey) != "" { // 已经被编码过了
+		return "", false
+	}
+	contentType := iris_context.TrimHeaderValue(header.Get(iris_context.ContentTypeHeaderKey))
+	if contentType == "text/event-stream" || inStrings(w.excludeContentTypes, contentType) {
+		return "", false
+	}
+
+	encoding, err := iris_context.GetEncoding(w.ctx.Request(), w.encodings)
+	if err != nil || encoding == iris_context.IDENTITY {
+		return "", false
+	}
+	return encoding, true
+}
+
+// 结束压缩, 写入剩余的数据
+func (w *compressResponseWriter) finish() {
+	if !w.decided {
+		if w.buff.Len() == 0 { // 没有写入数据, 由 iris 在请求结束时写入状态码
+			w.decided = true
+			return
+		}
+		if err := w.decide(false); err != nil {
+			w.ctx.Warn("api.compress write failed", zap.Error(err))
+		}
+	}
+	w.ctx.Values().Set(utils.ResponseRawSizeFieldKey, w.rawSize)
+
+	if w.cw == nil {
+		return
+	}
+	if err := w.cw.Close(); err != nil {
+		w.ctx.Warn("api.compress write failed", zap.Error(err))
+	}
+	w.cw = nil
+	w.ctx.Values().Set(utils.ResponseCompressedSizeFieldKey, w.dst.n)
+}
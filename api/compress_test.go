@@ -0,0 +1,152 @@
+package api
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/zly-app/service/api/config"
+)
+
+func TestCompressResponse(t *testing.T) {
+	conf := config.NewConfig()
+	conf.ResponseCompress = true
+	conf.ResponseCompressMinSize = 100
+	conf.ResponseCompressExcludeContentTypes = "image/png"
+	conf.Check()
+
+	big := strings.Repeat("0123456789", 100)
+	s := NewApiService(getTestApp(), conf)
+	s.Get("/big", func(ctx iris.Context) {
+		ctx.ContentType("text/plain")
+		for i := 0; i < len(big); i += 10 { // 分多次写入
+			_, _ = ctx.WriteString(big[i : i+10])
+		}
+	})
+	s.Get("/small", func(ctx iris.Context) {
+		ctx.ContentType("text/plain")
+		_, _ = ctx.WriteString("small")
+	})
+	s.Get("/png", func(ctx iris.Context) {
+		ctx.ContentType("image/png")
+		_, _ = ctx.WriteString(big)
+	})
+	s.Get("/sse", func(ctx iris.Context) {
+		ctx.ContentType("text/event-stream")
+		for i := 0; i < 3; i++ {
+			_, _ = ctx.WriteString("data: " + big + "\n\n")
+			ctx.ResponseWriter().Flush()
+		}
+	})
+	s.Get("/stream", func(ctx iris.Context) {
+		ctx.ContentType("text/plain")
+		_, _ = ctx.WriteString("data")
+		ctx.ResponseWriter().Flush() // 达到最小压缩大小之前 Flush 视为流式响应
+		_, _ = ctx.WriteString(big)
+	})
+	s.Get("/no_content", func(ctx iris.Context) {
+		ctx.StatusCode(http.StatusNoContent)
+	})
+	if err := s.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url      string
+		code     int
+		encoding string
+		body     string
+		flushed  bool
+	}{
+		{url: "/big", code: http.StatusOK, encoding: "gzip", body: big},
+		{url: "/small", code: http.StatusOK, body: "small"},
+		{url: "/png", code: http.StatusOK, body: big},
+		{url: "/sse", code: http.StatusOK, body: strings.Repeat("data: "+big+"\n\n", 3), flushed: true},
+		{url: "/stream", code: http.StatusOK, body: "data" + big, flushed: true},
+		{url: "/no_content", code: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, test.url, nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+
+			if w.Code != test.code {
+				t.Fatalf("状态码应该为%d, 实际为%d", test.code, w.Code)
+			}
+			if encoding := w.Header().Get("Content-Encoding"); encoding != test.encoding {
+				t.Fatalf("Content-Encoding 应该为 %q, 实际为 %q", test.encoding, encoding)
+			}
+			if w.Flushed != test.flushed {
+				t.Fatalf("Flushed 应该为 %v", test.flushed)
+			}
+
+			body := w.Body.Bytes()
+			if test.encoding == "gzip" {
+				gr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body, err = ioutil.ReadAll(gr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if string(body) != test.body {
+				t.Fatalf("响应数据错误: %q", body)
+			}
+		})
+	}
+}
+
+func TestCompressAccessLogBytes(t *testing.T) {
+	conf := config.NewConfig()
+	conf.ResponseCompress = true
+	conf.ResponseCompressMinSize = 100
+	conf.AccessLog = true
+	conf.AccessLogFile = filepath.Join(t.TempDir(), "access.log")
+	conf.AccessLogFormat = "{uri} {bytes}"
+	conf.Check()
+
+	big := strings.Repeat("0123456789", 100)
+	s := NewApiService(getTestApp(), conf)
+	s.Get("/big", func(ctx iris.Context) {
+		ctx.ContentType("text/plain")
+		_, _ = ctx.WriteString(big)
+	})
+	s.Get("/small", func(ctx iris.Context) {
+		ctx.ContentType("text/plain")
+		_, _ = ctx.WriteString("small")
+	})
+	if err := s.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 访问日志中的字节数是写入到客户端的字节数, 压缩时为压缩后的大小
+	var expect []string
+	for _, url := range []string{"/big", "/small"} {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		expect = append(expect, url+" "+strconv.Itoa(w.Body.Len()))
+	}
+	if !strings.HasPrefix(expect[0], "/big ") || expect[0] == "/big "+strconv.Itoa(len(big)) {
+		t.Fatalf("响应应该被压缩: %s", expect[0])
+	}
+
+	data, err := ioutil.ReadFile(conf.AccessLogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); strings.Join(lines, ",") != strings.Join(expect, ",") {
+		t.Fatalf("访问日志应该为 %v, 实际为 %v", expect, lines)
+	}
+}
@@ -10,9 +10,15 @@ package config
 
 import (
 	"runtime"
+	"strings"
 )
 
 const (
+	// 默认监听器名
+	DefaultListenerName = "default"
+	// 默认监听器网络类型
+	defaultListenerNetwork = "tcp"
+
 	// 默认bind
 	defaultBind = ":8080"
 	// 默认适配nginx的Real获取ip
@@ -22,11 +28,33 @@ const (
 	// 默认post允许最大数据大小(32M)
 	defaultPostMaxMemory = 32 << 20
 
+	// 默认读取请求超时, 单位毫秒
+	defaultReadTimeout = 60000
+	// 默认读取请求头超时, 单位毫秒
+	defaultReadHeaderTimeout = 10000
+	// 默认写入响应超时, 单位毫秒
+	defaultWriteTimeout = 60000
+	// 默认空闲连接超时, 单位毫秒
+	defaultIdleTimeout = 120000
+	// 默认请求头最大大小(1M)
+	defaultMaxHeaderBytes = 1 << 20
+
 	// 同时处理请求的goroutine数
 	defThreadCount = 0
 	// 最大请求等待队列大小
 	defMaxReqWaitQueueSize = 10000
 
+	// 默认限流模式
+	defaultLimitMode = "gpool"
+	// 默认自适应限流初始并发限制
+	defaultAdaptiveLimitInitial = 100
+	// 默认自适应限流最小并发限制
+	defaultAdaptiveLimitMin = 10
+	// 默认自适应限流最大并发限制
+	defaultAdaptiveLimitMax = 1000
+	// 默认自适应限流目标延迟
+	defaultAdaptiveLimitTargetLatency = 500
+
 	// 请求日志等级设为info
 	defReqLogLevelIsInfo = true
 	// 响应日志等级设为info
@@ -47,15 +75,70 @@ const (
 	defaultLogApiResultMaxSize = 64 << 10
 	// 输出body最大大小
 	defaultLogBodyMaxSize = 64 << 10
+
+	// 默认访问日志格式
+	defaultAccessLogFormat = "combined"
+	// 默认访问日志文件
+	defaultAccessLogFile = "./log/access.log"
+	// 默认访问日志文件最大大小
+	defaultAccessLogMaxSize = 100
+
+	// 默认管理接口路径前缀
+	defaultAdminPath = "/_admin"
+
+	// 默认支持的压缩算法
+	defaultCompressEncodings = "gzip,deflate,br"
+	// 默认响应压缩最小大小
+	defaultResponseCompressMinSize = 1 << 10
+	// 默认响应压缩等级
+	defaultResponseCompressLevel = -1
 )
 
+// 监听器配置
+type ListenerConfig struct {
+	Name          string // 监听器名, 用于将路由分配到监听器上
+	Network       string // 网络类型, 可选 tcp, unix, 默认为 tcp
+	Addr          string // 监听地址, tcp为 host:port, unix为socket文件路径
+	FileMode      string // unix socket 文件权限, 八进制, 如 0660
+	ProxyProtocol bool   // 解析 PROXY protocol (v1/v2) 获取真实的客户端地址, 没有头的连接会被拒绝, 只有在负载均衡器后面时才应该开启
+}
+
 // api服务配置
 type Config struct {
-	Bind                 string // bind地址
+	Bind                 string // bind地址, 如果设置了 Listeners 则不会生效
 	IPWithNginxForwarded bool   // 适配nginx的Forwarded获取ip, 优先级高于nginx的Real
 	IPWithNginxReal      bool   // 适配nginx的Real获取ip, 优先级高于sock连接的ip
 	PostMaxMemory        int64  // post允许客户端传输最大数据大小, 单位字节
 
+	// 可信代理, 多个用英文逗号分隔, 支持ip和CIDR, * 表示信任所有连接, 默认为空, 表示不信任任何连接
+	//
+	// 在反向代理之后部署时需要设置为代理的地址, 如 127.0.0.1,10.0.0.0/8.
+	// 只有sock连接的ip是可信代理时才会从 X-Forwarded-For 和 X-Real-IP 获取ip.
+	// X-Forwarded-For 会从右往左解析, 跳过可信代理的ip, 第一个不可信的ip就是客户端ip
+	TrustedProxies string
+	// 允许访问的ip, 多个用英文逗号分隔, 支持ip和CIDR, 为空表示允许所有ip
+	IPAllow string
+	// 禁止访问的ip, 多个用英文逗号分隔, 支持ip和CIDR, 优先于 IPAllow
+	IPDeny string
+
+	// 从这个header获取租户id, 如 X-Tenant-Id
+	TenantHeader string
+	// 从子域名获取租户id, 值为主域名, 如 example.com, 请求 foo.example.com 的租户为 foo
+	TenantDomain string
+	// 不需要租户的路径前缀, 多个用英文逗号分隔, 按路径段匹配, 如健康检查
+	TenantExemptPaths string
+	// 租户配置
+	Tenants []TenantConfig
+
+	// 监听器列表, 为空时使用 Bind 创建一个名为 default 的tcp监听器
+	Listeners []ListenerConfig
+
+	ReadTimeout       int // 读取整个请求的超时, 单位毫秒, 设为负数表示不限制
+	ReadHeaderTimeout int // 读取请求头的超时, 单位毫秒, 设为负数表示不限制, 用于防止慢速攻击
+	WriteTimeout      int // 写入响应的超时, 单位毫秒, 从读取完请求头开始计算, 设为负数表示不限制
+	IdleTimeout       int // keep-alive 连接等待下一个请求的超时, 单位毫秒, 设为负数表示使用 ReadTimeout
+	MaxHeaderBytes    int // 请求头最大大小, 单位字节, 超出时会直接返回431
+
 	// 同时处理请求的goroutine数, 设为0时取逻辑cpu数*2, 设为负数时不作任何限制, 每个请求由独立的线程执行
 	ThreadCount int
 	// 最大请求等待队列大小
@@ -64,6 +147,17 @@ type Config struct {
 	// 启动时创建一个指定大小的任务队列, 触发产生的请求会放入这个队列, 队列已满时新触发的请求会返回错误
 	MaxReqWaitQueueSize int
 
+	// 限流模式, 可选 gpool, aimd, gradient, 默认为 gpool
+	//
+	// gpool 使用 ThreadCount 和 MaxReqWaitQueueSize 限制固定的并发数.
+	// aimd 和 gradient 会根据观测到的延迟自适应调整并发限制, 超出限制的请求会被直接丢弃
+	LimitMode                  string
+	AdaptiveLimitInitial       int    // 自适应限流初始并发限制
+	AdaptiveLimitMin           int    // 自适应限流最小并发限制
+	AdaptiveLimitMax           int    // 自适应限流最大并发限制
+	AdaptiveLimitTargetLatency int    // aimd目标延迟, 单位毫秒, 延迟超过这个值时减少并发限制
+	AdaptiveLimitCriticalPaths string // 关键路径, 多个路径用英文逗号分隔, 如健康检查, 这些路径的请求最后才会被丢弃
+
 	ReqLogLevelIsInfo             bool  // 请求日志等级设为info
 	RspLogLevelIsInfo             bool  // 响应日志等级设为info
 	BindLogLevelIsInfo            bool  // bind日志等级设为info
@@ -74,6 +168,36 @@ type Config struct {
 	AlwaysLogBody                 bool  // 总是输出body日志, 如果设为false, 只会在出现错误时才会输出body日志
 	LogApiResultMaxSize           int   // 日志输出结果最大大小
 	LogBodyMaxSize                int64 // 日志输出body最大大小
+
+	AccessLog               bool   // 启用访问日志, 访问日志会写入到单独的文件中, 和app的日志相互独立
+	AccessLogFormat         string // 访问日志格式, 可选 combined, json, 其它值会被当作模板, 如 {ip} {method} {uri} {status} {latency_ms} {request_id}
+	AccessLogFile           string // 访问日志文件路径
+	AccessLogMaxSize        int    // 访问日志文件最大大小, 单位MB, 超出后会切割
+	AccessLogRotateInterval int    // 访问日志按时间切割间隔, 单位分钟, 0表示不按时间切割, 如1440表示每天切割
+	AccessLogMaxAge         int    // 访问日志文件保留天数, 0表示不按天数删除
+	AccessLogMaxBackups     int    // 访问日志文件最多保留数量, 0表示不按数量删除
+	AccessLogCompress       bool   // 是否压缩切割后的访问日志文件
+
+	EnableAdmin bool   // 启用管理接口
+	AdminBind   string // 管理接口bind地址, 为空时挂载到api服务上, 这时必须通过 api.WithAdminAuth 设置鉴权, 否则服务无法启动
+	AdminPath   string // 管理接口路径前缀
+	// 管理接口只在这个监听器上提供, 只有 AdminBind 为空时生效, 如 internal
+	AdminListener string
+
+	// 支持的压缩算法, 多个算法用英文逗号分隔, 可选 gzip, deflate, br
+	CompressEncodings string
+	// 启用请求解压缩, 会根据 Content-Encoding 在读取body前解压请求数据
+	RequestDecompress bool
+	// 解压后的body最大大小, 单位字节, 超出时会返回错误, 用于防止zip炸弹, 默认为 PostMaxMemory
+	RequestDecompressMaxSize int64
+	// 启用响应压缩, 会根据 Accept-Encoding 选择压缩算法
+	ResponseCompress bool
+	// 响应压缩最小大小, 单位字节, 响应数据小于这个值时不会压缩
+	ResponseCompressMinSize int
+	// 响应压缩等级, -1表示使用算法的默认等级
+	ResponseCompressLevel int
+	// 不进行压缩的响应 Content-Type, 多个类型用英文逗号分隔, 如 image/png,application/zip
+	ResponseCompressExcludeContentTypes string
 }
 
 func NewConfig() *Config {
@@ -83,6 +207,7 @@ func NewConfig() *Config {
 		IPWithNginxReal:      defaultIPWithNginxReal,
 
 		ThreadCount: defThreadCount,
+		LimitMode:   defaultLimitMode,
 
 		ReqLogLevelIsInfo:             defReqLogLevelIsInfo,
 		RspLogLevelIsInfo:             defRspLogLevelIsInfo,
@@ -92,6 +217,8 @@ func NewConfig() *Config {
 		SendDetailedErrorInProduction: defSendDetailedErrorInProduction,
 		AlwaysLogHeaders:              defAlwaysLogHeaders,
 		AlwaysLogBody:                 defAlwaysLogBody,
+
+		ResponseCompressLevel: defaultResponseCompressLevel,
 	}
 }
 
@@ -99,9 +226,37 @@ func (conf *Config) Check() {
 	if conf.Bind == "" {
 		conf.Bind = defaultBind
 	}
+	if len(conf.Listeners) == 0 {
+		conf.Listeners = []ListenerConfig{{Name: DefaultListenerName, Addr: conf.Bind}}
+	}
+	for i := range conf.Listeners {
+		l := &conf.Listeners[i]
+		l.Network = strings.ToLower(l.Network)
+		if l.Network == "" {
+			l.Network = defaultListenerNetwork
+		}
+		if l.Name == "" {
+			l.Name = l.Network + ":" + l.Addr
+		}
+	}
 	if conf.PostMaxMemory < 1 {
 		conf.PostMaxMemory = defaultPostMaxMemory
 	}
+	if conf.ReadTimeout == 0 {
+		conf.ReadTimeout = defaultReadTimeout
+	}
+	if conf.ReadHeaderTimeout == 0 {
+		conf.ReadHeaderTimeout = defaultReadHeaderTimeout
+	}
+	if conf.WriteTimeout == 0 {
+		conf.WriteTimeout = defaultWriteTimeout
+	}
+	if conf.IdleTimeout == 0 {
+		conf.IdleTimeout = defaultIdleTimeout
+	}
+	if conf.MaxHeaderBytes < 1 {
+		conf.MaxHeaderBytes = defaultMaxHeaderBytes
+	}
 
 	if conf.ThreadCount == 0 {
 		conf.ThreadCount = runtime.NumCPU() * 2
@@ -113,10 +268,51 @@ func (conf *Config) Check() {
 		conf.MaxReqWaitQueueSize = defMaxReqWaitQueueSize
 	}
 
+	conf.LimitMode = strings.ToLower(conf.LimitMode)
+	if conf.LimitMode == "" {
+		conf.LimitMode = defaultLimitMode
+	}
+	if conf.AdaptiveLimitInitial < 1 {
+		conf.AdaptiveLimitInitial = defaultAdaptiveLimitInitial
+	}
+	if conf.AdaptiveLimitMin < 1 {
+		conf.AdaptiveLimitMin = defaultAdaptiveLimitMin
+	}
+	if conf.AdaptiveLimitMax < 1 {
+		conf.AdaptiveLimitMax = defaultAdaptiveLimitMax
+	}
+	if conf.AdaptiveLimitTargetLatency < 1 {
+		conf.AdaptiveLimitTargetLatency = defaultAdaptiveLimitTargetLatency
+	}
+
 	if conf.LogApiResultMaxSize < 1 {
 		conf.LogApiResultMaxSize = defaultLogApiResultMaxSize
 	}
 	if conf.LogBodyMaxSize < 1 {
 		conf.LogBodyMaxSize = defaultLogBodyMaxSize
 	}
+
+	if conf.AccessLogFormat == "" {
+		conf.AccessLogFormat = defaultAccessLogFormat
+	}
+	if conf.AccessLogFile == "" {
+		conf.AccessLogFile = defaultAccessLogFile
+	}
+	if conf.AccessLogMaxSize < 1 {
+		conf.AccessLogMaxSize = defaultAccessLogMaxSize
+	}
+
+	if conf.AdminPath == "" {
+		conf.AdminPath = defaultAdminPath
+	}
+
+	if conf.CompressEncodings == "" {
+		conf.CompressEncodings = defaultCompressEncodings
+	}
+	if conf.RequestDecompressMaxSize < 1 {
+		conf.RequestDecompressMaxSize = conf.PostMaxMemory
+	}
+	if conf.ResponseCompressMinSize < 1 {
+		conf.ResponseCompressMinSize = defaultResponseCompressMinSize
+	}
 }
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// 动态配置, 用于在运行时修改配置
+//
+// 每次修改都会复制一份新的配置, 已经获取到的配置不会被修改
+type Dynamic struct {
+	v  atomic.Value
+	mx sync.Mutex // 保证修改操作串行
+}
+
+func NewDynamic(conf *Config) *Dynamic {
+	d := new(Dynamic)
+	d.v.Store(conf)
+	return d
+}
+
+// 获取当前配置, 不要修改返回的配置
+func (d *Dynamic) Load() *Config {
+	return d.v.Load().(*Config)
+}
+
+// 修改配置, fn 会收到当前配置的副本
+func (d *Dynamic) Update(fn func(conf *Config)) *Config {
+	d.mx.Lock()
+	conf := *d.Load()
+	fn(&conf)
+	d.v.Store(&conf)
+	d.mx.Unlock()
+	return &conf
+}
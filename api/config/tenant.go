@@ -0,0 +1,89 @@
+package config
+
+import (
+	"strings"
+)
+
+// 租户配置, 用于覆盖指定租户的限流, 日志策略和允许访问的路由, 没有设置的字段使用全局配置
+type TenantConfig struct {
+	Tenant       string  // 租户id
+	RateLimit    float64 // 每秒最多处理的请求数, 0表示不限制
+	RateBurst    int     // 允许突发的请求数, 默认等于 RateLimit
+	AllowedPaths string  // 允许访问的路径前缀, 多个用英文逗号分隔, 按路径段匹配, 为空表示允许所有路径
+
+	ReqLogLevelIsInfo     *bool // 请求日志等级设为info
+	RspLogLevelIsInfo     *bool // 响应日志等级设为info
+	LogApiResultInDevelop *bool // 在开发环境中输出api结果
+	LogApiResultInProd    *bool // 在生产环境中输出api结果
+	AlwaysLogHeaders      *bool // 总是输出headers日志
+	AlwaysLogBody         *bool // 总是输出body日志
+}
+
+// 是否覆盖了日志策略
+func (t *TenantConfig) hasLogPolicy() bool {
+	return t.ReqLogLevelIsInfo != nil || t.RspLogLevelIsInfo != nil ||
+		t.LogApiResultInDevelop != nil || t.LogApiResultInProd != nil ||
+		t.AlwaysLogHeaders != nil || t.AlwaysLogBody != nil
+}
+
+// 返回覆盖了租户日志策略的配置, 没有覆盖日志策略时返回原配置
+func (t *TenantConfig) Apply(conf *Config) *Config {
+	if !t.hasLogPolicy() {
+		return conf
+	}
+	c := *conf
+	setBool(&c.ReqLogLevelIsInfo, t.ReqLogLevelIsInfo)
+	setBool(&c.RspLogLevelIsInfo, t.RspLogLevelIsInfo)
+	setBool(&c.LogApiResultInDevelop, t.LogApiResultInDevelop)
+	setBool(&c.LogApiResultInProd, t.LogApiResultInProd)
+	setBool(&c.AlwaysLogHeaders, t.AlwaysLogHeaders)
+	setBool(&c.AlwaysLogBody, t.AlwaysLogBody)
+	return &c
+}
+
+// 检查路径是否允许访问
+func (t *TenantConfig) PathAllowed(path string) bool {
+	return t.AllowedPaths == "" || hasPathPrefix(path, t.AllowedPaths)
+}
+
+func setBool(dst *bool, v *bool) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// 获取租户配置, 没有设置时返回nil
+func (conf *Config) TenantConfig(tenant string) *TenantConfig {
+	for i := range conf.Tenants {
+		if conf.Tenants[i].Tenant == tenant {
+			return &conf.Tenants[i]
+		}
+	}
+	return nil
+}
+
+// 检查路径是否不需要租户
+func (conf *Config) TenantExempt(path string) bool {
+	return conf.TenantExemptPaths != "" && hasPathPrefix(path, conf.TenantExemptPaths)
+}
+
+// 检查路径是否匹配英文逗号分隔的路径前缀, 按路径段匹配
+func hasPathPrefix(path, prefixes string) bool {
+	for _, prefix := range strings.Split(prefixes, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix != "" && HasPathPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 检查路径是否以 prefix 开头, 按路径段匹配
+//
+// 路径等于 prefix 或 prefix 之后是 / 时匹配, 如 /orders 匹配 /orders 和 /orders/1, 不匹配 /orders-admin
+func HasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
@@ -43,10 +43,25 @@ func makeContext(irisCtx iris.Context) *Context {
 
 //  bind api数据, 它会将api数据反序列化到a中, 如果a是结构体会验证a
 func (c *Context) Bind(a interface{}) error {
+	if err := c.readBind(a); err != nil {
+		return err
+	}
+	return c.validBind(a)
+}
+
+// 将api数据反序列化到a中
+func (c *Context) readBind(a interface{}) error {
 	if err := c.ReadBody(a); err != nil {
+		if isBodyTooLarge(c) {
+			return RequestBodyTooLarge.WithError(err)
+		}
 		return ParamError.WithError(err)
 	}
+	return nil
+}
 
+// 输出bind日志, 如果a是结构体会验证a
+func (c *Context) validBind(a interface{}) error {
 	if c.conf.BindLogLevelIsInfo {
 		c.Info("api.request.bind", zap.Any("arg", a))
 	} else {
@@ -77,3 +92,18 @@ func (c *Context) RemoteAddr() string {
 func (c *Context) Context() context.Context {
 	return c.ctx
 }
+
+// 获取租户, 没有设置租户解析器或请求不需要租户时返回空字符串
+func (c *Context) Tenant() string {
+	return utils.Context.GetTenant(c.IrisContext)
+}
+
+// 设置用户标识, 一般由鉴权中间件设置, 会附加到错误上报中
+func (c *Context) SetPrincipal(principal string) {
+	c.Values().Set(utils.PrincipalFieldKey, principal)
+}
+
+// 获取用户标识
+func (c *Context) Principal() string {
+	return utils.Context.GetPrincipal(c.IrisContext)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+	app_utils "github.com/zly-app/zapp/pkg/utils"
+
+	"github.com/zly-app/service/api/reporter"
+	"github.com/zly-app/service/api/utils"
+)
+
+// 错误上报器保存字段
+const errorReporterFieldKey = "_error_reporter"
+
+// 错误上报中间件, 会上报panic和服务内部错误
+//
+// 上报的信息包括堆栈, 请求方法, 路径, 屏蔽了敏感数据的headers和body, 请求id, 处理程序名和用户标识
+func ErrorReportMiddleware(r reporter.IReporter) iris.Handler {
+	return func(irisCtx *iris_context.Context) {
+		irisCtx.Values().Set(errorReporterFieldKey, r)
+		irisCtx.Next()
+
+		err, ok := irisCtx.Values().Get("error").(error)
+		if !ok || err == nil {
+			return
+		}
+		isPanic, _ := irisCtx.Values().Get("panic").(bool)
+		if !isPanic {
+			if code, _ := decodeErr(err); code != ServiceInternalError.Code {
+				return
+			}
+		}
+		reportError(irisCtx, err, isPanic, irisCtx.Values().GetString("_handler_name"))
+	}
+}
+
+// 上报错误, 没有设置上报器时忽略
+func reportError(irisCtx *iris_context.Context, err error, isPanic bool, handlerName string) {
+	r, ok := irisCtx.Values().Get(errorReporterFieldKey).(reporter.IReporter)
+	if !ok {
+		return
+	}
+
+	kind, stack := reporter.KindError, ""
+	if isPanic {
+		kind = reporter.KindPanic
+		detail := app_utils.Recover.GetRecoverErrorDetail(err)
+		if i := strings.IndexByte(detail, '\n'); i != -1 {
+			stack = detail[i+1:]
+		}
+	} else if s := fmt.Sprintf("%+v", err); s != err.Error() { // 携带了堆栈的错误
+		stack = s
+	}
+	if handlerName == "" {
+		handlerName = irisCtx.HandlerName()
+	}
+
+	fields := map[string]string{
+		reporter.FieldService:   string(nowServiceType),
+		reporter.FieldKind:      kind,
+		reporter.FieldHandler:   handlerName,
+		reporter.FieldRequestID: utils.Context.GetRequestID(irisCtx),
+		reporter.FieldPrincipal: utils.Context.GetPrincipal(irisCtx),
+		reporter.FieldMethod:    irisCtx.Method(),
+		reporter.FieldPath:      irisCtx.Path(),
+		reporter.FieldHeaders:   reporter.RedactHeaders(irisCtx.Request().Header),
+		reporter.FieldBody:      reportBody(irisCtx),
+	}
+	r.Report(utils.Context.MustGetContextFromIrisContext(irisCtx), err, stack, fields)
+}
+
+// 获取用于上报的body, 规则和日志相同
+func reportBody(irisCtx *iris_context.Context) string {
+	conf := utils.Context.MustGetConfFromIrisContext(irisCtx)
+	switch {
+	case irisCtx.GetContentTypeRequested() == iris_context.ContentBinaryHeaderValue: // 流
+		return fmt.Sprintf("body<bytesLen=%d>", irisCtx.GetContentLength())
+	case irisCtx.GetHeader(iris_context.ContentEncodingHeaderKey) != "": // 未解压
+		return fmt.Sprintf("body<encoding=%s, len=%d>", irisCtx.GetHeader(iris_context.ContentEncodingHeaderKey), irisCtx.GetContentLength())
+	case irisCtx.GetContentLength() > conf.LogBodyMaxSize: // 超长
+		return fmt.Sprintf("body<len=%d>", irisCtx.GetContentLength())
+	}
+	body, _ := irisCtx.GetBody()
+	return reporter.RedactBody(irisCtx.GetContentTypeRequested(), body)
+}
@@ -1,139 +1,189 @@
-package api
-
-import (
-	"fmt"
-	"reflect"
-
-	"github.com/zly-app/zapp/logger"
-	"go.uber.org/zap"
-
-	"github.com/zly-app/service/api/utils"
-)
-
-type handlerUtil struct {
-	handler interface{}
-	name    string
-	hType   reflect.Type
-}
-
-func newHandler(handler interface{}) *handlerUtil {
-	if handler == nil {
-		logger.Log.Fatal("handler为nil", zap.String("handler", fmt.Sprintf("%T", handler)))
-	}
-
-	hType := reflect.TypeOf(handler)
-	if hType.Kind() != reflect.Func {
-		logger.Log.Fatal("handler必须是函数", zap.String("handler", fmt.Sprintf("%T", handler)))
-	}
-
-	hName := utils.GetFuncName(handler)
-
-	return &handlerUtil{
-		handler: handler,
-		name:    hName,
-		hType:   hType,
-	}
-}
-
-// 检查 handler 指纹
-func (h *handlerUtil) checkHandlerFingerprint() {
-	// 检查入参
-	if h.hType.NumIn() < 1 || h.hType.NumIn() > 2 {
-		logger.Log.Fatal("handler的入参数量为1个或2个", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
-	}
-
-	// 检查第一个参数
-	arg0 := h.hType.In(0)
-	if !arg0.AssignableTo(typeOfContext) {
-		logger.Log.Fatal("handler的第一个入参必须是 *api.Context", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
-	}
-
-	// 检查出参
-	if h.hType.NumOut() < 1 || h.hType.NumOut() > 2 {
-		logger.Log.Fatal("handler的出参数量为1个或2个", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
-	}
-
-	// 如果出参数为2个, 最后一个出参必须是error
-	if h.hType.NumOut() == 2 {
-		out1 := h.hType.Out(1)
-		if !out1.AssignableTo(typeOfError) {
-			logger.Log.Fatal("handler的第二个出参必须是 error", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
-		}
-	}
-}
-
-// 根据 handler 构建req建造者
-//
-// req 是 handler 的第二个入参, 如果入参数量小于 2 返回 nil
-// req 必须是 struct 或 *struct
-func (h *handlerUtil) mustMakeReqCreator() func(ctx *Context) (reflect.Value, error) {
-	if h.hType.NumIn() < 2 {
-		return nil
-	}
-
-	// 检查 req 是 struct 或 *struct
-	arg1 := h.hType.In(1)                  // 获取req的类型
-	reqIsPtr := arg1.Kind() == reflect.Ptr // req参数是否为指针
-	if reqIsPtr {
-		arg1 = arg1.Elem() // 获取req的真实类型
-	}
-	if arg1.Kind() != reflect.Struct {
-		logger.Log.Fatal("handler的第二个入参必须是 struct 或 *struct", zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
-	}
-
-	// 返回建造者
-	return func(ctx *Context) (reflect.Value, error) {
-		req := reflect.New(arg1)                          // 创建req实例
-		if err := ctx.Bind(req.Interface()); err != nil { // bind参数
-			return req, err
-		}
-
-		if reqIsPtr { // 如果req是指针, 直接返回
-			return req, nil
-		}
-		return req.Elem(), nil // 非指针要返回指向对象
-	}
-}
-
-// 构建handler
-func (h *handlerUtil) makeHandler() Handler {
-	hValue := reflect.ValueOf(h.handler)
-	reqCreator := h.mustMakeReqCreator()
-	return func(ctx *Context) interface{} {
-		var outValues []reflect.Value
-
-		// 调用handler
-		if reqCreator == nil { // 如果没有req建造者, 表示不需要req参数
-			outValues = hValue.Call([]reflect.Value{reflect.ValueOf(ctx)})
-		} else {
-			reqValue, err := reqCreator(ctx)
-			if err != nil {
-				return err
-			}
-			outValues = hValue.Call([]reflect.Value{reflect.ValueOf(ctx), reqValue})
-		}
-
-		// 检查结果
-		if len(outValues) == 1 { // 如果只有一个结果直接返回
-			return outValues[0].Interface()
-		}
-
-		err := outValues[1].Interface()
-		if err != nil {
-			return err.(error)
-		}
-		return outValues[0].Interface()
-	}
-}
-
-func (h *handlerUtil) MakeHandler() Handler {
-	fn, ok := h.handler.(Handler)
-	if !ok {
-		h.checkHandlerFingerprint() // 检查handler指纹
-		fn = h.makeHandler()        // 构建handler
-	}
-	return func(ctx *Context) interface{} {
-		ctx.Values().Set("_handler_name", h.name)
-		return fn(ctx)
-	}
-}
+package api
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/zly-app/zapp/logger"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/utils"
+)
+
+type handlerUtil struct {
+	handler interface{}
+	name    string
+	hType   reflect.Type
+}
+
+func newHandler(handler interface{}) *handlerUtil {
+	if handler == nil {
+		logger.Log.Fatal("handler为nil", zap.String("handler", fmt.Sprintf("%T", handler)))
+	}
+
+	hType := reflect.TypeOf(handler)
+	if hType.Kind() != reflect.Func {
+		logger.Log.Fatal("handler必须是函数", zap.String("handler", fmt.Sprintf("%T", handler)))
+	}
+
+	hName := utils.GetFuncName(handler)
+
+	return &handlerUtil{
+		handler: handler,
+		name:    hName,
+		hType:   hType,
+	}
+}
+
+// 检查 handler 指纹
+func (h *handlerUtil) checkHandlerFingerprint() {
+	// 检查入参
+	if h.hType.NumIn() < 1 || h.hType.NumIn() > 2 {
+		logger.Log.Fatal("handler的入参数量为1个或2个", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
+	}
+
+	// 检查第一个参数
+	arg0 := h.hType.In(0)
+	if !arg0.AssignableTo(typeOfContext) {
+		logger.Log.Fatal("handler的第一个入参必须是 *api.Context", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
+	}
+
+	// 检查出参
+	if h.hType.NumOut() < 1 || h.hType.NumOut() > 2 {
+		logger.Log.Fatal("handler的出参数量为1个或2个", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
+	}
+
+	// 如果出参数为2个, 最后一个出参必须是error
+	if h.hType.NumOut() == 2 {
+		out1 := h.hType.Out(1)
+		if !out1.AssignableTo(typeOfError) {
+			logger.Log.Fatal("handler的第二个出参必须是 error", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
+		}
+	}
+}
+
+// 获取 handler 的请求类型和响应类型
+//
+// 请求类型是第二个入参去掉指针后的类型, 没有第二个入参时为nil.
+// 响应类型是第一个出参的类型, 只返回error时为nil
+func (h *handlerUtil) types() (reqType, rspType reflect.Type) {
+	if _, ok := h.handler.(Handler); ok {
+		return nil, h.hType.Out(0)
+	}
+	if h.hType.NumIn() >= 2 {
+		reqType = h.hType.In(1)
+		if reqType.Kind() == reflect.Ptr {
+			reqType = reqType.Elem()
+		}
+	}
+	if h.hType.NumOut() >= 1 {
+		rspType = h.hType.Out(0)
+		if h.hType.NumOut() == 1 && rspType == typeOfError {
+			rspType = nil
+		}
+	}
+	return reqType, rspType
+}
+
+// 读取函数, 将请求数据反序列化到a中
+type bindFunc = func(ctx *Context, a interface{}) error
+
+// 根据 handler 构建req建造者
+//
+// req 是 handler 的第二个入参, 如果入参数量小于 2 返回 nil
+// req 必须是 struct 或 *struct
+// req 的字段可以通过 default 标签设置默认值, 默认值会在校验前设置
+func (h *handlerUtil) mustMakeReqCreator() func(ctx *Context, bind bindFunc) (reflect.Value, error) {
+	if h.hType.NumIn() < 2 {
+		return nil
+	}
+
+	// 检查 req 是 struct 或 *struct
+	arg1 := h.hType.In(1)                  // 获取req的类型
+	reqIsPtr := arg1.Kind() == reflect.Ptr // req参数是否为指针
+	if reqIsPtr {
+		arg1 = arg1.Elem() // 获取req的真实类型
+	}
+	if arg1.Kind() != reflect.Struct {
+		logger.Log.Fatal("handler的第二个入参必须是 struct 或 *struct", zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
+	}
+
+	// 默认值
+	defaults, err := utils.MakeDefaultsSetter(arg1)
+	if err != nil {
+		logger.Log.Fatal("handler的第二个入参默认值错误", zap.String("fingerprint", fmt.Sprintf("%T", h.handler)), zap.Error(err))
+	}
+
+	// 返回建造者
+	return func(ctx *Context, bind bindFunc) (reflect.Value, error) {
+		req := reflect.New(arg1) // 创建req实例
+		if defaults != nil {     // 请求数据中不存在的字段会保留默认值
+			defaults.Init(req.Elem())
+		}
+		if err := bind(ctx, req.Interface()); err != nil { // bind参数
+			return req, err
+		}
+		if defaults != nil { // 由请求数据创建的结构体
+			defaults.Complete(req.Elem())
+		}
+		if err := ctx.validBind(req.Interface()); err != nil { // 校验
+			return req, err
+		}
+
+		if reqIsPtr { // 如果req是指针, 直接返回
+			return req, nil
+		}
+		return req.Elem(), nil // 非指针要返回指向对象
+	}
+}
+
+// 构建调用者, 它会使用 bind 绑定req参数然后调用handler
+func (h *handlerUtil) makeCaller() func(ctx *Context, bind bindFunc) interface{} {
+	hValue := reflect.ValueOf(h.handler)
+	reqCreator := h.mustMakeReqCreator()
+	return func(ctx *Context, bind bindFunc) interface{} {
+		var outValues []reflect.Value
+
+		// 调用handler
+		if reqCreator == nil { // 如果没有req建造者, 表示不需要req参数
+			outValues = hValue.Call([]reflect.Value{reflect.ValueOf(ctx)})
+		} else {
+			reqValue, err := reqCreator(ctx, bind)
+			if err != nil {
+				return err
+			}
+			outValues = hValue.Call([]reflect.Value{reflect.ValueOf(ctx), reqValue})
+		}
+
+		// 检查结果
+		if len(outValues) == 1 { // 如果只有一个结果直接返回
+			return outValues[0].Interface()
+		}
+
+		err := outValues[1].Interface()
+		if err != nil {
+			return err.(error)
+		}
+		return outValues[0].Interface()
+	}
+}
+
+// 构建handler
+func (h *handlerUtil) makeHandler() Handler {
+	caller := h.makeCaller()
+	return func(ctx *Context) interface{} {
+		return caller(ctx, (*Context).readBind)
+	}
+}
+
+func (h *handlerUtil) MakeHandler() Handler {
+	fn, ok := h.handler.(Handler)
+	if !ok {
+		h.checkHandlerFingerprint() // 检查handler指纹
+		fn = h.makeHandler()        // 构建handler
+	}
+	return func(ctx *Context) interface{} {
+		ctx.Values().Set("_handler_name", h.name)
+		return fn(ctx)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+)
+
+// 保存在请求中的拦截器列表
+const interceptorsFieldKey = "_interceptors"
+
+// 响应拦截器, 在处理程序返回后且结果写入到客户端前调用
+//
+// result 和 err 只有一个会有值, 返回的 result 和 err 会替换原来的值, 返回的 err 不为nil时会忽略 result.
+// 可以用于错误转换, 按角色屏蔽字段, 添加分页信息等
+type Interceptor = func(ctx *Context, result interface{}, err error) (interface{}, error)
+
+// 创建一个注册拦截器的中间件, 可以用于 Party 或单个路由
+//
+// 拦截器按注册顺序从外到内执行: 先执行全局拦截器, 然后按 Party 的嵌套顺序执行, 同一次注册的拦截器按参数顺序执行
+//
+//	users := router.Party("/users", api.Intercept(MaskByRole, AddPagination))
+func Intercept(fns ...Interceptor) iris.Handler {
+	for _, fn := range fns {
+		if fn == nil {
+			panic("Interceptor is nil")
+		}
+	}
+	return func(irisCtx *iris_context.Context) {
+		old, _ := irisCtx.Values().Get(interceptorsFieldKey).([]Interceptor)
+		// 复制一份, 避免修改其它请求共享的底层数组
+		interceptors := make([]Interceptor, 0, len(old)+len(fns))
+		interceptors = append(interceptors, old...)
+		interceptors = append(interceptors, fns...)
+		irisCtx.Values().Set(interceptorsFieldKey, interceptors)
+		irisCtx.Next()
+	}
+}
+
+// 执行请求的所有拦截器, 返回最终要写入的结果, 如果有错误返回的是错误
+func runInterceptors(ctx *Context, result interface{}) interface{} {
+	interceptors, _ := ctx.Values().Get(interceptorsFieldKey).([]Interceptor)
+	if len(interceptors) == 0 {
+		return result
+	}
+
+	err, _ := result.(error)
+	if err != nil {
+		result = nil
+	}
+	for _, fn := range interceptors {
+		result, err = fn(ctx, result, err)
+		if err != nil { // 返回错误时忽略结果, 后面的拦截器只会收到错误
+			result = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/zly-app/service/api/config"
+)
+
+func TestInterceptor(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Check()
+
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(ctx *Context, result interface{}, err error) (interface{}, error) {
+			calls = append(calls, name)
+			return result, err
+		}
+	}
+	handlerCalled := false
+
+	s := NewApiService(getTestApp(), conf, WithInterceptor(record("global")))
+	party := s.Party("/interceptor", Intercept(record("party1"), record("party2")))
+	party.Get("/order", Intercept(record("route")), Wrap(func(ctx *Context) interface{} {
+		handlerCalled = true
+		return "ok"
+	}))
+	// 中间件返回错误时不会执行处理程序, 拦截器仍然会处理这个错误
+	party.Get("/blocked", WrapMiddleware(func(ctx *Context) error {
+		return AuthorizationError
+	}), Wrap(func(ctx *Context) interface{} {
+		handlerCalled = true
+		return "ok"
+	}))
+	// 拦截器把错误改写为结果
+	party.Get("/recover", Intercept(func(ctx *Context, result interface{}, err error) (interface{}, error) {
+		if err != nil {
+			return "recovered: " + err.Error(), nil
+		}
+		return result, err
+	}), Wrap(func(ctx *Context) error {
+		handlerCalled = true
+		return errors.New("failed")
+	}))
+	// 拦截器返回错误时会忽略结果, 后面的拦截器收到的是这个错误
+	party.Get("/reject", Intercept(func(ctx *Context, result interface{}, err error) (interface{}, error) {
+		return result, ParamError
+	}, func(ctx *Context, result interface{}, err error) (interface{}, error) {
+		calls = append(calls, "after reject")
+		if result != nil || err != ParamError {
+			t.Errorf("后面的拦截器应该只收到错误, 实际为 %v, %v", result, err)
+		}
+		return result, err
+	}), Wrap(func(ctx *Context) interface{} {
+		handlerCalled = true
+		return "ok"
+	}))
+	// 拦截器改写结果
+	party.Get("/rewrite", Intercept(func(ctx *Context, result interface{}, err error) (interface{}, error) {
+		return strings.ToUpper(result.(string)), err
+	}), Wrap(func(ctx *Context) interface{} {
+		handlerCalled = true
+		return "ok"
+	}))
+	if err := s.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url     string
+		code    int
+		data    string
+		handler bool
+		calls   string
+	}{
+		{url: "/order", code: OK.Code, data: "ok", handler: true, calls: "global,party1,party2,route"},
+		{url: "/blocked", code: AuthorizationError.Code, calls: "global,party1,party2"},
+		{url: "/recover", code: OK.Code, data: "recovered: failed", handler: true, calls: "global,party1,party2"},
+		{url: "/reject", code: ParamError.Code, handler: true, calls: "global,party1,party2,after reject"},
+		{url: "/rewrite", code: OK.Code, data: "OK", handler: true, calls: "global,party1,party2"},
+	}
+	for _, test := range tests {
+		calls, handlerCalled = nil, false
+		rsp := serveTest(t, s, http.MethodGet, "/interceptor"+test.url, nil)
+		if rsp.ErrCode != test.code {
+			t.Fatalf("%s: 错误码应该为 %d, 实际为 %d, %s", test.url, test.code, rsp.ErrCode, rsp.ErrMsg)
+		}
+		if data, _ := rsp.Data.(string); data != test.data {
+			t.Fatalf("%s: 数据应该为 %q, 实际为 %v", test.url, test.data, rsp.Data)
+		}
+		if handlerCalled != test.handler {
+			t.Fatalf("%s: 处理程序是否执行应该为 %v", test.url, test.handler)
+		}
+		if got := strings.Join(calls, ","); got != test.calls {
+			t.Fatalf("%s: 拦截器执行顺序应该为 %s, 实际为 %s", test.url, test.calls, got)
+		}
+	}
+}
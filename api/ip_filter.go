@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp/logger"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/utils"
+)
+
+// ip访问控制
+type ipFilter struct {
+	allow utils.IPNets
+	deny  utils.IPNets
+}
+
+// 创建ip访问控制, allow 和 deny 为英文逗号分隔的ip或CIDR
+func newIPFilter(allow, deny string) (*ipFilter, error) {
+	allowNets, err := utils.ParseIPNets(allow)
+	if err != nil {
+		return nil, err
+	}
+	denyNets, err := utils.ParseIPNets(deny)
+	if err != nil {
+		return nil, err
+	}
+	return &ipFilter{allow: allowNets, deny: denyNets}, nil
+}
+
+// 检查ip是否允许访问, deny 优先于 allow, allow 为空时允许所有不在 deny 中的ip
+func (f *ipFilter) Allowed(ip net.IP) bool {
+	if f.deny.Contains(ip) {
+		return false
+	}
+	return len(f.allow) == 0 || f.allow.Contains(ip)
+}
+
+func (f *ipFilter) Handler() iris.Handler {
+	return WrapMiddleware(func(ctx *Context) error {
+		ip := ctx.RemoteAddr()
+		if !f.Allowed(net.ParseIP(ip)) {
+			ctx.Warn("api.ip_forbidden", zap.String("ip", ip))
+			return IPForbidden
+		}
+		return nil
+	})
+}
+
+// ip访问控制中间件, 可以用于 Party 或路由, 会在全局的 IPAllow 和 IPDeny 之后检查, 被拒绝的请求会返回 IPForbidden
+//
+// allow 和 deny 为英文逗号分隔的ip或CIDR, deny 优先于 allow, allow 为空时允许所有不在 deny 中的ip
+//
+//	internal := router.Party("/internal", api.IPFilter("10.0.0.0/8,127.0.0.1", ""))
+func IPFilter(allow, deny string) iris.Handler {
+	f, err := newIPFilter(allow, deny)
+	if err != nil {
+		logger.Log.Fatal("ip访问控制配置错误", zap.String("allow", allow), zap.String("deny", deny), zap.Error(err))
+	}
+	return f.Handler()
+}
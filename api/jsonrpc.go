@@ -0,0 +1,301 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+	app_config "github.com/zly-app/zapp/config"
+	"github.com/zly-app/zapp/logger"
+	app_utils "github.com/zly-app/zapp/pkg/utils"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/utils"
+)
+
+const JsonRpcVersion = "2.0"
+
+// json-rpc 2.0 规范定义的错误码
+const (
+	RpcParseError     = -32700 // 解析请求失败
+	RpcInvalidRequest = -32600 // 无效的请求
+	RpcMethodNotFound = -32601 // 方法不存在
+	RpcInvalidParams  = -32602 // 无效的参数
+	RpcInternalError  = -32603 // 内部错误
+)
+
+type rpcRequest struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"` // 为nil表示这是一个通知
+}
+
+type rpcResponse struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// json-rpc 错误
+type RpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// 附加在 RpcError 中的 api.Error 信息
+type rpcErrorData struct {
+	ErrCode int    `json:"err_code"`
+	ErrMsg  string `json:"err_msg"`
+}
+
+var nullID = json.RawMessage("null")
+
+// 默认批量请求最多包含的请求数
+const defaultRpcMaxBatchSize = 100
+
+type rpcMethod struct {
+	name   string
+	caller func(ctx *Context, bind bindFunc) interface{}
+}
+
+// json-rpc 2.0 服务, 方法的处理程序和 Wrap 接受的处理程序相同, 同一个处理程序可以同时用于 rest 和 rpc
+//
+//	rpc := api.NewJsonRpc().
+//	    Register("user.get", GetUser).
+//	    Register("user.list", ListUser)
+//	router.Post("/rpc", rpc.Handler())
+//	router.Get("/user", api.Wrap(GetUser))
+type JsonRpc struct {
+	methods      map[string]*rpcMethod
+	maxBatchSize int
+	mx           sync.RWMutex
+}
+
+func NewJsonRpc() *JsonRpc {
+	return &JsonRpc{methods: make(map[string]*rpcMethod), maxBatchSize: defaultRpcMaxBatchSize}
+}
+
+// 设置批量请求最多包含的请求数, 默认为100, 小于1表示不限制, 超出时返回 RpcInvalidRequest
+func (r *JsonRpc) SetMaxBatchSize(size int) *JsonRpc {
+	r.mx.Lock()
+	r.maxBatchSize = size
+	r.mx.Unlock()
+	return r
+}
+
+// 注册方法, handler 的函数格式参考 Wrap, 方法的 params 会绑定到handler的第二个入参并校验
+func (r *JsonRpc) Register(method string, handler interface{}) *JsonRpc {
+	h := newHandler(handler)
+	h.checkHandlerFingerprint()
+
+	r.mx.Lock()
+	if _, ok := r.methods[method]; ok {
+		r.mx.Unlock()
+		logger.Log.Fatal("json-rpc方法重复注册", zap.String("method", method))
+	}
+	r.methods[method] = &rpcMethod{name: h.name, caller: h.makeCaller()}
+	r.mx.Unlock()
+	return r
+}
+
+// 获取json-rpc处理程序, 支持批量请求和通知
+func (r *JsonRpc) Handler() iris.Handler {
+	return func(irisCtx *iris_context.Context) {
+		ctx := makeContext(irisCtx)
+
+		body, err := ctx.GetBody()
+		if err != nil {
+			r.writeResponse(ctx, newRpcErrorResponse(nullID, RpcParseError, err.Error()))
+			return
+		}
+		body = bytes.TrimSpace(body)
+
+		// 单个请求
+		if len(body) == 0 || body[0] != '[' {
+			var req rpcRequest
+			if err = json.Unmarshal(body, &req); err != nil {
+				r.writeResponse(ctx, newRpcErrorResponse(nullID, RpcParseError, err.Error()))
+				return
+			}
+			if rsp := r.call(ctx, &req, false); rsp != nil {
+				r.writeResponse(ctx, rsp)
+				return
+			}
+			r.writeResponse(ctx, nil)
+			return
+		}
+
+		// 批量请求
+		var reqs []json.RawMessage
+		if err = json.Unmarshal(body, &reqs); err != nil {
+			r.writeResponse(ctx, newRpcErrorResponse(nullID, RpcParseError, err.Error()))
+			return
+		}
+		if len(reqs) == 0 {
+			r.writeResponse(ctx, newRpcErrorResponse(nullID, RpcInvalidRequest, "empty batch"))
+			return
+		}
+		r.mx.RLock()
+		maxBatchSize := r.maxBatchSize
+		r.mx.RUnlock()
+		if maxBatchSize > 0 && len(reqs) > maxBatchSize {
+			ctx.Warn("api.rpc batch too large", zap.Int("size", len(reqs)), zap.Int("max_batch_size", maxBatchSize))
+			r.writeResponse(ctx, newRpcErrorResponse(nullID, RpcInvalidRequest, fmt.Sprintf("batch too large, max size is %d", maxBatchSize)))
+			return
+		}
+
+		rsps := make([]*rpcResponse, 0, len(reqs))
+		for _, raw := range reqs {
+			var req rpcRequest
+			if err = json.Unmarshal(raw, &req); err != nil {
+				rsps = append(rsps, newRpcErrorResponse(nullID, RpcInvalidRequest, err.Error()))
+				continue
+			}
+			if rsp := r.call(ctx, &req, true); rsp != nil {
+				rsps = append(rsps, rsp)
+			}
+		}
+		if len(rsps) == 0 { // 全部是通知
+			r.writeResponse(ctx, nil)
+			return
+		}
+		r.writeResponse(ctx, rsps)
+	}
+}
+
+// 调用一个方法, 如果是通知返回nil, 无效的请求即使没有id也会返回错误
+func (r *JsonRpc) call(ctx *Context, req *rpcRequest, inBatch bool) *rpcResponse {
+	if req.JsonRpc != JsonRpcVersion || req.Method == "" {
+		id := req.ID
+		if id == nil {
+			id = nullID
+		}
+		return newRpcErrorResponse(id, RpcInvalidRequest, "invalid request")
+	}
+
+	rsp := r.invoke(ctx, req, inBatch)
+	if req.ID == nil {
+		return nil
+	}
+	return rsp
+}
+
+// 执行一个方法, inBatch 表示是否为批量请求中的一个请求
+func (r *JsonRpc) invoke(ctx *Context, req *rpcRequest, inBatch bool) *rpcResponse {
+	id := req.ID
+	if id == nil {
+		id = nullID
+	}
+
+	r.mx.RLock()
+	method, ok := r.methods[req.Method]
+	r.mx.RUnlock()
+	if !ok {
+		ctx.Warn("api.rpc method not found", zap.String("rpc_method", req.Method))
+		return newRpcErrorResponse(id, RpcMethodNotFound, "method not found: "+req.Method)
+	}
+
+	startTime := time.Now()
+	if !inBatch { // 批量请求包含多个方法, 请求的处理程序名保持为json-rpc的处理程序, 每个方法的日志中会带上自己的处理程序名
+		ctx.Values().Set("_handler_name", method.name)
+	}
+	var result interface{}
+	err := app_utils.Recover.WrapCall(func() error {
+		result = method.caller(ctx, func(ctx *Context, a interface{}) error {
+			if len(req.Params) > 0 {
+				if err := json.Unmarshal(req.Params, a); err != nil {
+					return ParamError.WithError(err)
+				}
+			}
+			return nil
+		})
+		result = runInterceptors(ctx, result)
+		return nil
+	})
+	if err != nil { // panic
+		ctx.Error("api.rpc panic",
+			zap.String("rpc_method", req.Method),
+			zap.String("handler_name", method.name),
+			zap.String("detail", app_utils.Recover.GetRecoverErrorDetail(err)),
+		)
+		reportError(ctx.IrisContext, err, true, method.name)
+		return newRpcErrorResponse(id, RpcInternalError, ServiceInternalError.Message)
+	}
+
+	fields := []interface{}{
+		zap.String("rpc_method", req.Method),
+		zap.String("handler_name", method.name),
+		zap.ByteString("rpc_id", id),
+		zap.Bool("notification", req.ID == nil),
+		zap.Duration("latency", time.Since(startTime)),
+	}
+	if err, ok := result.(error); ok {
+		ctx.Warn(append([]interface{}{"api.rpc call failed", zap.Error(err)}, fields...)...)
+		if code, _ := decodeErr(err); code == ServiceInternalError.Code {
+			reportError(ctx.IrisContext, err, false, method.name)
+		}
+		return newRpcResponseOfErr(ctx, id, err)
+	}
+	ctx.Debug(append([]interface{}{"api.rpc call"}, fields...)...)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return newRpcErrorResponse(id, RpcInternalError, fmt.Sprintf("marshal result failed: %s", err))
+	}
+	return &rpcResponse{JsonRpc: JsonRpcVersion, Result: data, ID: id}
+}
+
+// 写入响应, rsp 为nil时表示不需要响应
+func (r *JsonRpc) writeResponse(ctx *Context, rsp interface{}) {
+	defer ctx.StopExecution()
+	if rsp == nil {
+		ctx.StatusCode(iris.StatusNoContent)
+		return
+	}
+	ctx.Values().Set("result", rsp)
+	_, _ = ctx.JSON(rsp)
+}
+
+func newRpcErrorResponse(id json.RawMessage, code int, message string) *rpcResponse {
+	return &rpcResponse{
+		JsonRpc: JsonRpcVersion,
+		Error:   &RpcError{Code: code, Message: message},
+		ID:      id,
+	}
+}
+
+// 将 api.Error 转为 json-rpc 错误
+//
+// ParamError 会转为 RpcInvalidParams, ServiceInternalError 和非 api.Error 的错误会转为 RpcInternalError, 其它错误会直接使用 api.Error 的错误码
+func newRpcResponseOfErr(ctx *Context, id json.RawMessage, err error) *rpcResponse {
+	code, message := decodeErr(err)
+
+	conf := utils.Context.MustGetConfFromIrisContext(ctx.IrisContext)
+	if app_config.Conf.Config().Frame.Debug || conf.SendDetailedErrorInProduction {
+		message = err.Error()
+	}
+
+	rpcCode := code
+	switch code {
+	case ParamError.Code:
+		rpcCode = RpcInvalidParams
+	case ServiceInternalError.Code:
+		rpcCode = RpcInternalError
+	}
+	return &rpcResponse{
+		JsonRpc: JsonRpcVersion,
+		Error: &RpcError{
+			Code:    rpcCode,
+			Message: message,
+			Data:    &rpcErrorData{ErrCode: code, ErrMsg: message},
+		},
+		ID: id,
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	iris_context "github.com/kataras/iris/v12/context"
+
+	"github.com/zly-app/service/api/config"
+)
+
+type rpcTestReq struct {
+	A int `json:"a"`
+}
+
+func rpcTestAdd(ctx *Context, req *rpcTestReq) (interface{}, error) {
+	return req.A + 1, nil
+}
+
+func rpcTestEcho(ctx *Context, req *rpcTestReq) (interface{}, error) {
+	return req.A, nil
+}
+
+func TestJsonRpc(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Check()
+	s := NewApiService(getTestApp(), conf)
+
+	var handlerName string
+	saveHandlerName := func(irisCtx *iris_context.Context) {
+		irisCtx.Next()
+		handlerName = irisCtx.Values().GetString("_handler_name")
+	}
+	rpc := NewJsonRpc().
+		Register("add", rpcTestAdd).
+		Register("echo", rpcTestEcho).
+		SetMaxBatchSize(2)
+	s.Post("/rpc", saveHandlerName, rpc.Handler())
+	if err := s.Build(); err != nil {
+		t.Fatal(err)
+	}
+	post := func(body string) (int, string) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/rpc", strings.NewReader(body)))
+		return w.Code, w.Body.String()
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		id     string
+		code   int // json-rpc 错误码, 0表示成功
+		result string
+	}{
+		{name: "call", body: `{"jsonrpc":"2.0","method":"add","params":{"a":1},"id":1}`, id: "1", result: "2"},
+		{name: "invalid without id", body: `{"foo":1}`, id: "null", code: RpcInvalidRequest},
+		{name: "invalid version without id", body: `{"jsonrpc":"1.0","method":"add"}`, id: "null", code: RpcInvalidRequest},
+		{name: "invalid with id", body: `{"jsonrpc":"2.0","id":"x"}`, id: `"x"`, code: RpcInvalidRequest},
+		{name: "method not found", body: `{"jsonrpc":"2.0","method":"none","id":2}`, id: "2", code: RpcMethodNotFound},
+		{name: "parse error", body: `{`, id: "null", code: RpcParseError},
+		{name: "batch too large", body: `[{"jsonrpc":"2.0","method":"add","id":1},{"jsonrpc":"2.0","method":"add","id":2},{"jsonrpc":"2.0","method":"add","id":3}]`, id: "null", code: RpcInvalidRequest},
+	}
+	for _, test := range tests {
+		_, body := post(test.body)
+		var rsp rpcResponse
+		if err := json.Unmarshal([]byte(body), &rsp); err != nil {
+			t.Fatalf("%s: 解析响应失败: %v, %s", test.name, err, body)
+		}
+		if string(rsp.ID) != test.id {
+			t.Fatalf("%s: id应该为 %s, 实际为 %s", test.name, test.id, rsp.ID)
+		}
+		if test.code == 0 {
+			if rsp.Error != nil || string(rsp.Result) != test.result {
+				t.Fatalf("%s: 结果应该为 %s, 实际为 %s", test.name, test.result, body)
+			}
+			continue
+		}
+		if rsp.Error == nil || rsp.Error.Code != test.code {
+			t.Fatalf("%s: 错误码应该为 %d, 实际为 %s", test.name, test.code, body)
+		}
+	}
+
+	// 通知没有响应
+	if code, body := post(`{"jsonrpc":"2.0","method":"add","params":{"a":1}}`); code != 204 || body != "" {
+		t.Fatalf("通知不应该有响应: %d, %s", code, body)
+	}
+
+	// 单个请求的处理程序名为方法的处理程序
+	post(`{"jsonrpc":"2.0","method":"echo","id":1}`)
+	if handlerName != "github.com/zly-app/service/api.rpcTestEcho" {
+		t.Fatalf("单个请求的处理程序名错误: %s", handlerName)
+	}
+
+	// 批量请求不会被最后一个方法覆盖处理程序名
+	handlerName = ""
+	_, body := post(`[{"jsonrpc":"2.0","method":"add","params":{"a":1},"id":1},{"jsonrpc":"2.0","method":"echo","params":{"a":5},"id":2}]`)
+	var rsps []rpcResponse
+	if err := json.Unmarshal([]byte(body), &rsps); err != nil {
+		t.Fatalf("解析批量响应失败: %v, %s", err, body)
+	}
+	if len(rsps) != 2 || string(rsps[0].Result) != "2" || string(rsps[1].Result) != "5" {
+		t.Fatalf("批量响应错误: %s", body)
+	}
+	if strings.Contains(handlerName, "rpcTest") {
+		t.Fatalf("批量请求的处理程序名不应该是某个方法的处理程序: %s", handlerName)
+	}
+}
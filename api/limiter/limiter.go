@@ -0,0 +1,210 @@
+package limiter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 优先级, 优先级越低越先被丢弃
+type Priority int
+
+const (
+	// 低优先级
+	PriorityLow Priority = iota
+	// 普通优先级
+	PriorityNormal
+	// 高优先级, 如付费用户的请求
+	PriorityHigh
+	// 关键优先级, 如健康检查, 最后才会被丢弃
+	PriorityCritical
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("undefined priority: %d", p)
+}
+
+// 这个优先级可以使用的并发限制比例
+func (p Priority) share() float64 {
+	switch {
+	case p <= PriorityLow:
+		return 0.5
+	case p == PriorityNormal:
+		return 0.8
+	case p == PriorityHigh:
+		return 0.9
+	}
+	return 1
+}
+
+// 限流算法
+type Algorithm string
+
+const (
+	// 加性增乘性减, 延迟超过目标延迟时按比例减少并发限制, 否则逐步增加
+	AIMD Algorithm = "aimd"
+	// 梯度算法, 根据最小延迟和当前延迟的比值调整并发限制
+	Gradient Algorithm = "gradient"
+)
+
+// 自适应限流器
+type ILimiter interface {
+	// 尝试获取一个执行许可, 返回 false 表示请求应该被丢弃
+	Acquire(priority Priority) bool
+	// 释放执行许可并根据请求的延迟调整并发限制
+	Release(latency time.Duration)
+	// 当前并发限制
+	Limit() int
+	// 当前正在执行的请求数
+	Inflight() int
+}
+
+type Config struct {
+	Algorithm     Algorithm     // 限流算法
+	InitialLimit  int           // 初始并发限制
+	MinLimit      int           // 最小并发限制
+	MaxLimit      int           // 最大并发限制
+	TargetLatency time.Duration // aimd目标延迟, 延迟超过这个值时减少并发限制
+	BackoffRatio  float64       // aimd减少并发限制的比例, 取值范围 (0, 1)
+	Smoothing     float64       // gradient平滑系数, 取值范围 (0, 1]
+	MinRTTWindow  int           // gradient最小延迟采样窗口, 每采样这么多次后重新统计最小延迟
+}
+
+type Limiter struct {
+	conf Config
+
+	limit    float64
+	inflight int
+
+	minRTT     time.Duration // gradient观测到的最小延迟
+	rttSamples int           // gradient当前窗口的采样次数
+
+	mx sync.Mutex
+}
+
+// 创建一个自适应限流器
+func NewLimiter(conf Config) ILimiter {
+	conf.Algorithm = Algorithm(strings.ToLower(string(conf.Algorithm)))
+	if conf.MinLimit < 1 {
+		conf.MinLimit = 1
+	}
+	if conf.MaxLimit < conf.MinLimit {
+		conf.MaxLimit = conf.MinLimit
+	}
+	if conf.InitialLimit < conf.MinLimit {
+		conf.InitialLimit = conf.MinLimit
+	}
+	if conf.InitialLimit > conf.MaxLimit {
+		conf.InitialLimit = conf.MaxLimit
+	}
+	if conf.BackoffRatio <= 0 || conf.BackoffRatio >= 1 {
+		conf.BackoffRatio = 0.9
+	}
+	if conf.Smoothing <= 0 || conf.Smoothing > 1 {
+		conf.Smoothing = 0.2
+	}
+	if conf.MinRTTWindow < 1 {
+		conf.MinRTTWindow = 1000
+	}
+	return &Limiter{
+		conf:  conf,
+		limit: float64(conf.InitialLimit),
+	}
+}
+
+func (l *Limiter) Acquire(priority Priority) bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	max := int(math.Ceil(l.limit * priority.share()))
+	if max < 1 {
+		max = 1
+	}
+	if l.inflight >= max {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+func (l *Limiter) Release(latency time.Duration) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+
+	switch l.conf.Algorithm {
+	case Gradient:
+		l.gradient(latency, inflight)
+	default:
+		l.aimd(latency, inflight)
+	}
+
+	if l.limit < float64(l.conf.MinLimit) {
+		l.limit = float64(l.conf.MinLimit)
+	}
+	if l.limit > float64(l.conf.MaxLimit) {
+		l.limit = float64(l.conf.MaxLimit)
+	}
+}
+
+func (l *Limiter) aimd(latency time.Duration, inflight int) {
+	if l.conf.TargetLatency > 0 && latency > l.conf.TargetLatency {
+		l.limit *= l.conf.BackoffRatio
+		return
+	}
+	// 只有在并发限制被充分使用时才增加, 防止空闲时限制无限增长
+	if float64(inflight)*2 >= l.limit {
+		l.limit++
+	}
+}
+
+func (l *Limiter) gradient(latency time.Duration, inflight int) {
+	if latency <= 0 {
+		latency = 1
+	}
+
+	l.rttSamples++
+	if l.minRTT == 0 || latency < l.minRTT || l.rttSamples > l.conf.MinRTTWindow {
+		l.minRTT = latency
+		l.rttSamples = 0
+	}
+
+	// 并发限制未被充分使用时不调整
+	if float64(inflight)*2 < l.limit {
+		return
+	}
+
+	gradient := float64(l.minRTT) / float64(latency)
+	gradient = math.Max(0.5, math.Min(1, gradient))
+	queueSize := math.Sqrt(l.limit)
+	newLimit := l.limit*gradient + queueSize
+	l.limit = l.limit*(1-l.conf.Smoothing) + newLimit*l.conf.Smoothing
+}
+
+func (l *Limiter) Limit() int {
+	l.mx.Lock()
+	limit := int(l.limit)
+	l.mx.Unlock()
+	return limit
+}
+
+func (l *Limiter) Inflight() int {
+	l.mx.Lock()
+	inflight := l.inflight
+	l.mx.Unlock()
+	return inflight
+}
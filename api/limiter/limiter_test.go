@@ -0,0 +1,110 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 模拟一个慢处理程序, 并发越高延迟越高
+func runSlowHandler(l ILimiter, priority Priority, workers, requests int, baseLatency time.Duration) (shed int64) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				if !l.Acquire(priority) {
+					atomic.AddInt64(&shed, 1)
+					continue
+				}
+				latency := baseLatency * time.Duration(l.Inflight())
+				time.Sleep(latency)
+				l.Release(latency)
+			}
+		}()
+	}
+	wg.Wait()
+	return
+}
+
+func TestLimiter_AIMD(t *testing.T) {
+	l := NewLimiter(Config{
+		Algorithm:     AIMD,
+		InitialLimit:  50,
+		MinLimit:      2,
+		MaxLimit:      100,
+		TargetLatency: 5 * time.Millisecond,
+	})
+	shed := runSlowHandler(l, PriorityNormal, 40, 20, time.Millisecond)
+	if shed == 0 {
+		t.Fatal("慢处理程序应该触发丢弃")
+	}
+	if l.Limit() >= 50 {
+		t.Fatalf("并发限制应该降低, 当前为 %d", l.Limit())
+	}
+	if l.Inflight() != 0 {
+		t.Fatalf("执行结束后正在执行的请求数应该为0, 当前为 %d", l.Inflight())
+	}
+}
+
+func TestLimiter_Gradient(t *testing.T) {
+	l := NewLimiter(Config{
+		Algorithm:    Gradient,
+		InitialLimit: 50,
+		MinLimit:     2,
+		MaxLimit:     100,
+	})
+	runSlowHandler(l, PriorityNormal, 40, 20, time.Millisecond)
+	if l.Limit() >= 50 {
+		t.Fatalf("并发限制应该降低, 当前为 %d", l.Limit())
+	}
+}
+
+func TestLimiter_Priority(t *testing.T) {
+	l := NewLimiter(Config{Algorithm: AIMD, InitialLimit: 10, MinLimit: 10, MaxLimit: 10})
+	for i := 0; i < 5; i++ {
+		if !l.Acquire(PriorityLow) {
+			t.Fatal("低优先级在限制的一半以内应该能获取许可")
+		}
+	}
+	if l.Acquire(PriorityLow) {
+		t.Fatal("低优先级超出限制的一半应该被丢弃")
+	}
+	for i := 0; i < 3; i++ {
+		if !l.Acquire(PriorityNormal) {
+			t.Fatal("普通优先级应该能获取许可")
+		}
+	}
+	if l.Acquire(PriorityNormal) {
+		t.Fatal("普通优先级超出限制应该被丢弃")
+	}
+	if !l.Acquire(PriorityHigh) {
+		t.Fatal("高优先级应该能获取许可")
+	}
+	if !l.Acquire(PriorityCritical) {
+		t.Fatal("关键优先级应该能获取许可")
+	}
+	if l.Acquire(PriorityCritical) {
+		t.Fatal("超出限制后关键优先级也应该被丢弃")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(10, 5)
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		if l.Allow() {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Fatalf("突发请求数应该为5, 实际为 %d", allowed)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("等待后应该产生新的令牌")
+	}
+}
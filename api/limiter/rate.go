@@ -0,0 +1,50 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// 令牌桶限流器, 限制每秒处理的请求数
+type RateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mx     sync.Mutex
+}
+
+// 创建令牌桶限流器, rate 为每秒产生的令牌数, burst 为允许突发的请求数, burst 小于1时等于 rate
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = int(rate)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// 获取一个令牌, 没有令牌时返回false
+func (l *RateLimiter) Allow() bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	iris_context "github.com/kataras/iris/v12/context"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 读取 PROXY protocol 头的超时时间
+const proxyProtocolHeaderTimeout = 5 * time.Second
+
+type listenerNameKey struct{}
+
+// 获取请求来自哪个监听器
+func GetListenerName(ctx *Context) string {
+	return getListenerName(ctx.IrisContext)
+}
+
+func getListenerName(irisCtx *iris_context.Context) string {
+	name, _ := irisCtx.Request().Context().Value(listenerNameKey{}).(string)
+	return name
+}
+
+// 限制只有来自指定监听器的请求可以访问, 其它监听器会返回404, 就像这个路由不存在一样
+//
+//	internal := router.Party("/internal", api.OnlyListeners("internal"))
+func OnlyListeners(names ...string) iris_context.Handler {
+	return func(irisCtx *iris_context.Context) {
+		if !inStrings(names, getListenerName(irisCtx)) {
+			irisCtx.NotFound()
+			irisCtx.StopExecution()
+			return
+		}
+		irisCtx.Next()
+	}
+}
+
+// 创建一个只能通过指定监听器访问的 Party
+func ListenerParty(party Party, relativePath string, names ...string) Party {
+	return party.Party(relativePath, OnlyListeners(names...))
+}
+
+// 创建监听器
+func newListener(conf config.ListenerConfig) (net.Listener, error) {
+	var l net.Listener
+	var err error
+	switch conf.Network {
+	case "tcp", "tcp4", "tcp6":
+		l, err = net.Listen(conf.Network, conf.Addr)
+	case "unix":
+		l, err = newUnixListener(conf)
+	default:
+		return nil, fmt.Errorf("不支持的监听器网络类型: %s", conf.Network)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.ProxyProtocol {
+		l = &proxyProtocolListener{Listener: l}
+	}
+	return l, nil
+}
+
+func newUnixListener(conf config.ListenerConfig) (net.Listener, error) {
+	// 删除上次没有清理的socket文件
+	if info, err := os.Stat(conf.Addr); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("文件 %s 已存在且不是socket文件", conf.Addr)
+		}
+		if err = os.Remove(conf.Addr); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", conf.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if conf.FileMode != "" {
+		mode, err := strconv.ParseUint(conf.FileMode, 8, 32)
+		if err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("unix socket 文件权限 %s 无效: %s", conf.FileMode, err)
+		}
+		if err = os.Chmod(conf.Addr, os.FileMode(mode)); err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// 创建一个将监听器名写入请求context的 http.Server
+func newListenerServer(lc config.ListenerConfig, conf *config.Config) *http.Server {
+	srv := &http.Server{
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, listenerNameKey{}, lc.Name)
+		},
+	}
+	if lc.Network != "unix" {
+		srv.Addr = lc.Addr // iris 会根据它解析虚拟主机
+	}
+	applyServerLimits(srv, conf)
+	return srv
+}
+
+// 支持 PROXY protocol 的监听器
+//
+// 头部会在连接第一次读取或获取远程地址时解析, 避免慢客户端阻塞 Accept. 没有头部的连接会被拒绝
+type proxyProtocolListener struct {
+	net.Listener
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newProxyProtocolConn(conn, proxyProtocolHeaderTimeout), nil
+}
+
+type proxyProtocolConn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration // 读取头部的超时时间
+
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+	localAddr  net.Addr
+
+	mx           sync.Mutex
+	readDeadline time.Time // 外部设置的读取超时, 读取头部后恢复
+}
+
+func newProxyProtocolConn(conn net.Conn, timeout time.Duration) *proxyProtocolConn {
+	return &proxyProtocolConn{Conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyProtocolConn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *proxyProtocolConn) SetDeadline(t time.Time) error {
+	c.mx.Lock()
+	c.readDeadline = t
+	c.mx.Unlock()
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *proxyProtocolConn) SetReadDeadline(t time.Time) error {
+	c.mx.Lock()
+	c.readDeadline = t
+	c.mx.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+var (
+	proxyProtocolV1Prefix = []byte("PROXY ")
+	proxyProtocolV2Prefix = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// v1 头部的最大长度, 包含结尾的 \r\n
+const proxyProtocolV1MaxLen = 107
+
+// 没有 PROXY protocol 头
+var errProxyProtocolHeaderMissing = errors.New("缺少 PROXY protocol 头")
+
+// 解析 PROXY protocol 头, 没有头或头部无效时之后的读取都会返回错误
+func (c *proxyProtocolConn) readHeader() {
+	// 读取头部时使用单独的超时, 不会晚于外部设置的超时, 读取后恢复外部设置的超时
+	c.mx.Lock()
+	deadline := c.readDeadline
+	c.mx.Unlock()
+	headerDeadline := time.Now().Add(c.timeout)
+	if !deadline.IsZero() && deadline.Before(headerDeadline) {
+		headerDeadline = deadline
+	}
+	_ = c.Conn.SetReadDeadline(headerDeadline)
+	defer func() { _ = c.Conn.SetReadDeadline(deadline) }()
+
+	// v1 的前缀更短, 先判断v1
+	prefix, err := c.r.Peek(len(proxyProtocolV1Prefix))
+	if err != nil {
+		c.err = fmt.Errorf("读取 PROXY protocol 头失败: %s", err)
+		return
+	}
+	if bytes.Equal(prefix, proxyProtocolV1Prefix) {
+		c.err = c.readHeaderV1()
+		return
+	}
+	if !bytes.HasPrefix(proxyProtocolV2Prefix, prefix) {
+		c.err = errProxyProtocolHeaderMissing
+		return
+	}
+	prefix, err = c.r.Peek(len(proxyProtocolV2Prefix))
+	if err != nil {
+		c.err = fmt.Errorf("读取 PROXY protocol 头失败: %s", err)
+		return
+	}
+	if !bytes.Equal(prefix, proxyProtocolV2Prefix) {
+		c.err = errProxyProtocolHeaderMissing
+		return
+	}
+	c.err = c.readHeaderV2()
+}
+
+// v1 格式: PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n
+func (c *proxyProtocolConn) readHeaderV1() error {
+	line := make([]byte, 0, proxyProtocolV1MaxLen)
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("读取 PROXY protocol v1 头失败: %s", err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= proxyProtocolV1MaxLen {
+			return errors.New("PROXY protocol v1 头过长")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return errors.New("PROXY protocol v1 头无效")
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) < 2 {
+		return errors.New("PROXY protocol v1 头无效")
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil
+	case "TCP4", "TCP6":
+	default:
+		return fmt.Errorf("不支持的 PROXY protocol v1 协议: %s", fields[1])
+	}
+	if len(fields) != 6 {
+		return errors.New("PROXY protocol v1 头无效")
+	}
+
+	srcIP, dstIP := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	srcPort, err1 := strconv.ParseUint(fields[4], 10, 16)
+	dstPort, err2 := strconv.ParseUint(fields[5], 10, 16)
+	if srcIP == nil || dstIP == nil || err1 != nil || err2 != nil {
+		return errors.New("PROXY protocol v1 头无效")
+	}
+	if fields[1] == "TCP4" && (srcIP.To4() == nil || dstIP.To4() == nil) {
+		return errors.New("PROXY protocol v1 地址和协议不匹配")
+	}
+	c.remoteAddr = &net.TCPAddr{IP: srcIP, Port: int(srcPort)}
+	c.localAddr = &net.TCPAddr{IP: dstIP, Port: int(dstPort)}
+	return nil
+}
+
+// v2 格式为二进制, 12字节签名 + 版本命令 + 协议族 + 2字节长度 + 地址
+func (c *proxyProtocolConn) readHeaderV2() error {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return fmt.Errorf("读取 PROXY protocol v2 头失败: %s", err)
+	}
+	if header[12]>>4 != 2 {
+		return errors.New("PROXY protocol v2 版本无效")
+	}
+	command, family := header[12]&0x0f, header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return fmt.Errorf("读取 PROXY protocol v2 地址失败: %s", err)
+	}
+	switch command {
+	case 0: // LOCAL, 代理自身发起的连接, 使用原始地址
+		return nil
+	case 1: // PROXY
+	default:
+		return fmt.Errorf("不支持的 PROXY protocol v2 命令: %d", command)
+	}
+
+	switch family >> 4 {
+	case 1: // AF_INET
+		if len(payload) < 12 {
+			return errors.New("PROXY protocol v2 地址长度无效")
+		}
+		c.remoteAddr = &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:]))}
+		c.localAddr = &net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:]))}
+	case 2: // AF_INET6
+		if len(payload) < 36 {
+			return errors.New("PROXY protocol v2 地址长度无效")
+		}
+		c.remoteAddr = &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:]))}
+		c.localAddr = &net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:]))}
+	}
+	return nil
+}
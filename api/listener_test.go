@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 生成 PROXY protocol v2 头
+func makeProxyProtocolV2Header(command, family byte, payload []byte) []byte {
+	header := append([]byte{}, proxyProtocolV2Prefix...)
+	header = append(header, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:], uint16(len(payload)))
+	return append(header, payload...)
+}
+
+func TestProxyProtocolConn(t *testing.T) {
+	v4Payload := []byte{1, 2, 3, 4, 5, 6, 7, 8, 0x03, 0xe8, 0x01, 0xbb}
+	v6Payload := make([]byte, 36)
+	copy(v6Payload[0:], net.ParseIP("2001:db8::1"))
+	copy(v6Payload[16:], net.ParseIP("2001:db8::2"))
+	binary.BigEndian.PutUint16(v6Payload[32:], 1000)
+	binary.BigEndian.PutUint16(v6Payload[34:], 443)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		remote string // 为空表示使用原始地址
+		local  string
+		err    bool
+	}{
+		{name: "v1 tcp4", data: []byte("PROXY TCP4 1.2.3.4 5.6.7.8 1000 443\r\n"), remote: "1.2.3.4:1000", local: "5.6.7.8:443"},
+		{name: "v1 tcp6", data: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 1000 443\r\n"), remote: "[2001:db8::1]:1000", local: "[2001:db8::2]:443"},
+		{name: "v1 unknown", data: []byte("PROXY UNKNOWN\r\n")},
+		{name: "v1 truncated", data: []byte("PROXY TCP4 1.2.3.4"), err: true},
+		{name: "v1 oversized", data: []byte("PROXY TCP4 " + strings.Repeat("1", proxyProtocolV1MaxLen) + "\r\n"), err: true},
+		{name: "v1 invalid ip", data: []byte("PROXY TCP4 1.2.3 5.6.7.8 1000 443\r\n"), err: true},
+		{name: "v1 tcp4 with ipv6", data: []byte("PROXY TCP4 2001:db8::1 5.6.7.8 1000 443\r\n"), err: true},
+		{name: "v1 invalid port", data: []byte("PROXY TCP4 1.2.3.4 5.6.7.8 70000 443\r\n"), err: true},
+		{name: "v1 without crlf", data: []byte("PROXY TCP4 1.2.3.4 5.6.7.8 1000 443\n"), err: true},
+		{name: "v2 tcp4", data: makeProxyProtocolV2Header(1, 0x11, v4Payload), remote: "1.2.3.4:1000", local: "5.6.7.8:443"},
+		{name: "v2 tcp6", data: makeProxyProtocolV2Header(1, 0x21, v6Payload), remote: "[2001:db8::1]:1000", local: "[2001:db8::2]:443"},
+		{name: "v2 local", data: makeProxyProtocolV2Header(0, 0, nil)},
+		{name: "v2 unspec", data: makeProxyProtocolV2Header(1, 0, nil)},
+		{name: "v2 truncated", data: makeProxyProtocolV2Header(1, 0x11, v4Payload)[:20], err: true},
+		{name: "v2 short address", data: makeProxyProtocolV2Header(1, 0x11, v4Payload[:8]), err: true},
+		{name: "v2 invalid command", data: makeProxyProtocolV2Header(2, 0x11, v4Payload), err: true},
+		{name: "v2 truncated prefix", data: proxyProtocolV2Prefix[:8], err: true},
+		{name: "header missing", data: []byte("GET / HTTP/1.1\r\nHost: a\r\n\r\n"), err: true},
+		{name: "short data", data: []byte("GE"), err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			go func() {
+				_, _ = client.Write(append(test.data, "hello"...))
+				_ = client.Close()
+			}()
+
+			conn := newProxyProtocolConn(server, time.Second)
+			defer conn.Close()
+			body, err := ioutil.ReadAll(conn)
+			if test.err {
+				if err == nil {
+					t.Fatalf("应该返回错误, 读取到 %q", body)
+				}
+				if conn.RemoteAddr().String() != server.RemoteAddr().String() {
+					t.Fatalf("头部无效时应该使用原始地址, 实际为 %s", conn.RemoteAddr())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != "hello" {
+				t.Fatalf("头部之后的数据应该为 hello, 实际为 %q", body)
+			}
+
+			remote, local := test.remote, test.local
+			if remote == "" {
+				remote, local = server.RemoteAddr().String(), server.LocalAddr().String()
+			}
+			if conn.RemoteAddr().String() != remote || conn.LocalAddr().String() != local {
+				t.Fatalf("地址应该为 %s -> %s, 实际为 %s -> %s", remote, local, conn.RemoteAddr(), conn.LocalAddr())
+			}
+		})
+	}
+}
+
+func TestProxyProtocolHeaderTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	// 慢客户端只发送部分头部
+	go func() { _, _ = client.Write([]byte("PROXY TCP4")) }()
+	conn := newProxyProtocolConn(server, 50*time.Millisecond)
+	defer conn.Close()
+
+	start := time.Now()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("读取头部超时应该返回错误")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("读取头部应该在超时后结束, 实际用时 %s", d)
+	}
+
+	// 外部设置的更早的超时优先
+	client2, server2 := net.Pipe()
+	defer client2.Close()
+	conn2 := newProxyProtocolConn(server2, time.Minute)
+	defer conn2.Close()
+	_ = conn2.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	start = time.Now()
+	if _, err := conn2.Read(make([]byte, 1)); err == nil {
+		t.Fatal("读取头部超时应该返回错误")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("外部设置的超时应该生效, 实际用时 %s", d)
+	}
+}
+
+func TestProxyProtocolRestoreDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		_, _ = client.Write([]byte("PROXY UNKNOWN\r\na"))
+		time.Sleep(200 * time.Millisecond)
+		_, _ = client.Write([]byte("b"))
+	}()
+
+	// 读取头部之后恢复外部设置的超时, 头部的超时不再生效
+	conn := newProxyProtocolConn(server, 50*time.Millisecond)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1)
+	for _, want := range []string{"a", "b"} {
+		if _, err := conn.Read(buf); err != nil || string(buf) != want {
+			t.Fatalf("应该读取到 %q, 实际为 %q, %v", want, buf, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+	"github.com/zly-app/zapp/pkg/lumberjack"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/utils"
+)
+
+const (
+	// 组合日志格式, 在标准格式后面追加了 延迟秒数 错误码 请求id
+	AccessLogFormatCombined = "combined"
+	// json格式, 每行一个json对象
+	AccessLogFormatJson = "json"
+
+	// 组合日志格式的时间样式
+	accessLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+// 访问日志记录
+type AccessLogRecord struct {
+	Time      time.Time     `json:"time"`
+	IP        string        `json:"ip"`
+	Method    string        `json:"method"`
+	URI       string        `json:"uri"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Bytes     int           `json:"bytes"`
+	Referer   string        `json:"referer"`
+	UserAgent string        `json:"user_agent"`
+	Latency   time.Duration `json:"-"`
+	LatencyMs float64       `json:"latency_ms"`
+	ErrCode   int           `json:"err_code"`
+	RequestID string        `json:"request_id"`
+}
+
+// 访问日志, 写入到单独的文件中, 和app的日志相互独立
+type AccessLogger struct {
+	w      io.WriteCloser
+	format func(buff *bytes.Buffer, r *AccessLogRecord)
+
+	closeChan chan struct{}
+}
+
+// 创建访问日志
+func NewAccessLogger(conf *config.Config) (*AccessLogger, error) {
+	if err := os.MkdirAll(filepath.Dir(conf.AccessLogFile), 0755); err != nil {
+		return nil, err
+	}
+	w := &lumberjack.Logger{
+		Filename:   conf.AccessLogFile,
+		MaxSize:    conf.AccessLogMaxSize,
+		MaxAge:     conf.AccessLogMaxAge,
+		MaxBackups: conf.AccessLogMaxBackups,
+		LocalTime:  true,
+		Compress:   conf.AccessLogCompress,
+	}
+	a := &AccessLogger{
+		w:         w,
+		format:    makeAccessLogFormatter(conf.AccessLogFormat),
+		closeChan: make(chan struct{}),
+	}
+	if conf.AccessLogRotateInterval > 0 {
+		go a.rotateLoop(w, time.Duration(conf.AccessLogRotateInterval)*time.Minute)
+	}
+	return a, nil
+}
+
+// 按时间切割, 切割时间会对齐到间隔的整数倍
+func (a *AccessLogger) rotateLoop(w *lumberjack.Logger, interval time.Duration) {
+	for {
+		now := time.Now()
+		_, offset := now.Zone()
+		local := now.Add(time.Duration(offset) * time.Second)
+		next := local.Truncate(interval).Add(interval).Sub(local)
+
+		timer := time.NewTimer(next)
+		select {
+		case <-timer.C:
+			_ = w.Rotate()
+		case <-a.closeChan:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// 访问日志中间件
+func (a *AccessLogger) Handler() iris.Handler {
+	return func(irisCtx *iris_context.Context) {
+		startTime := time.Now()
+
+		irisCtx.Next()
+
+		r := &AccessLogRecord{
+			Time:      startTime,
+			IP:        utils.Context.GetRemoteIP(irisCtx),
+			Method:    irisCtx.Method(),
+			URI:       irisCtx.Request().RequestURI,
+			Proto:     irisCtx.Request().Proto,
+			Status:    irisCtx.GetStatusCode(),
+			Bytes:     writtenBytes(irisCtx),
+			Referer:   irisCtx.GetHeader("Referer"),
+			UserAgent: irisCtx.GetHeader("User-Agent"),
+			Latency:   time.Since(startTime),
+			ErrCode:   utils.Context.GetErrCode(irisCtx),
+			RequestID: utils.Context.GetRequestID(irisCtx),
+		}
+		r.LatencyMs = float64(r.Latency) / float64(time.Millisecond)
+
+		var buff bytes.Buffer
+		a.format(&buff, r)
+		buff.WriteByte('\n')
+		_, _ = a.w.Write(buff.Bytes())
+	}
+}
+
+func (a *AccessLogger) Close() error {
+	close(a.closeChan)
+	return a.w.Close()
+}
+
+// 获取写入到客户端的字节数
+func writtenBytes(irisCtx *iris_context.Context) int {
+	if size, ok := irisCtx.Values().Get(utils.ResponseCompressedSizeFieldKey).(int); ok { // 压缩后的大小
+		return size
+	}
+	if rec, ok := irisCtx.IsRecording(); ok { // 录制中的数据会在请求结束后写入
+		return len(rec.Body())
+	}
+	if n := irisCtx.ResponseWriter().Written(); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// 根据格式创建格式化函数, 非内置格式会被当作模板
+//
+// 模板支持以下变量: {time} {ip} {method} {uri} {proto} {status} {bytes} {referer} {user_agent} {latency} {latency_ms} {err_code} {request_id}
+func makeAccessLogFormatter(format string) func(buff *bytes.Buffer, r *AccessLogRecord) {
+	switch strings.ToLower(format) {
+	case "", AccessLogFormatCombined:
+		return formatCombined
+	case AccessLogFormatJson:
+		return formatJson
+	}
+
+	parts := parseAccessLogTemplate(format)
+	return func(buff *bytes.Buffer, r *AccessLogRecord) {
+		for _, part := range parts {
+			if part.field != nil {
+				part.field(buff, r)
+			} else {
+				buff.WriteString(part.literal)
+			}
+		}
+	}
+}
+
+// 模板变量, 来自客户端的值会转义
+var accessLogTemplateFields = map[string]func(buff *bytes.Buffer, r *AccessLogRecord){
+	"time":       func(buff *bytes.Buffer, r *AccessLogRecord) { buff.WriteString(r.Time.Format(time.RFC3339)) },
+	"ip":         func(buff *bytes.Buffer, r *AccessLogRecord) { buff.WriteString(r.IP) },
+	"method":     func(buff *bytes.Buffer, r *AccessLogRecord) { writeAccessLogEscaped(buff, r.Method) },
+	"uri":        func(buff *bytes.Buffer, r *AccessLogRecord) { writeAccessLogEscaped(buff, r.URI) },
+	"proto":      func(buff *bytes.Buffer, r *AccessLogRecord) { writeAccessLogEscaped(buff, r.Proto) },
+	"status":     func(buff *bytes.Buffer, r *AccessLogRecord) { buff.WriteString(strconv.Itoa(r.Status)) },
+	"bytes":      func(buff *bytes.Buffer, r *AccessLogRecord) { buff.WriteString(strconv.Itoa(r.Bytes)) },
+	"referer":    func(buff *bytes.Buffer, r *AccessLogRecord) { writeAccessLogEscaped(buff, r.Referer) },
+	"user_agent": func(buff *bytes.Buffer, r *AccessLogRecord) { writeAccessLogEscaped(buff, r.UserAgent) },
+	"latency":    func(buff *bytes.Buffer, r *AccessLogRecord) { buff.WriteString(r.Latency.String()) },
+	"latency_ms": func(buff *bytes.Buffer, r *AccessLogRecord) {
+		buff.WriteString(strconv.FormatFloat(r.LatencyMs, 'f', 3, 64))
+	},
+	"err_code":   func(buff *bytes.Buffer, r *AccessLogRecord) { buff.WriteString(strconv.Itoa(r.ErrCode)) },
+	"request_id": func(buff *bytes.Buffer, r *AccessLogRecord) { writeAccessLogEscaped(buff, r.RequestID) },
+}
+
+// 模板的一部分, field 为nil时是原样输出的文本
+type accessLogTemplatePart struct {
+	literal string
+	field   func(buff *bytes.Buffer, r *AccessLogRecord)
+}
+
+// 解析模板, 不支持的变量会原样输出
+func parseAccessLogTemplate(format string) []accessLogTemplatePart {
+	var parts []accessLogTemplatePart
+	var literal strings.Builder
+	for i := 0; i < len(format); {
+		if format[i] == '{' {
+			if end := strings.IndexByte(format[i:], '}'); end > 0 {
+				if field, ok := accessLogTemplateFields[format[i+1:i+end]]; ok {
+					if literal.Len() > 0 {
+						parts = append(parts, accessLogTemplatePart{literal: literal.String()})
+						literal.Reset()
+					}
+					parts = append(parts, accessLogTemplatePart{field: field})
+					i += end + 1
+					continue
+				}
+			}
+		}
+		literal.WriteByte(format[i])
+		i++
+	}
+	if literal.Len() > 0 {
+		parts = append(parts, accessLogTemplatePart{literal: literal.String()})
+	}
+	return parts
+}
+
+// 组合日志格式, 来自客户端的值会像 nginx 和 apache 一样转义
+//
+// 127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "http://example.com/" "Mozilla/4.08" 0.012 0 c7a1...
+func formatCombined(buff *bytes.Buffer, r *AccessLogRecord) {
+	buff.WriteString(dashIfEmpty(r.IP))
+	buff.WriteString(" - - [")
+	buff.WriteString(r.Time.Format(accessLogTimeLayout))
+	buff.WriteString("] \"")
+	writeAccessLogEscaped(buff, r.Method)
+	buff.WriteByte(' ')
+	writeAccessLogEscaped(buff, r.URI)
+	buff.WriteByte(' ')
+	writeAccessLogEscaped(buff, r.Proto)
+	buff.WriteString("\" ")
+	buff.WriteString(strconv.Itoa(r.Status))
+	buff.WriteByte(' ')
+	if r.Bytes > 0 {
+		buff.WriteString(strconv.Itoa(r.Bytes))
+	} else {
+		buff.WriteByte('-')
+	}
+	buff.WriteString(" \"")
+	writeAccessLogEscaped(buff, dashIfEmpty(r.Referer))
+	buff.WriteString("\" \"")
+	writeAccessLogEscaped(buff, dashIfEmpty(r.UserAgent))
+	buff.WriteString("\" ")
+	buff.WriteString(strconv.FormatFloat(r.Latency.Seconds(), 'f', 3, 64))
+	buff.WriteByte(' ')
+	buff.WriteString(strconv.Itoa(r.ErrCode))
+	buff.WriteByte(' ')
+	writeAccessLogEscaped(buff, dashIfEmpty(r.RequestID))
+}
+
+func formatJson(buff *bytes.Buffer, r *AccessLogRecord) {
+	_ = json.NewEncoder(buff).Encode(r)
+	buff.Truncate(buff.Len() - 1) // 去掉Encode添加的换行符
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// 写入转义后的值, 防止客户端伪造日志行或字段
+//
+// 和 apache 相同, 双引号和反斜杠前加反斜杠, 控制字符和非ascii字符写为 \xNN
+func writeAccessLogEscaped(buff *bytes.Buffer, s string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buff.WriteByte('\\')
+			buff.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			buff.WriteString("\\x")
+			buff.WriteByte(hex[c>>4])
+			buff.WriteByte(hex[c&0x0f])
+		default:
+			buff.WriteByte(c)
+		}
+	}
+}
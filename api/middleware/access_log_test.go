@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func makeTestAccessLogRecord() *AccessLogRecord {
+	return &AccessLogRecord{
+		Time:      time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		IP:        "127.0.0.1",
+		Method:    "GET",
+		URI:       "/a.gif?q=\"x\"\n127.0.0.1 - - [fake]",
+		Proto:     "HTTP/1.1",
+		Status:    200,
+		Bytes:     2326,
+		Referer:   "http://example.com/\\",
+		UserAgent: "Mozilla/4.08 \"evil\" \x1b[31m中",
+		Latency:   12 * time.Millisecond,
+		LatencyMs: 12,
+		ErrCode:   0,
+		RequestID: "c7a1",
+	}
+}
+
+func TestAccessLogFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		expect string
+	}{
+		{
+			name:   "combined",
+			format: AccessLogFormatCombined,
+			expect: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /a.gif?q=\"x\"\x0A127.0.0.1 - - [fake] HTTP/1.1" 200 2326 "http://example.com/\\" "Mozilla/4.08 \"evil\" \x1B[31m\xE4\xB8\xAD" 0.012 0 c7a1`,
+		},
+		{
+			name:   "template",
+			format: "{ip} {method} {uri} {status} {bytes} {latency_ms} {request_id} {user_agent} {unknown} {ip",
+			expect: `127.0.0.1 GET /a.gif?q=\"x\"\x0A127.0.0.1 - - [fake] 200 2326 12.000 c7a1 Mozilla/4.08 \"evil\" \x1B[31m\xE4\xB8\xAD {unknown} {ip`,
+		},
+		{
+			name:   "template without field",
+			format: "access",
+			expect: "access",
+		},
+	}
+	for _, test := range tests {
+		var buff bytes.Buffer
+		makeAccessLogFormatter(test.format)(&buff, makeTestAccessLogRecord())
+		if buff.String() != test.expect {
+			t.Fatalf("%s: 格式化结果错误\n应该为: %s\n实际为: %s", test.name, test.expect, buff.String())
+		}
+	}
+
+	// 空的字段输出为 -
+	var buff bytes.Buffer
+	formatCombined(&buff, &AccessLogRecord{Time: time.Unix(0, 0).UTC(), Method: "GET", URI: "/", Proto: "HTTP/1.1", Status: 204})
+	if expect := `- - - [01/Jan/1970:00:00:00 +0000] "GET / HTTP/1.1" 204 - "-" "-" 0.000 0 -`; buff.String() != expect {
+		t.Fatalf("空字段格式化结果错误: %s", buff.String())
+	}
+}
+
+func TestAccessLogFormatJson(t *testing.T) {
+	r := makeTestAccessLogRecord()
+	var buff bytes.Buffer
+	makeAccessLogFormatter(AccessLogFormatJson)(&buff, r)
+	if bytes.Contains(buff.Bytes(), []byte("\n")) {
+		t.Fatalf("json格式不应该包含换行: %s", buff.String())
+	}
+
+	var decoded AccessLogRecord
+	if err := json.Unmarshal(buff.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.URI != r.URI || decoded.UserAgent != r.UserAgent || decoded.Referer != r.Referer || decoded.LatencyMs != r.LatencyMs {
+		t.Fatalf("json格式解析后的值错误: %+v", decoded)
+	}
+}
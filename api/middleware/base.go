@@ -2,10 +2,16 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/kataras/iris/v12"
 	iris_context "github.com/kataras/iris/v12/context"
 	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
 
 	zapp_utils "github.com/zly-app/zapp/pkg/utils"
 
@@ -14,7 +20,17 @@ import (
 )
 
 // 用于构建相关log, trace等基础数据
-func BaseMiddleware(app core.IApp, conf *config.Config) iris.Handler {
+//
+// 设置了 resolvers 时会按顺序解析租户, 租户会保存到上下文中并添加到日志字段和链路追踪标签中,
+// 租户设置了日志策略时, 这个请求会使用覆盖了租户日志策略的配置
+func BaseMiddleware(app core.IApp, conf *config.Config, resolvers ...TenantResolver) iris.Handler {
+	return DynamicBaseMiddleware(app, config.NewDynamic(conf), resolvers...)
+}
+
+// 同 BaseMiddleware, 每个请求都会使用动态配置的当前值
+func DynamicBaseMiddleware(app core.IApp, dynConf *config.Dynamic, resolvers ...TenantResolver) iris.Handler {
+	proxies := new(trustedProxies)
+	tenants := new(tenantConfs)
 	return func(irisCtx *iris_context.Context) {
 		name := irisCtx.Method() + ": " + irisCtx.Path()
 		// 链路追踪
@@ -24,13 +40,81 @@ func BaseMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 		utils.Context.SaveContextToIrisContext(irisCtx, ctx)
 
 		// conf
+		conf := dynConf.Load()
+
+		// 客户端ip
+		irisCtx.Values().Set(utils.RemoteIPFieldKey, utils.ResolveClientIP(irisCtx.Request(), remoteAddrHeaders(conf), proxies.Get(conf)))
+
+		// 请求id
+		requestID := irisCtx.GetHeader(utils.RequestIDHeaderKey)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		irisCtx.Values().Set(utils.RequestIDFieldKey, requestID)
+		irisCtx.Header(utils.RequestIDHeaderKey, requestID)
+		span.SetTag("request_id", requestID)
+
+		// 租户
+		logFields := []zap.Field{zap.String("request_id", requestID)}
+		if len(resolvers) > 0 {
+			tenant, err := resolveTenant(irisCtx, resolvers)
+			if err != nil {
+				irisCtx.Values().Set(utils.TenantErrorFieldKey, err)
+			}
+			if tenant != "" {
+				irisCtx.Values().Set(utils.TenantFieldKey, tenant)
+				span.SetTag("tenant", tenant)
+				logFields = append(logFields, zap.String("tenant", tenant))
+				conf = tenants.Get(conf, tenant)
+			}
+		}
 		utils.Context.SaveConfToIrisContext(irisCtx, conf)
 
 		// log
-		log := app.NewTraceLogger(ctx)
+		log := app.NewTraceLogger(ctx, logFields...)
 		utils.Context.SaveLoggerToIrisContext(irisCtx, log)
 
 		// handler
 		irisCtx.Next()
 	}
 }
+
+// 生成请求id
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// 获取ip的header, 按优先级排序
+func remoteAddrHeaders(conf *config.Config) []string {
+	headers := make([]string, 0, 2)
+	if conf.IPWithNginxForwarded {
+		headers = append(headers, "X-Forwarded-For")
+	}
+	if conf.IPWithNginxReal {
+		headers = append(headers, "X-Real-IP")
+	}
+	return headers
+}
+
+// 可信代理, 配置改变时才会重新解析
+type trustedProxies struct {
+	v atomic.Value // *parsedTrustedProxies
+}
+
+type parsedTrustedProxies struct {
+	text string
+	nets utils.IPNets
+}
+
+func (t *trustedProxies) Get(conf *config.Config) utils.IPNets {
+	if p, ok := t.v.Load().(*parsedTrustedProxies); ok && p.text == conf.TrustedProxies {
+		return p.nets
+	}
+	nets, _ := utils.ParseIPNets(conf.TrustedProxies) // 配置在创建服务时已经检查过
+	t.v.Store(&parsedTrustedProxies{text: conf.TrustedProxies, nets: nets})
+	return nets
+}
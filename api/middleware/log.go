@@ -41,6 +41,20 @@ func valuesToTexts(values map[string][]string, sep string) []string {
 	return texts
 }
 
+// 获取压缩大小日志字段
+func compressSizeFields(irisCtx iris.Context) []interface{} {
+	var fields []interface{}
+	if size, ok := irisCtx.Values().Get(utils.RequestCompressedSizeFieldKey).(int64); ok {
+		fields = append(fields, zap.Int64("req_compressed_size", size))
+	}
+	if size, ok := irisCtx.Values().Get(utils.ResponseCompressedSizeFieldKey).(int); ok {
+		fields = append(fields, zap.Int("rsp_raw_size", utils.Context.GetResponseRawSize(irisCtx)))
+		fields = append(fields, zap.Int("rsp_compressed_size", size))
+	}
+	return fields
+}
+
+// 日志中间件, 日志配置会从请求上下文中获取, 所以它必须在 BaseMiddleware 之后
 func LoggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 	if app_config.Conf.Config().Frame.Log.Json {
 		return loggerMiddlewareWithJson(app, conf)
@@ -54,6 +68,9 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 	return func(irisCtx iris.Context) {
 		startTime := time.Now()
 
+		// 使用请求的配置, 这样可以在运行时修改日志配置
+		conf := utils.Context.MustGetConfFromIrisContext(irisCtx)
+
 		// log
 		log := utils.Context.MustGetLoggerFromIrisContext(irisCtx)
 
@@ -67,7 +84,7 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 		span.SetTag("method", irisCtx.Method())
 		span.SetTag("path", irisCtx.Path())
 		span.LogFields(open_log.String("params", strings.Join(params, "\n")))
-		span.LogFields(open_log.String("ip", irisCtx.RemoteAddr()))
+		span.LogFields(open_log.String("ip", ip))
 		var msgBuff bytes.Buffer
 		msgBuff.WriteString("api.request path: ")
 		msgBuff.WriteString(irisCtx.Method())
@@ -109,6 +126,7 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 			zap.String("latency_text", latency.String()),
 			zap.Duration("latency", latency),
 		}
+		fields = append(fields, compressSizeFields(irisCtx)...)
 
 		// error
 		err, hasErr := irisCtx.Values().Get("error").(error)
@@ -137,6 +155,8 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 			var bodyText string
 			if irisCtx.GetContentTypeRequested() == iris_context.ContentBinaryHeaderValue { // 流
 				bodyText = fmt.Sprintf("body<bytesLen=%d>", irisCtx.GetContentLength())
+			} else if encoding := irisCtx.GetHeader(iris_context.ContentEncodingHeaderKey); encoding != "" { // 未解压
+				bodyText = fmt.Sprintf("body<encoding=%s, len=%d>", encoding, irisCtx.GetContentLength())
 			} else if irisCtx.GetContentLength() > conf.LogBodyMaxSize { // 超长
 				bodyText = fmt.Sprintf("body<len=%d>", irisCtx.GetContentLength())
 			} else {
@@ -153,10 +173,11 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 		if !hasErr {
 			var result string
 			contentType := iris_context.TrimHeaderValue(irisCtx.ResponseWriter().Header().Get(iris_context.ContentTypeHeaderKey))
+			rspSize := utils.Context.GetResponseRawSize(irisCtx)
 			if contentType == iris_context.ContentBinaryHeaderValue { // 流
-				result = fmt.Sprintf("result<bytesLen=%d>", irisCtx.ResponseWriter().Written())
-			} else if irisCtx.ResponseWriter().Written() > conf.LogApiResultMaxSize { // 超长
-				result = fmt.Sprintf("result<len=%d>", irisCtx.ResponseWriter().Written())
+				result = fmt.Sprintf("result<bytesLen=%d>", rspSize)
+			} else if rspSize > conf.LogApiResultMaxSize { // 超长
+				result = fmt.Sprintf("result<len=%d>", rspSize)
 			} else {
 				switch v := irisCtx.Values().Get("result").(type) {
 				case nil:
@@ -188,6 +209,7 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 			msgBuff.WriteString("err: ")
 			msgBuff.WriteString(err.Error())
 			msgBuff.WriteString("\n\n")
+			fields = append(fields, zap.Int("err_code", utils.Context.GetErrCode(irisCtx)))
 			log.Error(append([]interface{}{msgBuff.String()}, fields...)...)
 			return
 		}
@@ -221,6 +243,7 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 				panicErrInfos...,
 			)
 		}
+		irisCtx.Values().Set(utils.ErrCodeFieldKey, result["err_code"])
 		_, _ = irisCtx.JSON(result)
 		irisCtx.StopExecution()
 	}
@@ -232,6 +255,9 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 	return func(irisCtx *iris_context.Context) {
 		startTime := time.Now()
 
+		// 使用请求的配置, 这样可以在运行时修改日志配置
+		conf := utils.Context.MustGetConfFromIrisContext(irisCtx)
+
 		// log
 		log := utils.Context.MustGetLoggerFromIrisContext(irisCtx)
 
@@ -245,7 +271,7 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 		span.SetTag("method", irisCtx.Method())
 		span.SetTag("path", irisCtx.Path())
 		span.LogFields(open_log.String("params", strings.Join(params, "\n")))
-		span.LogFields(open_log.String("ip", irisCtx.RemoteAddr()))
+		span.LogFields(open_log.String("ip", ip))
 
 		fields := []interface{}{
 			"api.request",
@@ -274,6 +300,7 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 			zap.String("latency_text", latency.String()),
 			zap.Duration("latency", latency),
 		}
+		fields = append(fields, compressSizeFields(irisCtx)...)
 
 		// error
 		err, hasErr := irisCtx.Values().Get("error").(error)
@@ -296,6 +323,8 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 			var bodyText string
 			if irisCtx.GetContentTypeRequested() == iris_context.ContentBinaryHeaderValue { // 流
 				bodyText = fmt.Sprintf("body<bytesLen=%d>", irisCtx.GetContentLength())
+			} else if encoding := irisCtx.GetHeader(iris_context.ContentEncodingHeaderKey); encoding != "" { // 未解压
+				bodyText = fmt.Sprintf("body<encoding=%s, len=%d>", encoding, irisCtx.GetContentLength())
 			} else if irisCtx.GetContentLength() > conf.LogBodyMaxSize { // 超长
 				bodyText = fmt.Sprintf("body<len=%d>", irisCtx.GetContentLength())
 			} else {
@@ -310,10 +339,11 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 		if !hasErr {
 			var result string
 			contentType := iris_context.TrimHeaderValue(irisCtx.ResponseWriter().Header().Get(iris_context.ContentTypeHeaderKey))
+			rspSize := utils.Context.GetResponseRawSize(irisCtx)
 			if contentType == iris_context.ContentBinaryHeaderValue { // 流
-				result = fmt.Sprintf("result<bytesLen=%d>", irisCtx.ResponseWriter().Written())
-			} else if irisCtx.ResponseWriter().Written() > conf.LogApiResultMaxSize { // 超长
-				result = fmt.Sprintf("result<len=%d>", irisCtx.ResponseWriter().Written())
+				result = fmt.Sprintf("result<bytesLen=%d>", rspSize)
+			} else if rspSize > conf.LogApiResultMaxSize { // 超长
+				result = fmt.Sprintf("result<len=%d>", rspSize)
 			} else {
 				switch v := irisCtx.Values().Get("result").(type) {
 				case nil:
@@ -340,7 +370,7 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 		if !hasPanic {
 			span.SetTag("error", true)
 			span.LogFields(open_log.String("err", err.Error()))
-			fields = append(fields, zap.String("err", err.Error()))
+			fields = append(fields, zap.String("err", err.Error()), zap.Int("err_code", utils.Context.GetErrCode(irisCtx)))
 			log.Error(fields...)
 			return
 		}
@@ -374,6 +404,7 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 				panicErrInfos...,
 			)
 		}
+		irisCtx.Values().Set(utils.ErrCodeFieldKey, result["err_code"])
 		_, _ = irisCtx.JSON(result)
 		irisCtx.StopExecution()
 	}
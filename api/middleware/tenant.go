@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	iris_context "github.com/kataras/iris/v12/context"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 租户id最大长度
+const maxTenantLength = 128
+
+// 租户解析器, 返回空字符串表示无法从请求中解析租户
+type TenantResolver func(irisCtx *iris_context.Context) (string, error)
+
+// 从header中获取租户
+func HeaderTenantResolver(header string) TenantResolver {
+	return func(irisCtx *iris_context.Context) (string, error) {
+		return irisCtx.GetHeader(header), nil
+	}
+}
+
+// 从子域名获取租户, 如 domain 为 example.com 时, 请求 foo.example.com 的租户为 foo
+func DomainTenantResolver(domain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return func(irisCtx *iris_context.Context) (string, error) {
+		host := strings.ToLower(irisCtx.Host())
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !strings.HasSuffix(host, suffix) {
+			return "", nil
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		if strings.Contains(sub, ".") { // 只取一级子域名
+			return "", nil
+		}
+		return sub, nil
+	}
+}
+
+// 从 Authorization: Bearer <token> 的jwt中获取租户
+//
+// parse 用于校验并解析jwt, 返回jwt的claims, 因为这时还没有经过鉴权中间件, parse 必须校验签名.
+// 如果已经在网关校验过jwt, 可以使用 UnverifiedJwtClaims
+func JwtClaimTenantResolver(claim string, parse func(token string) (map[string]interface{}, error)) TenantResolver {
+	return func(irisCtx *iris_context.Context) (string, error) {
+		auth := irisCtx.GetHeader("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+			return "", nil
+		}
+		claims, err := parse(strings.TrimSpace(auth[7:]))
+		if err != nil {
+			return "", err
+		}
+		switch v := claims[claim].(type) {
+		case nil:
+			return "", nil
+		case string:
+			return v, nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	}
+}
+
+// 解析jwt的claims, 不会校验签名, 只能在已经校验过jwt时使用
+func UnverifiedJwtClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jwt格式错误")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("jwt payload解码失败: %v", err)
+	}
+	var claims map[string]interface{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("jwt payload解析失败: %v", err)
+	}
+	return claims, nil
+}
+
+// 按顺序使用解析器解析租户, 返回第一个解析到的租户
+func resolveTenant(irisCtx *iris_context.Context, resolvers []TenantResolver) (string, error) {
+	var lastErr error
+	for _, resolve := range resolvers {
+		tenant, err := resolve(irisCtx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		tenant = strings.TrimSpace(tenant)
+		if tenant == "" {
+			continue
+		}
+		if len(tenant) > maxTenantLength {
+			lastErr = fmt.Errorf("租户id长度超过 %d", maxTenantLength)
+			continue
+		}
+		return tenant, nil
+	}
+	return "", lastErr
+}
+
+// 租户配置缓存, 全局配置改变后会重新生成
+type tenantConfs struct {
+	mx    sync.RWMutex
+	base  *config.Config
+	confs map[string]*config.Config
+}
+
+// 获取覆盖了租户日志策略的配置
+func (t *tenantConfs) Get(conf *config.Config, tenant string) *config.Config {
+	t.mx.RLock()
+	c, ok := t.confs[tenant]
+	base := t.base
+	t.mx.RUnlock()
+	if ok && base == conf {
+		return c
+	}
+
+	tc := conf.TenantConfig(tenant)
+	if tc == nil {
+		return conf
+	}
+	c = tc.Apply(conf)
+
+	t.mx.Lock()
+	if t.base != conf {
+		t.base = conf
+		t.confs = make(map[string]*config.Config)
+	}
+	t.confs[tenant] = c
+	t.mx.Unlock()
+	return c
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 生成没有签名的jwt
+func makeTestJwt(payload string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".sig"
+}
+
+func TestResolveTenant(t *testing.T) {
+	irisApp := iris.New()
+	badJwt := func(token string) (map[string]interface{}, error) { return nil, errors.New("bad token") }
+	resolvers := []TenantResolver{
+		JwtClaimTenantResolver("tenant_id", UnverifiedJwtClaims),
+		HeaderTenantResolver("X-Tenant-Id"),
+		DomainTenantResolver(".Example.com."),
+	}
+
+	tests := []struct {
+		name      string
+		host      string
+		headers   map[string]string
+		resolvers []TenantResolver
+		expect    string
+		hasErr    bool
+	}{
+		{name: "header", headers: map[string]string{"X-Tenant-Id": " a "}, expect: "a"},
+		{name: "domain", host: "b.example.com:8080", expect: "b"},
+		{name: "domain upper", host: "B.EXAMPLE.COM", expect: "b"},
+		{name: "multi level domain", host: "x.b.example.com"},
+		{name: "other domain", host: "b.other.com"},
+		{name: "jwt", headers: map[string]string{"Authorization": "Bearer " + makeTestJwt(`{"tenant_id":"c"}`)}, expect: "c"},
+		{name: "jwt number claim", headers: map[string]string{"Authorization": "bearer " + makeTestJwt(`{"tenant_id":123}`)}, expect: "123"},
+		{name: "jwt without claim", headers: map[string]string{"Authorization": "Bearer " + makeTestJwt(`{}`), "X-Tenant-Id": "a"}, expect: "a"},
+		{name: "jwt first", host: "b.example.com", headers: map[string]string{"Authorization": "Bearer " + makeTestJwt(`{"tenant_id":"c"}`), "X-Tenant-Id": "a"}, expect: "c"},
+		{name: "bad jwt fallback", headers: map[string]string{"Authorization": "Bearer bad", "X-Tenant-Id": "a"}, expect: "a"},
+		{name: "bad jwt", headers: map[string]string{"Authorization": "Bearer bad"}, hasErr: true},
+		{name: "verify failed", headers: map[string]string{"Authorization": "Bearer x"},
+			resolvers: []TenantResolver{JwtClaimTenantResolver("tenant_id", badJwt)}, hasErr: true},
+		{name: "too long", headers: map[string]string{"X-Tenant-Id": strings.Repeat("a", maxTenantLength+1)}, hasErr: true},
+		{name: "none"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.host != "" {
+			r.Host = test.host
+		}
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		irisCtx := iris_context.NewContext(irisApp)
+		irisCtx.ResetRequest(r)
+
+		rs := resolvers
+		if test.resolvers != nil {
+			rs = test.resolvers
+		}
+		tenant, err := resolveTenant(irisCtx, rs)
+		if tenant != test.expect || (err != nil) != test.hasErr {
+			t.Fatalf("%s: 解析的租户应该为 %q, 错误 %v, 实际为 %q, %v", test.name, test.expect, test.hasErr, tenant, err)
+		}
+	}
+}
+
+func TestTenantConfs(t *testing.T) {
+	no := false
+	conf := config.NewConfig()
+	conf.Tenants = []config.TenantConfig{
+		{Tenant: "log", AlwaysLogBody: &no},
+		{Tenant: "plain", RateLimit: 1},
+	}
+	var tenants tenantConfs
+
+	c := tenants.Get(conf, "log")
+	if c == conf || c.AlwaysLogBody || !conf.AlwaysLogBody {
+		t.Fatal("覆盖了日志策略的租户应该使用新的配置, 且不应该修改全局配置")
+	}
+	if tenants.Get(conf, "log") != c {
+		t.Fatal("配置没有改变时应该使用缓存")
+	}
+	if tenants.Get(conf, "plain") != conf || tenants.Get(conf, "unknown") != conf {
+		t.Fatal("没有覆盖日志策略的租户应该使用全局配置")
+	}
+
+	newConf := *conf
+	if c2 := tenants.Get(&newConf, "log"); c2 == c || c2.AlwaysLogBody {
+		t.Fatal("全局配置改变后应该重新生成租户配置")
+	}
+}
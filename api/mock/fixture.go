@@ -0,0 +1,139 @@
+package mock
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	iris_context "github.com/kataras/iris/v12/context"
+
+	"github.com/zly-app/service/api/reporter"
+)
+
+// 录制的请求和响应
+type Fixture struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Query       string `json:"query,omitempty"`        // 已排序并屏蔽了敏感数据
+	ContentType string `json:"content_type,omitempty"` // 请求的 Content-Type
+	Body        string `json:"body,omitempty"`         // 请求body, 已屏蔽敏感数据
+
+	Status         int             `json:"status"`
+	RspContentType string          `json:"rsp_content_type,omitempty"`
+	Response       json.RawMessage `json:"response,omitempty"`     // json格式的响应, 已屏蔽敏感数据
+	ResponseRaw    []byte          `json:"response_raw,omitempty"` // 其它格式的响应
+
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// 匹配key, 相同key的请求会覆盖之前录制的数据
+func (f *Fixture) key() string {
+	return f.Method + " " + f.Path + "?" + f.Query + "\n" + f.Body
+}
+
+// 文件名, 如 GET_users_1_0a1b2c3d4e.json
+func (f *Fixture) fileName() string {
+	sum := sha1.Sum([]byte(f.key()))
+	path := fileNameRegexp.ReplaceAllString(strings.Trim(f.Path, "/"), "_")
+	if len(path) > 64 {
+		path = path[:64]
+	}
+	return fmt.Sprintf("%s_%s_%s.json", f.Method, path, hex.EncodeToString(sum[:])[:10])
+}
+
+// 响应数据
+func (f *Fixture) responseBody() []byte {
+	if len(f.Response) > 0 {
+		return f.Response
+	}
+	return f.ResponseRaw
+}
+
+func (f *Fixture) setResponse(status int, contentType string, body []byte) {
+	f.Status = status
+	f.RspContentType = contentType
+	if strings.Contains(contentType, "json") && json.Valid(body) {
+		f.Response = json.RawMessage(reporter.RedactBody(contentType, body))
+		return
+	}
+	f.ResponseRaw = append([]byte(nil), body...)
+}
+
+var fileNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// 根据请求创建 Fixture, 请求数据会屏蔽敏感数据, 和录制时使用相同的规则以便匹配
+func newRequestFixture(irisCtx *iris_context.Context) *Fixture {
+	req := irisCtx.Request()
+	f := &Fixture{
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Query:       redactQuery(req.URL.Query()),
+		ContentType: irisCtx.GetContentTypeRequested(),
+	}
+	if f.ContentType != iris_context.ContentBinaryHeaderValue { // 流数据不录制
+		body, _ := irisCtx.GetBody()
+		f.Body = reporter.RedactBody(f.ContentType, body)
+	}
+	return f
+}
+
+// 排序并屏蔽url参数中的敏感数据
+func redactQuery(values url.Values) string {
+	for k := range values {
+		if reporter.IsSensitive(k) {
+			values[k] = []string{reporter.RedactedValue}
+		}
+	}
+	return values.Encode()
+}
+
+// 保存到目录中
+func saveFixture(dir string, f *Fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, f.fileName()), data, 0644)
+}
+
+// 加载目录中所有的录制数据, 按文件名排序
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	fixtures := make([]*Fixture, 0, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		f := new(Fixture)
+		if err = json.Unmarshal(data, f); err != nil {
+			return nil, fmt.Errorf("解析录制数据 %s 失败: %v", file, err)
+		}
+		if len(f.Response) > 0 { // 去掉保存时添加的缩进
+			var buf bytes.Buffer
+			if err = json.Compact(&buf, f.Response); err == nil {
+				f.Response = buf.Bytes()
+			}
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
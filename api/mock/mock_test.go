@@ -0,0 +1,94 @@
+package mock
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp"
+
+	"github.com/zly-app/service/api"
+	"github.com/zly-app/service/api/config"
+)
+
+type loginReq struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+type loginRsp struct {
+	User  string `json:"user"`
+	Token string `json:"token"`
+}
+
+func serve(t *testing.T, s *api.ApiService, method, url, body string) (int, string) {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w.Code, w.Body.String()
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	app := zapp.NewApp("test")
+	conf := config.NewConfig()
+	conf.Check()
+
+	// 录制
+	s := api.NewApiService(app, conf)
+	s.Configure(iris.WithoutBodyConsumptionOnUnmarshal)
+	p := s.Party("/v1", NewRecorder(dir).Handler())
+	p.Post("/login", api.Wrap(func(ctx *api.Context, req *loginReq) interface{} {
+		return &loginRsp{User: req.User, Token: "secret-token"}
+	}))
+	s.Get("/health", api.Wrap(func(ctx *api.Context) interface{} { return "ok" }))
+	if err = s.Build(); err != nil {
+		t.Fatal(err)
+	}
+	serve(t, s, "POST", "/v1/login", `{"user":"bob","password":"p1"}`)
+	serve(t, s, "GET", "/health", "")
+
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 1 {
+		t.Fatalf("应该只录制经过录制中间件的请求, 录制了 %d 个", len(fixtures))
+	}
+	f := fixtures[0]
+	if strings.Contains(f.Body, "p1") || strings.Contains(string(f.Response), "secret-token") {
+		t.Fatalf("敏感数据没有被屏蔽: %s %s", f.Body, f.Response)
+	}
+
+	// 回放
+	stub, err := NewStubService(app, conf, dir, WithUnmatchedError(api.ParamError.WithMessage("no fixture")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub.Configure(iris.WithoutBodyConsumptionOnUnmarshal)
+	if err = stub.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	code, body := serve(t, stub, "POST", "/v1/login", `{"password":"p2", "user":"bob"}`)
+	if code != 200 || !strings.Contains(body, `"user":"bob"`) {
+		t.Fatalf("回放失败: %d %s", code, body)
+	}
+	_, body = serve(t, stub, "POST", "/v1/login", `{"user":"alice"}`)
+	if !strings.Contains(body, `"err_code": 2`) || !strings.Contains(body, "no fixture") {
+		t.Fatalf("不匹配的请求应该返回设置的错误: %s", body)
+	}
+	_, body = serve(t, stub, "GET", "/health", "")
+	if !strings.Contains(body, `"err_code": 2`) {
+		t.Fatalf("没有录制的路由应该返回设置的错误: %s", body)
+	}
+}
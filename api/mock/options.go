@@ -0,0 +1,54 @@
+package mock
+
+import (
+	"github.com/zly-app/service/api"
+)
+
+type options struct {
+	redact       func(f *Fixture)
+	unmatchedErr error
+	ignoreQuery  bool
+	ignoreBody   bool
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		unmatchedErr: api.FixtureNotFound,
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+type Option func(o *options)
+
+// 自定义屏蔽敏感数据, 会在内置的屏蔽规则之后调用
+//
+// 录制和回放时应该使用相同的函数, 否则请求可能无法匹配, 回放时 fn 收到的 Fixture 只有请求数据
+func WithRedact(fn func(f *Fixture)) Option {
+	return func(o *options) {
+		o.redact = fn
+	}
+}
+
+// 回放时没有匹配的录制数据返回的错误, 默认为 api.FixtureNotFound
+func WithUnmatchedError(err error) Option {
+	return func(o *options) {
+		o.unmatchedErr = err
+	}
+}
+
+// 回放时不匹配url参数
+func WithIgnoreQuery() Option {
+	return func(o *options) {
+		o.ignoreQuery = true
+	}
+}
+
+// 回放时不匹配请求body
+func WithIgnoreBody() Option {
+	return func(o *options) {
+		o.ignoreBody = true
+	}
+}
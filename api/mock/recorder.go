@@ -0,0 +1,57 @@
+package mock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/utils"
+)
+
+// 录制器, 将请求和响应保存到目录中, 每个不同的请求保存为一个json文件
+type Recorder struct {
+	dir  string
+	opts *options
+	mx   sync.Mutex
+}
+
+// 创建录制器, 录制数据会保存到 dir 目录中
+func NewRecorder(dir string, opts ...Option) *Recorder {
+	return &Recorder{
+		dir:  dir,
+		opts: newOptions(opts...),
+	}
+}
+
+// 录制中间件, 可以用于 Party 或路由, 只有经过这个中间件的请求才会录制
+//
+//	rec := mock.NewRecorder("./testdata/fixtures")
+//	users := router.Party("/users", rec.Handler())
+func (r *Recorder) Handler() iris.Handler {
+	return func(irisCtx *iris_context.Context) {
+		f := newRequestFixture(irisCtx)
+
+		irisCtx.Record()
+		irisCtx.Next()
+
+		rec, ok := irisCtx.IsRecording()
+		if !ok {
+			return
+		}
+		f.setResponse(irisCtx.GetStatusCode(), rec.Header().Get(iris_context.ContentTypeHeaderKey), rec.Body())
+		f.RecordedAt = time.Now()
+		if r.opts.redact != nil {
+			r.opts.redact(f)
+		}
+
+		r.mx.Lock()
+		err := saveFixture(r.dir, f)
+		r.mx.Unlock()
+		if err != nil {
+			utils.Context.MustGetLoggerFromIrisContext(irisCtx).Warn("api.mock.record_failed", zap.String("path", f.Path), zap.Error(err))
+		}
+	}
+}
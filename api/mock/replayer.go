@@ -0,0 +1,105 @@
+package mock
+
+import (
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api"
+	"github.com/zly-app/service/api/config"
+)
+
+// 回放器, 根据请求返回录制的响应
+//
+// 请求方法和路径必须相同, url参数和请求body默认也必须相同, 比较前会使用和录制时相同的规则屏蔽敏感数据
+type Replayer struct {
+	opts     *options
+	fixtures map[string][]*Fixture // key为请求方法和路径
+}
+
+// 创建回放器, 会加载 dir 目录中所有的录制数据
+func NewReplayer(dir string, opts ...Option) (*Replayer, error) {
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayerWithFixtures(fixtures, opts...), nil
+}
+
+// 使用指定的录制数据创建回放器
+func NewReplayerWithFixtures(fixtures []*Fixture, opts ...Option) *Replayer {
+	r := &Replayer{
+		opts:     newOptions(opts...),
+		fixtures: make(map[string][]*Fixture, len(fixtures)),
+	}
+	for _, f := range fixtures {
+		key := f.Method + " " + f.Path
+		r.fixtures[key] = append(r.fixtures[key], f)
+	}
+	return r
+}
+
+// 查找和请求匹配的录制数据
+func (r *Replayer) Match(irisCtx *iris_context.Context) (*Fixture, bool) {
+	req := irisCtx.Request()
+	candidates := r.fixtures[req.Method+" "+req.URL.Path]
+	if len(candidates) == 0 {
+		return nil, false
+	}
+
+	in := newRequestFixture(irisCtx)
+	if r.opts.redact != nil {
+		r.opts.redact(in)
+	}
+	for _, f := range candidates {
+		if !r.opts.ignoreQuery && f.Query != in.Query {
+			continue
+		}
+		if !r.opts.ignoreBody && f.Body != in.Body {
+			continue
+		}
+		return f, true
+	}
+	return nil, false
+}
+
+// 回放处理程序, 没有匹配的录制数据时返回 WithUnmatchedError 设置的错误
+func (r *Replayer) Handler() iris.Handler {
+	unmatched := api.Wrap(func(ctx *api.Context) error {
+		ctx.Warn("api.mock.unmatched", zap.String("method", ctx.Method()), zap.String("path", ctx.Path()))
+		return r.opts.unmatchedErr
+	})
+	return func(irisCtx *iris_context.Context) {
+		f, ok := r.Match(irisCtx)
+		if !ok {
+			unmatched(irisCtx)
+			return
+		}
+
+		if f.RspContentType != "" {
+			irisCtx.ContentType(f.RspContentType)
+		}
+		irisCtx.StatusCode(f.Status)
+		_, _ = irisCtx.Write(f.responseBody())
+		irisCtx.StopExecution()
+	}
+}
+
+// 创建只回放录制数据的api服务, 可以让其它团队在不运行真实依赖的情况下测试接口
+//
+//	s, _ := mock.NewStubService(app, conf, "./testdata/fixtures")
+//	_ = s.Build()
+//	srv := httptest.NewServer(s)
+func NewStubService(app core.IApp, conf *config.Config, dir string, opts ...Option) (*api.ApiService, error) {
+	r, err := NewReplayer(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := api.NewApiService(app, conf)
+	h := r.Handler()
+	s.Any("/", h)
+	s.Any("/{p:path}", h)
+	return s, nil
+}
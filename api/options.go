@@ -1,34 +1,84 @@
-package api
-
-import (
-	"github.com/kataras/iris/v12"
-)
-
-type options struct {
-	Middlewares  []interface{}       // 中间件, 函数格式参考WrapMiddleware
-	Configurator []iris.Configurator // 配置项
-}
-
-type Option func(o *options)
-
-func newOptions(opts ...Option) *options {
-	o := &options{}
-	for _, fn := range opts {
-		fn(o)
-	}
-	return o
-}
-
-// 添加iris配置项
-func WithConfigurator(configs ...iris.Configurator) Option {
-	return func(o *options) {
-		o.Configurator = append(o.Configurator, configs...)
-	}
-}
-
-// 添加全局中间件, 函数格式参考WrapMiddleware
-func WithMiddleware(fn interface{}) Option {
-	return func(o *options) {
-		o.Middlewares = append(o.Middlewares, fn)
-	}
-}
+package api
+
+import (
+	"github.com/kataras/iris/v12"
+
+	"github.com/zly-app/service/api/middleware"
+	"github.com/zly-app/service/api/reporter"
+)
+
+// 租户解析器, 返回空字符串表示无法从请求中解析租户
+type TenantResolver = middleware.TenantResolver
+
+type options struct {
+	Middlewares      []interface{}       // 中间件, 函数格式参考WrapMiddleware
+	Configurator     []iris.Configurator // 配置项
+	AdminAuth        interface{}         // 管理接口鉴权, 函数格式参考WrapMiddleware
+	Interceptors     []Interceptor       // 全局响应拦截器
+	Reporter         reporter.IReporter  // 错误上报器
+	Tenants          []TenantResolver    // 租户解析器
+	PriorityResolver PriorityResolver    // 请求优先级解析函数
+}
+
+type Option func(o *options)
+
+func newOptions(opts ...Option) *options {
+	o := &options{}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// 添加iris配置项
+func WithConfigurator(configs ...iris.Configurator) Option {
+	return func(o *options) {
+		o.Configurator = append(o.Configurator, configs...)
+	}
+}
+
+// 添加全局中间件, 函数格式参考WrapMiddleware
+func WithMiddleware(fn interface{}) Option {
+	return func(o *options) {
+		o.Middlewares = append(o.Middlewares, fn)
+	}
+}
+
+// 设置管理接口鉴权, 函数格式参考WrapMiddleware, 返回非nil值表示拒绝访问. 管理接口挂载在api服务上时必须设置
+func WithAdminAuth(fn interface{}) Option {
+	return func(o *options) {
+		o.AdminAuth = fn
+	}
+}
+
+// 添加全局响应拦截器, 全局拦截器会在所有 Party 的拦截器之前执行
+func WithInterceptor(fns ...Interceptor) Option {
+	return func(o *options) {
+		o.Interceptors = append(o.Interceptors, fns...)
+	}
+}
+
+// 设置错误上报器, panic和服务内部错误会上报到这里
+func WithErrorReporter(r reporter.IReporter) Option {
+	return func(o *options) {
+		o.Reporter = r
+	}
+}
+
+// 设置请求优先级解析函数, 用于自适应限流, 如果返回 limiter.PriorityNormal 以外的值会覆盖关键路径的判断
+func WithPriorityResolver(fn PriorityResolver) Option {
+	return func(o *options) {
+		o.PriorityResolver = fn
+	}
+}
+
+// 添加租户解析器, 会在配置的 TenantHeader 和 TenantDomain 之前按顺序解析, 第一个解析到的租户生效
+//
+// 设置了租户解析器后, 无法解析租户的请求会返回 TenantRequired
+//
+//	api.WithTenantResolver(middleware.JwtClaimTenantResolver("tenant_id", parseJwt))
+func WithTenantResolver(resolvers ...TenantResolver) Option {
+	return func(o *options) {
+		o.Tenants = append(o.Tenants, resolvers...)
+	}
+}
@@ -0,0 +1,97 @@
+package reporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 被屏蔽的值
+const RedactedValue = "******"
+
+// 名称包含这些文本时值会被屏蔽
+var sensitiveKeywords = []string{"password", "passwd", "secret", "token", "credential", "privatekey", "private_key", "authorization", "cookie", "session", "api_key", "apikey", "api-key"}
+
+// 检查名称是否为敏感字段
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, keyword := range sensitiveKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// 将headers转为文本并屏蔽敏感数据, 每行一个header, 按名称排序
+func RedactHeaders(headers http.Header) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		for _, v := range headers[k] {
+			if IsSensitive(k) {
+				v = RedactedValue
+			}
+			sb.WriteString(k)
+			sb.WriteString(": ")
+			sb.WriteString(v)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// 屏蔽body中的敏感数据, 支持json和表单格式, 其它格式会原样返回
+func RedactBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return string(body)
+		}
+		for k := range values {
+			if IsSensitive(k) {
+				values[k] = []string{RedactedValue}
+			}
+		}
+		return values.Encode()
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	data, err := json.Marshal(redactJson(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
+
+func redactJson(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if IsSensitive(k) {
+				val[k] = RedactedValue
+				continue
+			}
+			val[k] = redactJson(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactJson(item)
+		}
+	}
+	return v
+}
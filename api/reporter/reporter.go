@@ -0,0 +1,207 @@
+package reporter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zly-app/service/api/limiter"
+	"github.com/zly-app/zapp/logger"
+	"go.uber.org/zap"
+)
+
+// 事件中常用的字段名
+const (
+	FieldService   = "service"    // 来源服务, 如 api, cron, kafka-consume
+	FieldKind      = "kind"       // 错误类型, panic 或 error
+	FieldHandler   = "handler"    // 处理程序名
+	FieldRequestID = "request_id" // 请求id
+	FieldPrincipal = "principal"  // 用户标识
+	FieldMethod    = "method"     // 请求方法
+	FieldPath      = "path"       // 请求路径
+	FieldHeaders   = "headers"    // 请求headers, 已屏蔽敏感数据
+	FieldBody      = "body"       // 请求body, 已屏蔽敏感数据
+)
+
+// 错误类型
+const (
+	KindPanic = "panic"
+	KindError = "error"
+)
+
+const (
+	// 默认每批最多上报的事件数
+	defaultBatchSize = 100
+	// 默认批量上报间隔
+	defaultFlushInterval = 5 * time.Second
+	// 默认事件队列大小
+	defaultQueueSize = 1000
+	// 默认每秒最多接收的事件数
+	defaultRateLimit = 10
+)
+
+// 错误上报器
+//
+// 方法只使用了标准库类型, 其它服务只要定义相同方法的接口就可以使用同一个上报器
+type IReporter interface {
+	// 上报错误, stack 为堆栈信息, 可以为空, fields 为附加信息, 如请求元数据
+	Report(ctx context.Context, err error, stack string, fields map[string]string)
+}
+
+// 错误事件
+type Event struct {
+	Time   time.Time         `json:"time"`
+	Error  string            `json:"error"`
+	Stack  string            `json:"stack,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// 上报器配置
+type Config struct {
+	BatchSize     int           // 每批最多上报的事件数
+	FlushInterval time.Duration // 批量上报间隔, 事件数量不足一批时到时间也会上报
+	QueueSize     int           // 等待上报的事件队列大小, 队列已满时新事件会被丢弃
+	RateLimit     float64       // 每秒最多接收的事件数, 超出的事件会被丢弃, 负数表示不限制
+	Burst         int           // 允许突发的事件数, 默认等于 RateLimit
+}
+
+func (conf *Config) check() {
+	if conf.BatchSize < 1 {
+		conf.BatchSize = defaultBatchSize
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = defaultFlushInterval
+	}
+	if conf.QueueSize < 1 {
+		conf.QueueSize = defaultQueueSize
+	}
+	if conf.RateLimit == 0 {
+		conf.RateLimit = defaultRateLimit
+	}
+	if conf.Burst < 1 {
+		conf.Burst = int(conf.RateLimit)
+		if conf.Burst < 1 {
+			conf.Burst = 1
+		}
+	}
+}
+
+// 批量上报的错误上报器, 上报不会阻塞调用者
+type Reporter struct {
+	sink    ISink
+	conf    Config
+	limiter *limiter.RateLimiter
+
+	events  chan *Event
+	dropped int64
+	closed  bool
+	mx      sync.RWMutex // 保证关闭后不会再写入事件
+	done    chan struct{}
+}
+
+var _ IReporter = (*Reporter)(nil)
+
+// 创建上报器, 事件会批量写入到 sink 中
+func NewReporter(sink ISink, conf Config) *Reporter {
+	conf.check()
+	r := &Reporter{
+		sink:   sink,
+		conf:   conf,
+		events: make(chan *Event, conf.QueueSize),
+		done:   make(chan struct{}),
+	}
+	if conf.RateLimit > 0 {
+		r.limiter = limiter.NewRateLimiter(conf.RateLimit, conf.Burst)
+	}
+	go r.loop()
+	return r
+}
+
+func (r *Reporter) Report(ctx context.Context, err error, stack string, fields map[string]string) {
+	if err == nil {
+		return
+	}
+	if r.limiter != nil && !r.limiter.Allow() {
+		atomic.AddInt64(&r.dropped, 1)
+		return
+	}
+
+	event := &Event{
+		Time:  time.Now(),
+		Error: err.Error(),
+		Stack: stack,
+	}
+	if len(fields) > 0 {
+		event.Fields = make(map[string]string, len(fields))
+		for k, v := range fields {
+			event.Fields[k] = v
+		}
+	}
+
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	if r.closed {
+		atomic.AddInt64(&r.dropped, 1)
+		return
+	}
+	select {
+	case r.events <- event:
+	default:
+		atomic.AddInt64(&r.dropped, 1)
+	}
+}
+
+// 获取因为限流或队列已满被丢弃的事件数
+func (r *Reporter) Dropped() int64 {
+	return atomic.LoadInt64(&r.dropped)
+}
+
+// 关闭上报器, 会上报剩余的事件并关闭 sink
+func (r *Reporter) Close() error {
+	r.mx.Lock()
+	if r.closed {
+		r.mx.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.events)
+	r.mx.Unlock()
+
+	<-r.done
+	return r.sink.Close()
+}
+
+func (r *Reporter) loop() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.conf.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Event, 0, r.conf.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.sink.Write(batch); err != nil {
+			logger.Log.Error("上报错误事件失败", zap.Int("count", len(batch)), zap.Error(err))
+		}
+		batch = make([]*Event, 0, r.conf.BatchSize)
+	}
+
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= r.conf.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
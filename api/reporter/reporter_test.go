@@ -0,0 +1,81 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memorySink struct {
+	batches [][]*Event
+	mx      sync.Mutex
+}
+
+func (s *memorySink) Write(events []*Event) error {
+	s.mx.Lock()
+	s.batches = append(s.batches, events)
+	s.mx.Unlock()
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func TestReporter_Batch(t *testing.T) {
+	sink := new(memorySink)
+	r := NewReporter(sink, Config{BatchSize: 3, FlushInterval: time.Hour, RateLimit: -1})
+	for i := 0; i < 7; i++ {
+		r.Report(context.Background(), errors.New("err"), "", map[string]string{FieldKind: KindError})
+	}
+	_ = r.Close()
+
+	if len(sink.batches) != 3 {
+		t.Fatalf("应该上报3批, 实际为 %d", len(sink.batches))
+	}
+	if len(sink.batches[0]) != 3 || len(sink.batches[2]) != 1 {
+		t.Fatalf("批量大小错误: %d %d", len(sink.batches[0]), len(sink.batches[2]))
+	}
+	if sink.batches[0][0].Fields[FieldKind] != KindError {
+		t.Fatal("事件字段丢失")
+	}
+}
+
+func TestReporter_RateLimit(t *testing.T) {
+	sink := new(memorySink)
+	r := NewReporter(sink, Config{RateLimit: 1, Burst: 5})
+	for i := 0; i < 20; i++ {
+		r.Report(context.Background(), errors.New("err"), "", nil)
+	}
+	_ = r.Close()
+
+	if r.Dropped() != 15 {
+		t.Fatalf("应该丢弃15个事件, 实际为 %d", r.Dropped())
+	}
+	r.Report(context.Background(), errors.New("err"), "", nil)
+	if r.Dropped() != 16 {
+		t.Fatal("关闭后的事件应该被丢弃")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer abc")
+	headers.Set("X-Trace", "1")
+	text := RedactHeaders(headers)
+	if strings.Contains(text, "abc") || !strings.Contains(text, "X-Trace: 1") {
+		t.Fatalf("headers屏蔽错误: %s", text)
+	}
+
+	body := RedactBody("application/json", []byte(`{"user":"a","password":"p","items":[{"access_token":"t"}]}`))
+	if strings.Contains(body, `"p"`) || strings.Contains(body, `"t"`) || !strings.Contains(body, `"user":"a"`) {
+		t.Fatalf("json body屏蔽错误: %s", body)
+	}
+
+	body = RedactBody("application/x-www-form-urlencoded", []byte("user=a&secret=s"))
+	if strings.Contains(body, "secret=s") || !strings.Contains(body, "user=a") {
+		t.Fatalf("表单body屏蔽错误: %s", body)
+	}
+}
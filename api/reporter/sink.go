@@ -0,0 +1,113 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 默认http上报超时
+const defaultHttpSinkTimeout = 5 * time.Second
+
+// 事件写入目标
+type ISink interface {
+	// 写入一批事件
+	Write(events []*Event) error
+	Close() error
+}
+
+// 文件写入目标, 每行一个json格式的事件
+type FileSink struct {
+	f  *os.File
+	mx sync.Mutex
+}
+
+// 创建文件写入目标, 会以追加的方式打开文件
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Write(events []*Event) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	w := bufio.NewWriter(s.f)
+	encoder := json.NewEncoder(w)
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// http写入目标, 每批事件会以json数组的方式 POST 到指定地址
+type HttpSink struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+// 创建http写入目标, headers 会附加到每个请求中, 如鉴权信息, timeout 为0时使用默认超时
+func NewHttpSink(url string, headers http.Header, timeout time.Duration) *HttpSink {
+	if timeout <= 0 {
+		timeout = defaultHttpSinkTimeout
+	}
+	return &HttpSink{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *HttpSink) Write(events []*Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, values := range s.headers {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, rsp.Body)
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("上报错误事件失败, 状态码: %d", rsp.StatusCode)
+	}
+	return nil
+}
+
+func (s *HttpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
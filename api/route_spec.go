@@ -0,0 +1,72 @@
+package api
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/core/router"
+)
+
+// 路由的类型信息, 用于生成客户端
+type RouteSpec struct {
+	RouteInfo
+	Req reflect.Type // 请求类型, 已经去掉了指针, 没有请求参数时为nil
+	Rsp reflect.Type // 响应类型, 只返回error时为nil
+}
+
+// 获取经过 Wrap 包装的路由的类型信息, 按路径和方法排序
+//
+// 只包含处理程序经过 Wrap 包装的路由, 包括版本化路由, json-rpc 不包含在内
+func (a *ApiService) RouteSpecs() []RouteSpec {
+	return makeRouteSpecs(a.GetRoutes())
+}
+
+// 收集路由的类型信息, 不需要创建服务, 用于在 go generate 中生成客户端
+//
+// 路由会注册到一个临时的 iris.Application 上, 传给 fn 的组件为nil, 注册路由时不应该使用组件
+func CollectRouteSpecs(fn ...RegisterApiRouterFunc) []RouteSpec {
+	app := iris.New()
+	app.Logger().SetLevel("disable")
+	for _, h := range fn {
+		h(nil, app.Party("/"))
+	}
+	return makeRouteSpecs(app.GetRoutes())
+}
+
+func makeRouteSpecs(routes []*router.Route) []RouteSpec {
+	specs := make([]RouteSpec, 0, len(routes))
+	for _, route := range routes {
+		w, ok := routeWrappedHandler(route)
+		if !ok {
+			continue
+		}
+		specs = append(specs, RouteSpec{
+			RouteInfo: RouteInfo{
+				Method:      route.Method,
+				Path:        route.Path,
+				HandlerName: w.name,
+			},
+			Req: w.reqType,
+			Rsp: w.rspType,
+		})
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		if specs[i].Path == specs[j].Path {
+			return specs[i].Method < specs[j].Method
+		}
+		return specs[i].Path < specs[j].Path
+	})
+	return specs
+}
+
+// 获取路由最后一个经过 Wrap 包装的处理程序, 中间件不算
+func routeWrappedHandler(route *router.Route) (*wrappedHandler, bool) {
+	for i := len(route.Handlers) - 1; i >= 0; i-- {
+		w, ok := getWrappedHandler(route.Handlers[i])
+		if ok && !w.isMiddleware {
+			return w, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	iris_context "github.com/kataras/iris/v12/context"
+	"github.com/zly-app/zapp/core"
+)
+
+type routeSpecTestReq struct {
+	ID int `json:"id"`
+}
+
+type routeSpecTestRsp struct {
+	Name string `json:"name"`
+}
+
+func getRouteSpecTestUser(ctx *Context, req *routeSpecTestReq) (*routeSpecTestRsp, error) {
+	return &routeSpecTestRsp{}, nil
+}
+
+func routeSpecTestAuth(ctx *Context) error {
+	return nil
+}
+
+func TestCollectRouteSpecs(t *testing.T) {
+	specs := CollectRouteSpecs(func(_ core.IComponent, router Party) {
+		router.Post("/user", WrapMiddleware(routeSpecTestAuth), Wrap(getRouteSpecTestUser))
+		router.Get("/native", func(ctx *iris_context.Context) {})
+	})
+	if len(specs) != 1 {
+		t.Fatalf("只应该包含经过 Wrap 包装的路由: %+v", specs)
+	}
+	spec := specs[0]
+	if spec.Method != "POST" || spec.Path != "/user" || spec.Req != reflect.TypeOf(routeSpecTestReq{}) || spec.Rsp != reflect.TypeOf(&routeSpecTestRsp{}) {
+		t.Fatalf("路由的类型信息错误: %+v", spec)
+	}
+}
+
+func TestGetWrappedHandlerName(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		_ = Wrap(getRouteSpecTestUser)
+	}
+	runtime.GC()
+
+	name, isMiddleware, ok := GetWrappedHandlerName(WrapMiddleware(routeSpecTestAuth))
+	if !ok || !isMiddleware || name != "github.com/zly-app/service/api.routeSpecTestAuth" {
+		t.Fatalf("获取中间件名称错误: %s, %v, %v", name, isMiddleware, ok)
+	}
+
+	// 包装后的处理程序是同一个闭包函数创建的, 需要能区分
+	name, isMiddleware, ok = GetWrappedHandlerName(Wrap(getRouteSpecTestUser))
+	if !ok || isMiddleware || name != "github.com/zly-app/service/api.getRouteSpecTestUser" {
+		t.Fatalf("获取处理程序名称错误: %s, %v, %v", name, isMiddleware, ok)
+	}
+
+	// 没有经过包装的处理程序不会被执行
+	called := false
+	if _, _, ok := GetWrappedHandlerName(func(ctx *iris_context.Context) { called = true }); ok || called {
+		t.Fatalf("没有经过包装的处理程序不应该获取到名称, 且不应该被执行")
+	}
+	if _, _, ok := GetWrappedHandlerName(nil); ok {
+		t.Fatal("nil不应该获取到名称")
+	}
+}
@@ -11,6 +11,10 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
 
 	"github.com/iris-contrib/middleware/cors"
 	"github.com/kataras/iris/v12"
@@ -20,7 +24,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/limiter"
 	"github.com/zly-app/service/api/middleware"
+	"github.com/zly-app/service/api/utils"
 )
 
 type Party = iris.Party
@@ -29,11 +35,27 @@ type Party = iris.Party
 type RegisterApiRouterFunc = func(c core.IComponent, router Party)
 
 type ApiService struct {
-	app  core.IApp
-	conf *config.Config
+	app        core.IApp
+	conf       *config.Config
+	dynConf    *config.Dynamic   // 可在运行时修改的配置
+	adminApp   *iris.Application // 管理接口服务, 只有管理接口使用单独的bind地址时才会创建
+	adminParty Party             // 管理接口路由, 只有启用管理接口时才会创建
+	accessLog  *middleware.AccessLogger
 	*iris.Application
 }
 
+// 请求优先级解析函数, 用于自适应限流时决定请求被丢弃的顺序
+type PriorityResolver func(ctx *Context) limiter.Priority
+
+// 请求限制, 如果 conf.LimitMode 为 aimd 或 gradient 使用自适应限流, 否则使用协程池限制
+func limitMiddleware(app core.IApp, conf *config.Config, resolver PriorityResolver) func(ctx *Context) error {
+	switch limiter.Algorithm(conf.LimitMode) {
+	case limiter.AIMD, limiter.Gradient:
+		return AdaptiveLimitMiddleware(app, conf, resolver)
+	}
+	return GPoolLimitMiddleware(app, conf)
+}
+
 // 协程池限制
 func GPoolLimitMiddleware(app core.IApp, conf *config.Config) func(ctx *Context) error {
 	pool := gpool.NewGPool(&gpool.GPoolConfig{
@@ -52,17 +74,85 @@ func GPoolLimitMiddleware(app core.IApp, conf *config.Config) func(ctx *Context)
 	}
 }
 
+// 自适应限流
+//
+// resolver 为请求优先级解析函数, 可以为nil, 如果返回 limiter.PriorityNormal 以外的值会覆盖关键路径的判断
+func AdaptiveLimitMiddleware(app core.IApp, conf *config.Config, resolver PriorityResolver) func(ctx *Context) error {
+	l := limiter.NewLimiter(limiter.Config{
+		Algorithm:     limiter.Algorithm(conf.LimitMode),
+		InitialLimit:  conf.AdaptiveLimitInitial,
+		MinLimit:      conf.AdaptiveLimitMin,
+		MaxLimit:      conf.AdaptiveLimitMax,
+		TargetLatency: time.Duration(conf.AdaptiveLimitTargetLatency) * time.Millisecond,
+	})
+	criticalPaths := splitConfValues(conf.AdaptiveLimitCriticalPaths)
+	return func(ctx *Context) error {
+		priority := limiter.PriorityNormal
+		if inStrings(criticalPaths, ctx.Path()) {
+			priority = limiter.PriorityCritical
+		}
+		if resolver != nil {
+			if p := resolver(ctx); p != limiter.PriorityNormal {
+				priority = p
+			}
+		}
+
+		if !l.Acquire(priority) {
+			ctx.Warn("api.shed", zap.String("priority", priority.String()), zap.Int("limit", l.Limit()))
+			return ServiceOverload
+		}
+
+		startTime := time.Now()
+		defer func() { l.Release(time.Since(startTime)) }()
+		ctx.Next()
+		return nil
+	}
+}
+
 func NewApiService(app core.IApp, conf *config.Config, opts ...Option) *ApiService {
 	// 处理选项
 	o := newOptions(opts...)
 
+	if _, err := utils.ParseIPNets(conf.TrustedProxies); err != nil {
+		app.Fatal("api可信代理配置错误", zap.String("TrustedProxies", conf.TrustedProxies), zap.Error(err))
+	}
+
+	dynConf := config.NewDynamic(conf)
+
 	// irisApp
 	irisApp := iris.New()
 	irisApp.Logger().SetLevel("disable") // 关闭默认日志
+	tenantResolvers := makeTenantResolvers(conf, o)
+	irisApp.Use(middleware.DynamicBaseMiddleware(app, dynConf, tenantResolvers...))
+
+	// 访问日志
+	var accessLog *middleware.AccessLogger
+	if conf.AccessLog {
+		var err error
+		accessLog, err = middleware.NewAccessLogger(conf)
+		if err != nil {
+			app.Fatal("创建api访问日志失败", zap.Error(err))
+		}
+		irisApp.Use(accessLog.Handler())
+	}
+
+	irisApp.Use(middleware.LoggerMiddleware(app, conf)) // 日志
+	if o.Reporter != nil {
+		irisApp.Use(ErrorReportMiddleware(o.Reporter)) // 错误上报
+	}
+	if conf.IPAllow != "" || conf.IPDeny != "" {
+		ipFilter, err := newIPFilter(conf.IPAllow, conf.IPDeny)
+		if err != nil {
+			app.Fatal("api的ip访问控制配置错误", zap.String("IPAllow", conf.IPAllow), zap.String("IPDeny", conf.IPDeny), zap.Error(err))
+		}
+		irisApp.Use(ipFilter.Handler()) // ip访问控制
+	}
+	if len(tenantResolvers) > 0 {
+		irisApp.Use(WrapMiddleware(TenantMiddleware())) // 租户
+	}
 	irisApp.Use(
-		middleware.BaseMiddleware(app, conf),
-		middleware.LoggerMiddleware(app, conf),          // 日志
-		WrapMiddleware(GPoolLimitMiddleware(app, conf)), // 协程池限制
+		WrapMiddleware(limitMiddleware(app, conf, o.PriorityResolver)), // 请求限制
+		WrapMiddleware(CompressMiddleware(app, conf)),                  // 压缩
 		cors.AllowAll(),
 		middleware.Recover(), // panic恢复
 	)
@@ -71,30 +161,84 @@ func NewApiService(app core.IApp, conf *config.Config, opts ...Option) *ApiServi
 	// 配置项
 	irisApp.Configure(o.Configurator...)
 
+	// 全局拦截器
+	if len(o.Interceptors) > 0 {
+		irisApp.Use(Intercept(o.Interceptors...))
+	}
+
 	// 中间件
 	for _, fn := range o.Middlewares {
 		irisApp.Use(WrapMiddleware(fn))
 	}
 
+	a := &ApiService{
+		app:         app,
+		conf:        conf,
+		dynConf:     dynConf,
+		accessLog:   accessLog,
+		Application: irisApp,
+	}
+
+	// 管理接口
+	if conf.EnableAdmin {
+		a.makeAdmin(o)
+	}
+
 	// 在app关闭前优雅的关闭服务
 	zapp.AddHandler(zapp.BeforeExitHandler, func(app core.IApp, handlerType zapp.HandlerType) {
+		if a.adminApp != nil {
+			if err := a.adminApp.Shutdown(context.Background()); err != nil {
+				app.Error("api管理接口关闭失败", zap.Error(err))
+			}
+		}
+
 		err := irisApp.Shutdown(context.Background())
 		if err != nil {
 			app.Error("irisApp关闭失败", zap.Error(err))
 			return
 		}
+		if a.accessLog != nil {
+			_ = a.accessLog.Close()
+		}
 		app.Warn("api服务已关闭")
 	})
 
-	return &ApiService{
-		app:         app,
-		conf:        conf,
-		Application: irisApp,
+	return a
+}
+
+// 构建管理接口
+func (a *ApiService) makeAdmin(o *options) {
+	if a.conf.AdminBind == "" {
+		// 挂载在api服务上时必须设置鉴权, 否则任何人都可以访问管理接口
+		if o.AdminAuth == nil {
+			a.app.Fatal("api管理接口挂载在api服务上时必须通过 api.WithAdminAuth 设置鉴权, 或者设置 AdminBind 使用单独的bind地址")
+		}
+		party := a.Party(a.conf.AdminPath)
+		if a.conf.AdminListener != "" {
+			party.Use(OnlyListeners(a.conf.AdminListener))
+		}
+		party.Use(WrapMiddleware(o.AdminAuth))
+		a.registryAdminRouter(party)
+		a.adminParty = party
+		return
+	}
+
+	adminApp := iris.New()
+	adminApp.Logger().SetLevel("disable") // 关闭默认日志
+	adminApp.Use(
+		middleware.DynamicBaseMiddleware(a.app, a.dynConf),
+		middleware.LoggerMiddleware(a.app, a.conf), // 日志
+		middleware.Recover(),                       // panic恢复
+	)
+	if o.AdminAuth != nil {
+		adminApp.Use(WrapMiddleware(o.AdminAuth))
 	}
+	a.adminParty = adminApp.Party(a.conf.AdminPath)
+	a.registryAdminRouter(a.adminParty)
+	a.adminApp = adminApp
 }
 
 func (a *ApiService) Start() error {
-	a.app.Info("正在启动api服务", zap.String("bind", a.conf.Bind))
 	opts := []iris.Configurator{
 		iris.WithoutBodyConsumptionOnUnmarshal,       // 重复消费
 		iris.WithoutPathCorrection,                   // 不自动补全斜杠
@@ -104,13 +248,50 @@ func (a *ApiService) Start() error {
 		iris.WithFireMethodNotAllowed,                // 路由未找到时返回405而不是404
 		iris.WithPostMaxMemory(a.conf.PostMaxMemory), // post允许客户端传输最大数据大小
 	}
-	if a.conf.IPWithNginxForwarded {
-		opts = append(opts, iris.WithRemoteAddrHeader("X-Forwarded-For"))
+
+	if a.adminApp != nil {
+		a.app.Info("正在启动api管理接口", zap.String("bind", a.conf.AdminBind))
+		go func() {
+			err := a.adminApp.Run(iris.Addr(a.conf.AdminBind, serverLimitsConfigurator(a.conf)), append(opts, iris.WithoutServerError(iris.ErrServerClosed))...)
+			if err != nil {
+				a.app.Error("api管理接口启动失败", zap.Error(err))
+			}
+		}()
+	}
+
+	// 创建所有监听器, 任何一个失败都不会启动服务
+	listeners := make([]net.Listener, 0, len(a.conf.Listeners))
+	for _, lc := range a.conf.Listeners {
+		a.app.Info("正在启动api服务", zap.String("listener", lc.Name), zap.String("network", lc.Network), zap.String("bind", lc.Addr))
+		l, err := newListener(lc)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return fmt.Errorf("创建api监听器 %s 失败: %s", lc.Name, err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	// 先构建路由, 额外的监听器需要在 Run 之前开始服务
+	a.Configure(opts...)
+	if err := a.Build(); err != nil {
+		return err
 	}
-	if a.conf.IPWithNginxReal {
-		opts = append(opts, iris.WithRemoteAddrHeader("X-Real-IP"))
+	for i := 1; i < len(listeners); i++ {
+		lc, l := a.conf.Listeners[i], listeners[i]
+		su := a.NewHost(newListenerServer(lc, a.conf))
+		go func() {
+			if err := su.Serve(l); err != nil && err != http.ErrServerClosed {
+				a.app.Error("api监听器服务失败", zap.String("listener", lc.Name), zap.Error(err))
+			}
+		}()
 	}
-	return a.Run(iris.Addr(a.conf.Bind), opts...)
+
+	lc, l := a.conf.Listeners[0], listeners[0]
+	return a.Run(func(app *iris.Application) error {
+		return app.NewHost(newListenerServer(lc, a.conf)).Serve(l)
+	})
 }
 
 // 注册路由
@@ -120,6 +301,16 @@ func (a *ApiService) RegistryRouter(fn ...RegisterApiRouterFunc) {
 	}
 }
 
+// 注册管理接口路由, 路由的路径前缀为 AdminPath, 和内置的管理接口使用相同的鉴权和监听器, 没有启用管理接口时会报错退出
+func (a *ApiService) RegistryAdminRouter(fn ...RegisterApiRouterFunc) {
+	if a.adminParty == nil {
+		a.app.Fatal("注册api管理接口路由失败, 没有启用管理接口 EnableAdmin")
+	}
+	for _, h := range fn {
+		h(a.app.GetComponent(), a.adminParty)
+	}
+}
+
 func (a *ApiService) Close() error {
 	return nil
 }
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/limiter"
+	"github.com/zly-app/service/api/middleware"
+	"github.com/zly-app/service/api/utils"
+)
+
+// 根据配置和选项创建租户解析器, 选项中的解析器优先
+func makeTenantResolvers(conf *config.Config, o *options) []TenantResolver {
+	resolvers := append([]TenantResolver(nil), o.Tenants...)
+	if conf.TenantHeader != "" {
+		resolvers = append(resolvers, middleware.HeaderTenantResolver(conf.TenantHeader))
+	}
+	if conf.TenantDomain != "" {
+		resolvers = append(resolvers, middleware.DomainTenantResolver(conf.TenantDomain))
+	}
+	return resolvers
+}
+
+// 租户限流器
+type tenantLimiter struct {
+	rate    float64
+	burst   int
+	limiter *limiter.RateLimiter
+}
+
+// 租户中间件, 拒绝没有租户的请求, 并检查租户允许访问的路由和限流
+func TenantMiddleware() func(ctx *Context) error {
+	var limiters sync.Map // map[string]*tenantLimiter
+	return func(ctx *Context) error {
+		path := ctx.Path()
+		if ctx.conf.TenantExempt(path) || isAdminPath(ctx.conf, path) {
+			return nil
+		}
+
+		tenant := ctx.Tenant()
+		if tenant == "" {
+			fields := []interface{}{"api.tenant_required", zap.String("path", path)}
+			if err, ok := ctx.Values().Get(utils.TenantErrorFieldKey).(error); ok {
+				fields = append(fields, zap.Error(err))
+			}
+			ctx.Warn(fields...)
+			return TenantRequired
+		}
+
+		tc := ctx.conf.TenantConfig(tenant)
+		if tc == nil {
+			return nil
+		}
+		if !tc.PathAllowed(path) {
+			ctx.Warn("api.tenant_forbidden", zap.String("path", path))
+			return TenantForbidden
+		}
+		if tc.RateLimit > 0 {
+			v, ok := limiters.Load(tenant)
+			l, _ := v.(*tenantLimiter)
+			if !ok || l.rate != tc.RateLimit || l.burst != tc.RateBurst { // 配置改变后重新创建
+				l = &tenantLimiter{rate: tc.RateLimit, burst: tc.RateBurst, limiter: limiter.NewRateLimiter(tc.RateLimit, tc.RateBurst)}
+				limiters.Store(tenant, l)
+			}
+			if !l.limiter.Allow() {
+				ctx.Warn("api.tenant_rate_limited", zap.Float64("rate", tc.RateLimit))
+				return TenantRateLimited
+			}
+		}
+		return nil
+	}
+}
+
+// 挂载在api服务上的管理接口不需要租户
+func isAdminPath(conf *config.Config, path string) bool {
+	return conf.EnableAdmin && conf.AdminBind == "" && config.HasPathPrefix(path, conf.AdminPath)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/middleware"
+)
+
+var (
+	testApp     core.IApp
+	testAppOnce sync.Once
+)
+
+// 一个进程只能创建一个app
+func getTestApp() core.IApp {
+	testAppOnce.Do(func() {
+		testApp = zapp.NewApp("test")
+	})
+	return testApp
+}
+
+// 发送请求并解析响应
+func serveTest(t *testing.T, s *ApiService, method, url string, headers map[string]string) Response {
+	r := httptest.NewRequest(method, url, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	var rsp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+		t.Fatalf("%s %s 解析响应失败: %v, %s", method, url, err, w.Body.String())
+	}
+	return rsp
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	conf := config.NewConfig()
+	conf.TenantHeader = "X-Tenant-Id"
+	conf.TenantDomain = "example.com"
+	conf.TenantExemptPaths = "/health"
+	conf.EnableAdmin = true
+	conf.Tenants = []config.TenantConfig{
+		{Tenant: "a", AllowedPaths: "/v1"},
+		{Tenant: "limited", RateLimit: 0.001, RateBurst: 1},
+	}
+	conf.Check()
+
+	s := NewApiService(getTestApp(), conf,
+		WithTenantResolver(middleware.JwtClaimTenantResolver("tenant_id", middleware.UnverifiedJwtClaims)),
+		WithAdminAuth(func(ctx *Context) error {
+			if ctx.GetHeader("X-Admin-Token") != "token" {
+				return AuthorizationError
+			}
+			return nil
+		}),
+	)
+	handler := Wrap(func(ctx *Context) interface{} { return ctx.Tenant() })
+	s.Get("/v1/user", handler)
+	s.Get("/v2/user", handler)
+	s.Get("/health", handler)
+	if err := s.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := "eyJhbGciOiJub25lIn0.eyJ0ZW5hbnRfaWQiOiJhIn0.sig" // {"tenant_id":"a"}
+	tests := []struct {
+		name    string
+		url     string
+		headers map[string]string
+		code    int
+		tenant  string
+	}{
+		{name: "header", url: "/v1/user", headers: map[string]string{"X-Tenant-Id": "a"}, code: OK.Code, tenant: "a"},
+		{name: "domain", url: "http://b.example.com/v2/user", code: OK.Code, tenant: "b"},
+		{name: "jwt", url: "/v1/user", headers: map[string]string{"Authorization": "Bearer " + jwt, "X-Tenant-Id": "b"}, code: OK.Code, tenant: "a"},
+		{name: "required", url: "/v1/user", code: TenantRequired.Code},
+		{name: "bad jwt", url: "/v1/user", headers: map[string]string{"Authorization": "Bearer bad"}, code: TenantRequired.Code},
+		{name: "exempt", url: "/health", code: OK.Code},
+		{name: "admin", url: conf.AdminPath + "/routes", headers: map[string]string{"X-Admin-Token": "token"}, code: OK.Code},
+		{name: "path not allowed", url: "/v2/user", headers: map[string]string{"X-Tenant-Id": "a"}, code: TenantForbidden.Code},
+		{name: "tenant without config", url: "/v2/user", headers: map[string]string{"X-Tenant-Id": "c"}, code: OK.Code, tenant: "c"},
+		{name: "rate limit", url: "/v1/user", headers: map[string]string{"X-Tenant-Id": "limited"}, code: OK.Code, tenant: "limited"},
+		{name: "rate limited", url: "/v1/user", headers: map[string]string{"X-Tenant-Id": "limited"}, code: TenantRateLimited.Code},
+		{name: "other tenant not limited", url: "/v1/user", headers: map[string]string{"X-Tenant-Id": "c"}, code: OK.Code, tenant: "c"},
+	}
+	for _, test := range tests {
+		rsp := serveTest(t, s, "GET", test.url, test.headers)
+		if rsp.ErrCode != test.code {
+			t.Fatalf("%s: 错误码应该为 %d, 实际为 %d, %s", test.name, test.code, rsp.ErrCode, rsp.ErrMsg)
+		}
+		if tenant, _ := rsp.Data.(string); test.code == OK.Code && test.name != "admin" && tenant != test.tenant {
+			t.Fatalf("%s: 租户应该为 %q, 实际为 %q", test.name, test.tenant, tenant)
+		}
+	}
+}
+
+func TestPathPrefixMatch(t *testing.T) {
+	conf := config.NewConfig()
+	conf.TenantExemptPaths = "/health, /static/"
+	conf.EnableAdmin = true
+	conf.Check()
+	tenant := &config.TenantConfig{AllowedPaths: "/orders,/users"}
+
+	tests := []struct {
+		path    string
+		allowed bool
+		exempt  bool
+		admin   bool
+	}{
+		{path: "/orders", allowed: true},
+		{path: "/orders/1", allowed: true},
+		{path: "/orders-admin"},
+		{path: "/ordersX"},
+		{path: "/users/1/orders", allowed: true},
+		{path: "/health", exempt: true},
+		{path: "/health/live", exempt: true},
+		{path: "/healthz"},
+		{path: "/static/a.js", exempt: true},
+		{path: "/static"},
+		{path: conf.AdminPath, admin: true},
+		{path: conf.AdminPath + "/routes", admin: true},
+		{path: conf.AdminPath + "x/routes"},
+	}
+	for _, test := range tests {
+		if allowed := tenant.PathAllowed(test.path); allowed != test.allowed {
+			t.Fatalf("%s: PathAllowed 应该为 %v", test.path, test.allowed)
+		}
+		if exempt := conf.TenantExempt(test.path); exempt != test.exempt {
+			t.Fatalf("%s: TenantExempt 应该为 %v", test.path, test.exempt)
+		}
+		if admin := isAdminPath(conf, test.path); admin != test.admin {
+			t.Fatalf("%s: isAdminPath 应该为 %v", test.path, test.admin)
+		}
+	}
+}
@@ -1,84 +1,127 @@
-/*
--------------------------------------------------
-   Author :       zlyuancn
-   date：         2021/1/21
-   Description :
--------------------------------------------------
-*/
-
-package utils
-
-import (
-	"context"
-	"net"
-	"strings"
-
-	"github.com/kataras/iris/v12"
-	"github.com/zly-app/zapp/core"
-
-	"github.com/zly-app/service/api/config"
-)
-
-var Context = new(contextUtil)
-
-type contextUtil struct{}
-
-// 日志保存字段
-const LoggerSaveFieldKey = "_api_logger"
-
-// 上下文保存字段
-const ContextFieldKey = "_ctx"
-
-// conf保存字段
-const ConfContextFieldKey = "_conf"
-
-// 将log保存在iris上下文中
-func (c *contextUtil) SaveLoggerToIrisContext(ctx iris.Context, log core.ILogger) {
-	ctx.Values().Set(LoggerSaveFieldKey, log)
-}
-
-// 从iris上下文中获取log, 如果失败会panic
-func (c *contextUtil) MustGetLoggerFromIrisContext(ctx iris.Context) core.ILogger {
-	return ctx.Values().Get(LoggerSaveFieldKey).(core.ILogger)
-}
-
-// 将context保存在iris上下文中
-func (c *contextUtil) SaveContextToIrisContext(ctx iris.Context, context context.Context) {
-	ctx.Values().Set(ContextFieldKey, context)
-}
-
-// 从iris上下文中获取context, 如果失败会panic
-func (c *contextUtil) MustGetContextFromIrisContext(ctx iris.Context) context.Context {
-	return ctx.Values().Get(ContextFieldKey).(context.Context)
-}
-
-// 将conf保存在iris上下文中
-func (c *contextUtil) SaveConfToIrisContext(ctx iris.Context, conf *config.Config) {
-	ctx.Values().Set(ConfContextFieldKey, conf)
-}
-
-// 从iris上下文中获取conf, 如果失败会panic
-func (c *contextUtil) MustGetConfFromIrisContext(ctx iris.Context) *config.Config {
-	return ctx.Values().Get(ConfContextFieldKey).(*config.Config)
-}
-
-// 试图解析并返回真实客户端的请求IP
-func (c *contextUtil) GetRemoteIP(ctx iris.Context) string {
-	remoteHeaders := ctx.Application().ConfigurationReadOnly().GetRemoteAddrHeaders()
-	for _, headerName := range remoteHeaders {
-		ipAddresses := strings.Split(ctx.GetHeader(headerName), ",")
-		for _, addr := range ipAddresses {
-			if net.ParseIP(addr) != nil {
-				return addr
-			}
-		}
-	}
-
-	addr := strings.TrimSpace(ctx.Request().RemoteAddr)
-	if addr != "" {
-		if ip, _, err := net.SplitHostPort(addr); err == nil {
-			return ip
-		}
-	}
-	return addr
-}
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2021/1/21
+   Description :
+-------------------------------------------------
+*/
+
+package utils
+
+import (
+	"context"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp/core"
+
+	"github.com/zly-app/service/api/config"
+)
+
+var Context = new(contextUtil)
+
+type contextUtil struct{}
+
+// 日志保存字段
+const LoggerSaveFieldKey = "_api_logger"
+
+// 上下文保存字段
+const ContextFieldKey = "_ctx"
+
+// conf保存字段
+const ConfContextFieldKey = "_conf"
+
+// 请求id保存字段
+const RequestIDFieldKey = "_request_id"
+
+// 响应错误码保存字段
+const ErrCodeFieldKey = "_err_code"
+
+// 用户标识保存字段
+const PrincipalFieldKey = "_principal"
+
+// 客户端ip保存字段
+const RemoteIPFieldKey = "_remote_ip"
+
+// 租户保存字段
+const TenantFieldKey = "_tenant"
+
+// 解析租户的错误保存字段
+const TenantErrorFieldKey = "_tenant_error"
+
+// 请求id header
+const RequestIDHeaderKey = "X-Request-Id"
+
+// 请求body压缩后大小保存字段
+const RequestCompressedSizeFieldKey = "_req_compressed_size"
+
+// 响应body原始大小保存字段
+const ResponseRawSizeFieldKey = "_rsp_raw_size"
+
+// 响应body压缩后大小保存字段
+const ResponseCompressedSizeFieldKey = "_rsp_compressed_size"
+
+// 将log保存在iris上下文中
+func (c *contextUtil) SaveLoggerToIrisContext(ctx iris.Context, log core.ILogger) {
+	ctx.Values().Set(LoggerSaveFieldKey, log)
+}
+
+// 从iris上下文中获取log, 如果失败会panic
+func (c *contextUtil) MustGetLoggerFromIrisContext(ctx iris.Context) core.ILogger {
+	return ctx.Values().Get(LoggerSaveFieldKey).(core.ILogger)
+}
+
+// 将context保存在iris上下文中
+func (c *contextUtil) SaveContextToIrisContext(ctx iris.Context, context context.Context) {
+	ctx.Values().Set(ContextFieldKey, context)
+}
+
+// 从iris上下文中获取context, 如果失败会panic
+func (c *contextUtil) MustGetContextFromIrisContext(ctx iris.Context) context.Context {
+	return ctx.Values().Get(ContextFieldKey).(context.Context)
+}
+
+// 将conf保存在iris上下文中
+func (c *contextUtil) SaveConfToIrisContext(ctx iris.Context, conf *config.Config) {
+	ctx.Values().Set(ConfContextFieldKey, conf)
+}
+
+// 从iris上下文中获取conf, 如果失败会panic
+func (c *contextUtil) MustGetConfFromIrisContext(ctx iris.Context) *config.Config {
+	return ctx.Values().Get(ConfContextFieldKey).(*config.Config)
+}
+
+// 获取请求id
+func (c *contextUtil) GetRequestID(ctx iris.Context) string {
+	return ctx.Values().GetString(RequestIDFieldKey)
+}
+
+// 获取用户标识, 如果没有设置返回空字符串
+func (c *contextUtil) GetPrincipal(ctx iris.Context) string {
+	return ctx.Values().GetString(PrincipalFieldKey)
+}
+
+// 获取租户, 如果没有解析到租户返回空字符串
+func (c *contextUtil) GetTenant(ctx iris.Context) string {
+	return ctx.Values().GetString(TenantFieldKey)
+}
+
+// 获取响应错误码, 如果没有设置返回0
+func (c *contextUtil) GetErrCode(ctx iris.Context) int {
+	return ctx.Values().GetIntDefault(ErrCodeFieldKey, 0)
+}
+
+// 获取响应body的原始大小, 如果响应被压缩了返回压缩前的大小
+func (c *contextUtil) GetResponseRawSize(ctx iris.Context) int {
+	if size, ok := ctx.Values().Get(ResponseRawSizeFieldKey).(int); ok {
+		return size
+	}
+	return ctx.ResponseWriter().Written()
+}
+
+// 返回真实客户端的请求IP, 由基础中间件根据可信代理解析, 如果没有解析过则返回sock连接的ip
+func (c *contextUtil) GetRemoteIP(ctx iris.Context) string {
+	if ip := ctx.Values().GetString(RemoteIPFieldKey); ip != "" {
+		return ip
+	}
+	return ResolveClientIP(ctx.Request(), nil, nil)
+}
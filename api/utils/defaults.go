@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认值标签
+const DefaultTag = "default"
+
+// 支持的时间格式
+var defaultTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+var (
+	typeOfTime     = reflect.TypeOf(time.Time{})
+	typeOfDuration = reflect.TypeOf(time.Duration(0))
+)
+
+// 设置默认值的方式
+type defaultsMode int
+
+const (
+	defaultsModeInit defaultsMode = iota // 在bind前设置所有默认值
+	defaultsModeWalk                     // 在bind后查找由请求数据创建的结构体
+	defaultsModeFill                     // 只为零值字段设置默认值
+)
+
+// 默认值设置器, 根据字段的 default 标签设置默认值
+//
+// 支持基础类型, time.Time, time.Duration 和它们的指针和切片, 切片的默认值用英文逗号分隔, 如 default:"a,b,c".
+// 嵌套的结构体, 结构体指针和结构体切片中的字段也会设置默认值
+type DefaultsSetter struct {
+	fields []*defaultsField
+}
+
+type defaultsField struct {
+	index int
+	value reflect.Value   // 默认值, 无效值表示没有默认值
+	elem  *DefaultsSetter // 嵌套的结构体
+}
+
+// 根据结构体类型创建默认值设置器, 如果结构体中没有任何默认值返回nil
+func MakeDefaultsSetter(t reflect.Type) (*DefaultsSetter, error) {
+	return makeDefaultsSetter(t, make(map[reflect.Type]*DefaultsSetter))
+}
+
+func makeDefaultsSetter(t reflect.Type, cache map[reflect.Type]*DefaultsSetter) (*DefaultsSetter, error) {
+	if d, ok := cache[t]; ok { // 递归类型
+		return d, nil
+	}
+	d := new(DefaultsSetter)
+	cache[t] = d
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" { // 未导出
+			continue
+		}
+
+		if text, ok := field.Tag.Lookup(DefaultTag); ok {
+			value, err := parseDefaultValue(field.Type, text)
+			if err != nil {
+				return nil, fmt.Errorf("字段 %s.%s 的默认值 %q 无效: %s", t.Name(), field.Name, text, err)
+			}
+			d.fields = append(d.fields, &defaultsField{index: i, value: value})
+			continue
+		}
+
+		if st := nestedStructType(field.Type); st != nil {
+			elem, err := makeDefaultsSetter(st, cache)
+			if err != nil {
+				return nil, err
+			}
+			if elem != nil {
+				d.fields = append(d.fields, &defaultsField{index: i, elem: elem})
+			}
+		}
+	}
+
+	if len(d.fields) == 0 {
+		cache[t] = nil
+		return nil, nil
+	}
+	return d, nil
+}
+
+// 获取嵌套的结构体类型, 支持 struct, *struct, []struct, []*struct
+func nestedStructType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && t != typeOfTime {
+		return t
+	}
+	return nil
+}
+
+// 在bind前为结构体设置默认值, v 必须是可设置的结构体
+func (d *DefaultsSetter) Init(v reflect.Value) {
+	d.apply(v, defaultsModeInit)
+}
+
+// 在bind后为由请求数据创建的结构体设置默认值, 如切片和指针中的结构体, 这些结构体只会为零值字段设置默认值
+func (d *DefaultsSetter) Complete(v reflect.Value) {
+	d.apply(v, defaultsModeWalk)
+}
+
+func (d *DefaultsSetter) apply(v reflect.Value, mode defaultsMode) {
+	for _, f := range d.fields {
+		fv := v.Field(f.index)
+		if f.value.IsValid() {
+			if mode == defaultsModeInit || (mode == defaultsModeFill && fv.IsZero()) {
+				fv.Set(copyDefaultValue(f.value))
+			}
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			f.elem.apply(fv, mode)
+		case reflect.Ptr:
+			if mode != defaultsModeInit && !fv.IsNil() {
+				f.elem.apply(fv.Elem(), defaultsModeFill)
+			}
+		case reflect.Slice:
+			if mode == defaultsModeInit {
+				continue
+			}
+			for i := 0; i < fv.Len(); i++ {
+				item := fv.Index(i)
+				if item.Kind() == reflect.Ptr {
+					if item.IsNil() {
+						continue
+					}
+					item = item.Elem()
+				}
+				f.elem.apply(item, defaultsModeFill)
+			}
+		}
+	}
+}
+
+// 复制默认值, 避免不同请求共享指针和切片的底层数据
+func copyDefaultValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(v.Elem())
+		return p
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(s, v)
+		return s
+	}
+	return v
+}
+
+// 解析默认值
+func parseDefaultValue(t reflect.Type, text string) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, err := parseDefaultValue(t.Elem(), text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(elem)
+		return p, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 { // []byte
+			return reflect.ValueOf([]byte(text)).Convert(t), nil
+		}
+		var items []string
+		if text != "" {
+			items = strings.Split(text, ",")
+		}
+		s := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			v, err := parseDefaultValue(t.Elem(), strings.TrimSpace(item))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			s.Index(i).Set(v)
+		}
+		return s, nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch {
+	case t == typeOfTime:
+		tm, err := parseDefaultTime(text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return v, nil
+	case t == typeOfDuration:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(int64(d))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetFloat(f)
+	default:
+		return reflect.Value{}, fmt.Errorf("不支持的类型 %s", t)
+	}
+	return v, nil
+}
+
+func parseDefaultTime(text string) (time.Time, error) {
+	for _, layout := range defaultTimeLayouts {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("时间格式必须是 %s", strings.Join(defaultTimeLayouts, ", "))
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type defaultsItem struct {
+	Name  string `default:"item"`
+	Count int    `default:"1"`
+}
+
+type defaultsPager struct {
+	Page     int `default:"1"`
+	PageSize int `default:"20"`
+}
+
+type defaultsReq struct {
+	Keyword  string
+	Enable   bool          `default:"true"`
+	Ratio    float64       `default:"0.5"`
+	Tags     []string      `default:"a, b"`
+	Limit    *int          `default:"10"`
+	Timeout  time.Duration `default:"1m30s"`
+	Start    time.Time     `default:"2021-01-02"`
+	Pager    defaultsPager
+	Items    []defaultsItem
+	Ptr      *defaultsItem
+	Children []*defaultsReq
+}
+
+func TestDefaultsSetter(t *testing.T) {
+	d, err := MakeDefaultsSetter(reflect.TypeOf(defaultsReq{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req defaultsReq
+	d.Init(reflect.ValueOf(&req).Elem())
+	if !req.Enable || req.Ratio != 0.5 || *req.Limit != 10 || req.Timeout != 90*time.Second {
+		t.Fatalf("基础类型默认值错误: %+v", req)
+	}
+	if !reflect.DeepEqual(req.Tags, []string{"a", "b"}) {
+		t.Fatalf("切片默认值错误: %v", req.Tags)
+	}
+	if req.Start.Year() != 2021 || req.Start.Day() != 2 {
+		t.Fatalf("时间默认值错误: %v", req.Start)
+	}
+	if req.Pager.Page != 1 || req.Pager.PageSize != 20 {
+		t.Fatalf("嵌套结构体默认值错误: %+v", req.Pager)
+	}
+
+	// 不同实例不能共享指针和切片
+	var req2 defaultsReq
+	d.Init(reflect.ValueOf(&req2).Elem())
+	*req2.Limit = 5
+	req2.Tags[0] = "x"
+	if *req.Limit != 10 || req.Tags[0] != "a" {
+		t.Fatal("默认值被其它实例修改")
+	}
+
+	// 模拟bind, 明确设置的零值不会被覆盖, 由请求数据创建的结构体会为零值字段设置默认值
+	req.Enable = false
+	req.Items = []defaultsItem{{Name: "x"}, {}}
+	req.Ptr = &defaultsItem{Count: 3}
+	req.Children = []*defaultsReq{{Keyword: "c"}, nil}
+	d.Complete(reflect.ValueOf(&req).Elem())
+	if req.Enable {
+		t.Fatal("明确设置的零值不应该被覆盖")
+	}
+	if req.Items[0].Name != "x" || req.Items[0].Count != 1 || req.Items[1].Name != "item" {
+		t.Fatalf("切片中的结构体默认值错误: %+v", req.Items)
+	}
+	if req.Ptr.Name != "item" || req.Ptr.Count != 3 {
+		t.Fatalf("指针中的结构体默认值错误: %+v", req.Ptr)
+	}
+	if c := req.Children[0]; c.Keyword != "c" || c.Pager.PageSize != 20 || !c.Enable {
+		t.Fatalf("递归结构体默认值错误: %+v", c)
+	}
+}
+
+func TestDefaultsSetter_Invalid(t *testing.T) {
+	type badReq struct {
+		Size int `default:"abc"`
+	}
+	if _, err := MakeDefaultsSetter(reflect.TypeOf(badReq{})); err == nil {
+		t.Fatal("无效的默认值应该返回错误")
+	}
+
+	type noDefaults struct {
+		A string
+		B struct{ C int }
+	}
+	d, err := MakeDefaultsSetter(reflect.TypeOf(noDefaults{}))
+	if err != nil || d != nil {
+		t.Fatal("没有默认值时应该返回nil")
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// 表示所有ip
+const AllIP = "*"
+
+// ip网段列表
+type IPNets []*net.IPNet
+
+// 解析ip网段列表, 多个网段用英文逗号分隔, 支持 ip 和 CIDR, 如 10.0.0.0/8,127.0.0.1,::1, * 表示所有ip
+func ParseIPNets(s string) (IPNets, error) {
+	var nets IPNets
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if item == AllIP {
+			_, v4, _ := net.ParseCIDR("0.0.0.0/0")
+			_, v6, _ := net.ParseCIDR("::/0")
+			nets = append(nets, v4, v6)
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的ip: %s", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("无效的CIDR: %s", item)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// 检查ip是否在列表中
+func (n IPNets) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range n {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析客户端ip
+//
+// 只有sock连接的ip在 trusted 中时才会按顺序从 headers 中获取ip.
+// X-Forwarded-For 会从右往左解析, 跳过可信代理的ip, 第一个不可信的ip就是客户端ip, 如果全部都是可信代理则取最左边的ip.
+// 其它header只取第一个值
+func ResolveClientIP(r *http.Request, headers []string, trusted IPNets) string {
+	peer := strings.TrimSpace(r.RemoteAddr)
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !trusted.Contains(net.ParseIP(peer)) {
+		return peer
+	}
+
+	for _, name := range headers {
+		values := r.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if !strings.EqualFold(name, "X-Forwarded-For") {
+			if ip := parseHeaderIP(values[0]); ip != "" {
+				return ip
+			}
+			continue
+		}
+		if ip := resolveForwardedFor(values, trusted); ip != "" {
+			return ip
+		}
+	}
+	return peer
+}
+
+// 从右往左解析 X-Forwarded-For, 多个header会按顺序拼接
+func resolveForwardedFor(values []string, trusted IPNets) string {
+	var hops []string
+	for _, v := range values {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	var client string
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHeaderIP(hops[i])
+		if ip == "" { // 无效的值表示之后的数据不可信, 使用已经解析到的ip
+			break
+		}
+		client = ip
+		if !trusted.Contains(net.ParseIP(ip)) {
+			break
+		}
+	}
+	return client
+}
+
+// 解析header中的ip, 允许带端口, 无效时返回空字符串
+func parseHeaderIP(s string) string {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.Trim(s, "[]")
+	if net.ParseIP(s) == nil {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/zly-app/service/api/config"
+)
+
+func TestParseIPNets(t *testing.T) {
+	nets, err := ParseIPNets("10.0.0.0/8, 127.0.0.1,::1,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string]bool{
+		"10.1.2.3":  true,
+		"127.0.0.1": true,
+		"127.0.0.2": false,
+		"::1":       true,
+		"1.1.1.1":   false,
+	} {
+		if got := nets.Contains(net.ParseIP(ip)); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	all, _ := ParseIPNets(AllIP)
+	if !all.Contains(net.ParseIP("8.8.8.8")) || !all.Contains(net.ParseIP("2001:db8::1")) {
+		t.Error("* 应该包含所有ip")
+	}
+
+	for _, s := range []string{"abc", "10.0.0.0/33", "1.2.3"} {
+		if _, err := ParseIPNets(s); err == nil {
+			t.Errorf("ParseIPNets(%q) 应该返回错误", s)
+		}
+	}
+}
+
+func TestResolveClientIP(t *testing.T) {
+	trusted, _ := ParseIPNets("127.0.0.1,10.0.0.0/8")
+	headers := []string{"X-Forwarded-For", "X-Real-IP"}
+
+	tests := []struct {
+		name   string
+		peer   string
+		xff    []string
+		realIP string
+		want   string
+	}{
+		{name: "不可信的连接忽略header", peer: "1.1.1.1:1000", xff: []string{"2.2.2.2"}, realIP: "3.3.3.3", want: "1.1.1.1"},
+		{name: "可信的连接", peer: "127.0.0.1:1000", xff: []string{"2.2.2.2"}, want: "2.2.2.2"},
+		{name: "从右往左跳过可信代理", peer: "127.0.0.1:1000", xff: []string{"6.6.6.6, 2.2.2.2, 10.0.0.2"}, want: "2.2.2.2"},
+		{name: "多个header", peer: "127.0.0.1:1000", xff: []string{"6.6.6.6, 2.2.2.2", "10.0.0.2"}, want: "2.2.2.2"},
+		{name: "全部可信取最左边", peer: "127.0.0.1:1000", xff: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "无效值停止解析", peer: "127.0.0.1:1000", xff: []string{"2.2.2.2, unknown, 10.0.0.2"}, want: "10.0.0.2"},
+		{name: "带端口", peer: "127.0.0.1:1000", xff: []string{"[2001:db8::1]:443"}, want: "2001:db8::1"},
+		{name: "使用Real", peer: "127.0.0.1:1000", realIP: "3.3.3.3", want: "3.3.3.3"},
+		{name: "无效的Real", peer: "127.0.0.1:1000", realIP: "x", want: "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.peer
+		for _, v := range tt.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := ResolveClientIP(r, headers, trusted); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveClientIPWithDefaultConfig(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Check()
+	trusted, err := ParseIPNets(conf.TrustedProxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 默认不信任任何连接, 本地回环和内网地址也会忽略header
+	for _, peer := range []string{"127.0.0.1:1000", "10.0.0.1:1000", "[::1]:1000"} {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = peer
+		r.Header.Set("X-Forwarded-For", "2.2.2.2")
+		r.Header.Set("X-Real-IP", "3.3.3.3")
+		want, _, _ := net.SplitHostPort(peer)
+		if got := ResolveClientIP(r, []string{"X-Forwarded-For", "X-Real-IP"}, trusted); got != want {
+			t.Errorf("%s: got %s, want %s", peer, got, want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+	"go.uber.org/zap"
+)
+
+const (
+	// 请求版本的header
+	AcceptVersionHeaderKey = "Accept-Version"
+	// Accept header 中表示版本的媒体类型参数, 如 application/json; version=2
+	AcceptHeaderVersionParam = "version"
+	// 响应版本的header
+	ApiVersionHeaderKey = "Api-Version"
+	// 版本弃用header
+	DeprecationHeaderKey = "Deprecation"
+	// 版本下线时间header
+	SunsetHeaderKey = "Sunset"
+
+	// 版本保存字段
+	versionFieldKey = "_api_version"
+)
+
+// 版本弃用信息
+type deprecation struct {
+	sunset time.Time // 下线时间, 为零值表示未确定
+	calls  int64     // 调用次数
+}
+
+// 版本化路由
+//
+// 同一个路径可以注册多个版本的处理程序, 版本按以下顺序选择:
+//  1. url前缀, 如 /v2/user
+//  2. Accept-Version header, 如 Accept-Version: 2
+//  3. Accept header 的 version 参数, 如 Accept: application/json; version=2
+//  4. 默认版本
+type VersionParty struct {
+	party          Party
+	defaultVersion string
+	deprecations   map[string]*deprecation
+	mx             sync.RWMutex
+}
+
+// 创建一个版本化路由, defaultVersion 为请求未指定版本时使用的版本
+func NewVersionParty(party Party, defaultVersion string) *VersionParty {
+	return &VersionParty{
+		party:          party,
+		defaultVersion: normalizeVersion(defaultVersion),
+		deprecations:   make(map[string]*deprecation),
+	}
+}
+
+// 将版本标记为弃用, sunset 为版本下线时间, 传入零值表示未确定
+func (v *VersionParty) Deprecate(version string, sunset time.Time) *VersionParty {
+	v.mx.Lock()
+	v.deprecations[normalizeVersion(version)] = &deprecation{sunset: sunset}
+	v.mx.Unlock()
+	return v
+}
+
+// 获取弃用版本的调用次数
+func (v *VersionParty) DeprecatedCalls(version string) int64 {
+	v.mx.RLock()
+	d, ok := v.deprecations[normalizeVersion(version)]
+	v.mx.RUnlock()
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(&d.calls)
+}
+
+// 注册路由, versions 的 key 为版本号, value 为处理程序, 处理程序会经过 Wrap 包装
+//
+// 每个版本注册为一个带url前缀的路由, 不带前缀的路由的处理程序为默认版本的处理程序,
+// 所以路由信息, 客户端生成和拦截器都和直接使用 Wrap 注册的路由相同
+func (v *VersionParty) Handle(method, path string, versions map[string]interface{}) {
+	handlers := make(map[string]iris.Handler, len(versions))
+	names := make([]string, 0, len(versions))
+	for version, handler := range versions {
+		version = normalizeVersion(version)
+		handlers[version] = Wrap(handler)
+		names = append(names, version)
+	}
+	sort.Strings(names)
+
+	// 根据header选择版本, 选择了默认版本时继续调用链
+	chain := []iris.Handler{func(irisCtx *iris_context.Context) {
+		v.dispatch(irisCtx, handlers, v.requestedVersion(irisCtx))
+	}}
+	if handler, ok := handlers[v.defaultVersion]; ok {
+		chain = append(chain, handler)
+	}
+	v.party.Handle(method, path, chain...)
+
+	// 根据url前缀选择版本
+	for _, version := range names {
+		version := version
+		v.party.Handle(method, "/v"+version+path, func(irisCtx *iris_context.Context) {
+			v.setVersion(irisCtx, version)
+			irisCtx.Next()
+		}, handlers[version])
+	}
+}
+
+func (v *VersionParty) Get(path string, versions map[string]interface{}) {
+	v.Handle(http.MethodGet, path, versions)
+}
+func (v *VersionParty) Post(path string, versions map[string]interface{}) {
+	v.Handle(http.MethodPost, path, versions)
+}
+func (v *VersionParty) Put(path string, versions map[string]interface{}) {
+	v.Handle(http.MethodPut, path, versions)
+}
+func (v *VersionParty) Delete(path string, versions map[string]interface{}) {
+	v.Handle(http.MethodDelete, path, versions)
+}
+func (v *VersionParty) Patch(path string, versions map[string]interface{}) {
+	v.Handle(http.MethodPatch, path, versions)
+}
+
+// 从header中获取请求的版本
+func (v *VersionParty) requestedVersion(irisCtx *iris_context.Context) string {
+	if version := irisCtx.GetHeader(AcceptVersionHeaderKey); version != "" {
+		return normalizeVersion(version)
+	}
+
+	for _, accept := range strings.Split(irisCtx.GetHeader("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if version, ok := params[AcceptHeaderVersionParam]; ok && version != "" {
+			return normalizeVersion(version)
+		}
+	}
+	return v.defaultVersion
+}
+
+// 使用指定版本的处理程序处理请求, 默认版本交给调用链中的下一个处理程序
+func (v *VersionParty) dispatch(irisCtx *iris_context.Context, handlers map[string]iris.Handler, version string) {
+	handler, ok := handlers[version]
+	if !ok {
+		ctx := makeContext(irisCtx)
+		WriteToCtx(ctx, VersionNotSupported.WithMessage("api version not supported: "+version))
+		ctx.StopExecution()
+		return
+	}
+
+	v.setVersion(irisCtx, version)
+	if version == v.defaultVersion {
+		irisCtx.Next()
+		return
+	}
+	handler(irisCtx)
+}
+
+// 设置请求的版本, 如果版本已弃用则添加弃用header并记录调用
+func (v *VersionParty) setVersion(irisCtx *iris_context.Context, version string) {
+	irisCtx.Values().Set(versionFieldKey, version)
+	irisCtx.Header(ApiVersionHeaderKey, version)
+
+	v.mx.RLock()
+	d, deprecated := v.deprecations[version]
+	v.mx.RUnlock()
+	if !deprecated {
+		return
+	}
+
+	irisCtx.Header(DeprecationHeaderKey, "true")
+	if !d.sunset.IsZero() {
+		irisCtx.Header(SunsetHeaderKey, d.sunset.UTC().Format(http.TimeFormat))
+	}
+
+	calls := atomic.AddInt64(&d.calls, 1)
+	ctx := makeContext(irisCtx)
+	ctx.Warn("api.deprecated_version",
+		zap.String("version", version),
+		zap.String("path", irisCtx.Path()),
+		zap.Int64("deprecated_calls", calls),
+	)
+}
+
+// 获取当前请求的api版本, 未使用版本化路由时返回空字符串
+func (c *Context) ApiVersion() string {
+	return c.Values().GetString(versionFieldKey)
+}
+
+// 统一版本号格式, 去掉开头的v
+func normalizeVersion(version string) string {
+	version = strings.TrimSpace(version)
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') {
+		version = version[1:]
+	}
+	return version
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zly-app/service/api/config"
+)
+
+type versionTestReq struct {
+	ID int `json:"id"`
+}
+
+type versionTestRspV1 struct {
+	Name string `json:"name"`
+}
+
+type versionTestRspV2 struct {
+	FullName string `json:"full_name"`
+}
+
+func versionTestGetUserV1(ctx *Context, req *versionTestReq) (*versionTestRspV1, error) {
+	return &versionTestRspV1{Name: "v1"}, nil
+}
+
+func versionTestGetUserV2(ctx *Context, req *versionTestReq) (*versionTestRspV2, error) {
+	return &versionTestRspV2{FullName: "v2"}, nil
+}
+
+func TestVersionParty(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Check()
+
+	intercepted := 0
+	s := NewApiService(getTestApp(), conf, WithInterceptor(func(ctx *Context, result interface{}, err error) (interface{}, error) {
+		intercepted++
+		return result, err
+	}))
+	NewVersionParty(s, "2").
+		Deprecate("1", time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)).
+		Get("/user", map[string]interface{}{
+			"v1": versionTestGetUserV1,
+			"v2": versionTestGetUserV2,
+		})
+	if err := s.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每个版本都是经过 Wrap 包装的路由
+	specs := map[string]RouteSpec{}
+	for _, spec := range s.RouteSpecs() {
+		specs[spec.Path] = spec
+	}
+	for path, rsp := range map[string]reflect.Type{
+		"/user":    reflect.TypeOf(&versionTestRspV2{}),
+		"/v1/user": reflect.TypeOf(&versionTestRspV1{}),
+		"/v2/user": reflect.TypeOf(&versionTestRspV2{}),
+	} {
+		spec, ok := specs[path]
+		if !ok {
+			t.Fatalf("路由信息中没有 %s: %+v", path, specs)
+		}
+		if spec.Req != reflect.TypeOf(versionTestReq{}) || spec.Rsp != rsp {
+			t.Fatalf("%s 的类型信息错误: %+v", path, spec)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		headers    map[string]string
+		code       int
+		version    string
+		deprecated bool
+	}{
+		{name: "default", url: "/user", code: OK.Code, version: "2"},
+		{name: "prefix", url: "/v1/user", code: OK.Code, version: "1", deprecated: true},
+		{name: "accept-version", url: "/user", headers: map[string]string{AcceptVersionHeaderKey: "v1"}, code: OK.Code, version: "1", deprecated: true},
+		{name: "accept", url: "/user", headers: map[string]string{"Accept": "application/json; version=2"}, code: OK.Code, version: "2"},
+		{name: "not supported", url: "/user", headers: map[string]string{AcceptVersionHeaderKey: "3"}, code: VersionNotSupported.Code},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			intercepted = 0
+			r := httptest.NewRequest(http.MethodGet, test.url, nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+
+			var rsp Response
+			if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+				t.Fatalf("解析响应失败: %v, %s", err, w.Body.String())
+			}
+			if rsp.ErrCode != test.code {
+				t.Fatalf("错误码应该为%d, 实际为%d", test.code, rsp.ErrCode)
+			}
+			if version := w.Header().Get(ApiVersionHeaderKey); version != test.version {
+				t.Fatalf("版本应该为 %q, 实际为 %q", test.version, version)
+			}
+			if deprecated := w.Header().Get(DeprecationHeaderKey) == "true"; deprecated != test.deprecated {
+				t.Fatalf("弃用header应该为 %v", test.deprecated)
+			}
+			if test.code == OK.Code && intercepted != 1 {
+				t.Fatalf("拦截器应该被调用, 实际调用了%d次", intercepted)
+			}
+		})
+	}
+}
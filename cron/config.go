@@ -1,51 +1,106 @@
-/*
--------------------------------------------------
-   Author :       zlyuancn
-   date：         2021/1/26
-   Description :
--------------------------------------------------
-*/
-
-package cron
-
-import (
-	"runtime"
-)
-
-const (
-	// 默认线程数
-	defaultThreadCount = -1
-	// 默认最大任务队列大小
-	defaultMaxTaskQueueSize = 10000
-)
-
-// CronService配置
-type Config struct {
-	// 线程数, 默认为-1
-	//
-	// 同时处理任务的全局最大goroutine数
-	// 如果为0, 所有触发的任务都会新开启一个goroutine
-	// 如果为-1, 使用逻辑cpu数量
-	ThreadCount int
-	// 最大任务队列大小, 默认为10000
-	//
-	// 只有 ThreadCount > 0 时生效
-	// 启动时创建一个指定大小的任务队列, 触发产生的任务会放入这个队列, 队列已满时新触发的任务会被抛弃
-	MaxTaskQueueSize int
-}
-
-func newConfig() *Config {
-	return &Config{
-		ThreadCount:      defaultThreadCount,
-		MaxTaskQueueSize: defaultMaxTaskQueueSize,
-	}
-}
-
-func (c *Config) check() {
-	if c.ThreadCount == -1 {
-		c.ThreadCount = runtime.NumCPU()
-	}
-	if c.MaxTaskQueueSize <= 0 {
-		c.MaxTaskQueueSize = defaultMaxTaskQueueSize
-	}
-}
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2021/1/26
+   Description :
+-------------------------------------------------
+*/
+
+package cron
+
+import (
+	"fmt"
+	"runtime"
+)
+
+const (
+	// 默认线程数
+	defaultThreadCount = -1
+	// 默认最大任务队列大小
+	defaultMaxTaskQueueSize = 10000
+	// 默认加锁模式
+	defaultLockMode = "fire_time"
+	// 默认锁租约时间
+	defaultLockTTL = 60000
+	// 默认每个任务保留的执行记录数
+	defaultHistorySize = 20
+	// 默认关闭时等待正在执行的任务结束的时间
+	defaultCloseGracePeriod = 10000
+)
+
+// CronService配置
+type Config struct {
+	// 线程数, 默认为-1
+	//
+	// 同时处理任务的全局最大goroutine数
+	// 如果为0, 所有触发的任务都会新开启一个goroutine
+	// 如果为-1, 使用逻辑cpu数量
+	ThreadCount int
+	// 最大任务队列大小, 默认为10000
+	//
+	// 只有 ThreadCount > 0 时生效
+	// 启动时创建一个指定大小的任务队列, 触发产生的任务会放入这个队列, 队列已满时新触发的任务会被抛弃
+	MaxTaskQueueSize int
+	// 加锁模式, 默认为 fire_time
+	//
+	// 只有通过 cron.SetLocker 设置了分布式锁时生效, 任务可以通过 TaskConfig.LockMode 覆盖这个设置
+	// none: 不加锁, 每个实例都会执行
+	// task: 以任务为粒度加锁, 同一时间只有一个实例在执行这个任务
+	// fire_time: 以触发时间为粒度加锁, 每次触发只有一个实例执行
+	LockMode string
+	// 锁租约时间, 单位毫秒, 默认为60000
+	//
+	// 任务执行期间每隔 1/3 租约时间续约一次, 实例崩溃后最多经过这个时间其它实例才能获取锁
+	LockTTL int
+	// 锁key前缀, 默认为 {app名}:cron:
+	LockKeyPrefix string
+	// 每个任务在内存中保留的执行记录数, 默认为20
+	HistorySize int
+	// 关闭时等待正在执行的任务结束的时间, 单位毫秒, 默认为10000
+	//
+	// 关闭时会先取消所有正在执行的任务的 IContext.Ctx(), 再等待任务结束, 超时后不再等待
+	CloseGracePeriod int
+	// 通过配置声明的任务, 启动时添加
+	Tasks []ConfigTask
+	// 观察任务配置的组名和key名, 设置了key名时通过配置观察提供者获取任务列表, 配置变更后不需要重启就会生效
+	//
+	// 观察的数据为 yaml 或 json 格式的任务列表, 和 Tasks 合并后应用
+	TasksWatchGroup string
+	TasksWatchKey   string
+}
+
+func newConfig() *Config {
+	return &Config{
+		ThreadCount:      defaultThreadCount,
+		MaxTaskQueueSize: defaultMaxTaskQueueSize,
+		LockMode:         defaultLockMode,
+		LockTTL:          defaultLockTTL,
+		HistorySize:      defaultHistorySize,
+		CloseGracePeriod: defaultCloseGracePeriod,
+	}
+}
+
+func (c *Config) check() error {
+	if c.ThreadCount == -1 {
+		c.ThreadCount = runtime.NumCPU()
+	}
+	if c.MaxTaskQueueSize <= 0 {
+		c.MaxTaskQueueSize = defaultMaxTaskQueueSize
+	}
+	if c.LockMode == "" {
+		c.LockMode = defaultLockMode
+	}
+	if mode, err := ParseLockMode(c.LockMode); err != nil || mode == LockDefault {
+		return fmt.Errorf("LockMode 配置错误: %s", c.LockMode)
+	}
+	if c.LockTTL <= 0 {
+		c.LockTTL = defaultLockTTL
+	}
+	if c.HistorySize <= 0 {
+		c.HistorySize = defaultHistorySize
+	}
+	if c.CloseGracePeriod <= 0 {
+		c.CloseGracePeriod = defaultCloseGracePeriod
+	}
+	return nil
+}
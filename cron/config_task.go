@@ -0,0 +1,210 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+)
+
+// 通过配置声明的任务
+type ConfigTask struct {
+	// 任务名
+	Name string `yaml:"Name"`
+	// handler名, 需要通过 cron.RegistryNamedHandler 注册
+	Handler string `yaml:"Handler"`
+	// cron表达式
+	Expression string `yaml:"Expression"`
+	// 是否启用
+	Enable bool `yaml:"Enable"`
+	// 失败重试次数, 默认为0
+	RetryCount int64 `yaml:"RetryCount"`
+	// 失败重试间隔时间, 单位毫秒, 默认为0
+	RetryInterval int `yaml:"RetryInterval"`
+	// 最大并发执行数, 默认为1, -1表示不限制
+	Concurrency int64 `yaml:"Concurrency"`
+	// 时区, 如 Asia/Shanghai, 默认使用本地时区
+	Timezone string `yaml:"Timezone"`
+}
+
+var (
+	namedHandlers   = make(map[string]Handler)
+	namedHandlersMx sync.RWMutex
+)
+
+// 注册一个命名的handler, 用于绑定配置声明的任务, 这个函数应该在服务启动之前调用
+func RegistryNamedHandler(name string, handler Handler) {
+	namedHandlersMx.Lock()
+	namedHandlers[name] = handler
+	namedHandlersMx.Unlock()
+}
+
+func getNamedHandler(name string) (Handler, bool) {
+	namedHandlersMx.RLock()
+	handler, ok := namedHandlers[name]
+	namedHandlersMx.RUnlock()
+	return handler, ok
+}
+
+// 根据配置创建任务
+func (conf ConfigTask) makeTask() (ITask, error) {
+	if conf.Name == "" {
+		return nil, errors.New("任务名为空")
+	}
+	handler, ok := getNamedHandler(conf.Handler)
+	if !ok {
+		return nil, fmt.Errorf("任务 %s 的handler %s 未注册", conf.Name, conf.Handler)
+	}
+
+	var loc *time.Location
+	if conf.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(conf.Timezone); err != nil {
+			return nil, fmt.Errorf("任务 %s 的时区错误: %s", conf.Name, err)
+		}
+	}
+	trigger, err := NewCronTriggerOfConfig(conf.Expression, CronTriggerConfig{Location: loc})
+	if err != nil {
+		return nil, fmt.Errorf("任务 %s 的表达式错误: %s", conf.Name, err)
+	}
+
+	concurrency := conf.Concurrency
+	switch {
+	case concurrency == 0:
+		concurrency = 1
+	case concurrency < 0:
+		concurrency = 0
+	}
+	return NewTaskOfConfig(conf.Name, TaskConfig{
+		Trigger:  trigger,
+		Executor: NewExecutor(conf.RetryCount, time.Duration(conf.RetryInterval)*time.Millisecond, concurrency),
+		Handler:  handler,
+		Enable:   conf.Enable,
+	}), nil
+}
+
+// 除了启用状态以外的配置是否相同
+func (conf ConfigTask) sameSchedule(other ConfigTask) bool {
+	conf.Enable, other.Enable = false, false
+	return conf == other
+}
+
+// 应用配置声明的任务, 和上次应用的配置比较后生效
+//
+// 只修改了启用状态的任务会启用或停用, 修改了其它配置的任务会替换触发器, 执行器和handler, 配置中删除的任务会被移除.
+// 配置有错误时不会修改任何任务
+func (c *CronService) ApplyConfigTasks(confs []ConfigTask) error {
+	c.configMx.Lock()
+	defer c.configMx.Unlock()
+
+	// 检查配置
+	tasks := make(map[string]ITask, len(confs))
+	newConfs := make(map[string]ConfigTask, len(confs))
+	for _, conf := range confs {
+		if _, ok := newConfs[conf.Name]; ok {
+			return fmt.Errorf("任务 %s 重复", conf.Name)
+		}
+		task, err := conf.makeTask()
+		if err != nil {
+			return err
+		}
+		if _, ok := c.configTasks[conf.Name]; !ok && c.GetTask(conf.Name) != nil {
+			return fmt.Errorf("任务 %s 已经在代码中注册", conf.Name)
+		}
+		tasks[conf.Name] = task
+		newConfs[conf.Name] = conf
+	}
+
+	// 移除删除的任务
+	for _, name := range sortedConfigTaskNames(c.configTasks) {
+		if _, ok := newConfs[name]; !ok {
+			c.RemoveTask(name)
+			delete(c.configTasks, name)
+			c.app.Info("移除配置的cron任务", zap.String("task_name", name))
+		}
+	}
+
+	for _, name := range sortedConfigTaskNames(newConfs) {
+		conf := newConfs[name]
+		old, ok := c.configTasks[name]
+		switch {
+		case ok && old == conf: // 没有变化
+			continue
+		case ok && old.sameSchedule(conf): // 只修改了启用状态
+			task := c.GetTask(name)
+			if task == nil {
+				c.app.Error("修改配置的cron任务启用状态失败, 任务不存在", zap.String("task_name", name))
+				delete(c.configTasks, name)
+				continue
+			}
+			c.EnableTask(task, conf.Enable)
+			c.app.Info("修改配置的cron任务启用状态", zap.String("task_name", name), zap.Bool("enable", conf.Enable))
+		case ok: // 替换配置, 保留执行记录
+			if !c.ReconfigureTask(tasks[name]) {
+				c.app.Error("修改配置的cron任务失败, 任务不存在", zap.String("task_name", name))
+				delete(c.configTasks, name)
+				continue
+			}
+			c.app.Info("修改配置的cron任务", zap.String("task_name", name), zap.String("expression", conf.Expression))
+		default:
+			if !c.AddTask(tasks[name]) {
+				c.app.Error("添加配置的cron任务失败", zap.String("task_name", name))
+				continue
+			}
+			c.app.Info("添加配置的cron任务", zap.String("task_name", name), zap.String("expression", conf.Expression))
+		}
+		c.configTasks[name] = conf
+	}
+	return nil
+}
+
+func sortedConfigTaskNames(confs map[string]ConfigTask) []string {
+	names := make([]string, 0, len(confs))
+	for name := range confs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 合并配置文件和观察key中的任务, 观察key中的任务会替换配置文件中的同名任务
+func mergeConfigTasks(static, watched []ConfigTask) []ConfigTask {
+	names := make(map[string]struct{}, len(watched))
+	for _, conf := range watched {
+		names[conf.Name] = struct{}{}
+	}
+	confs := make([]ConfigTask, 0, len(static)+len(watched))
+	for _, conf := range static {
+		if _, ok := names[conf.Name]; !ok {
+			confs = append(confs, conf)
+		}
+	}
+	return append(confs, watched...)
+}
+
+// 加载配置声明的任务, 设置了观察key时观察配置变更
+func (c *CronService) loadConfigTasks() {
+	if err := c.ApplyConfigTasks(c.conf.Tasks); err != nil {
+		c.app.Fatal("cron任务配置错误", zap.Error(err))
+	}
+	if c.conf.TasksWatchKey == "" {
+		return
+	}
+
+	c.app.GetConfig().WatchKey(c.conf.TasksWatchGroup, c.conf.TasksWatchKey).
+		AddCallback(func(w core.IConfigWatchKeyObject, _, _ []byte) {
+			var confs []ConfigTask
+			if err := w.ParseYaml(&confs); err != nil {
+				c.app.Error("解析cron任务配置失败", zap.String("group", w.GroupName()), zap.String("key", w.KeyName()), zap.Error(err))
+				return
+			}
+			confs = mergeConfigTasks(c.conf.Tasks, confs)
+			if err := c.ApplyConfigTasks(confs); err != nil {
+				c.app.Error("应用cron任务配置失败", zap.String("group", w.GroupName()), zap.String("key", w.KeyName()), zap.Error(err))
+			}
+		})
+}
@@ -0,0 +1,148 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyConfigTasks(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	RegistryNamedHandler("config_task_handler", func(ctx IContext) error { return nil })
+	c.AddTask(NewTask("code_task", "* * * * *", true, func(ctx IContext) error { return nil }))
+
+	confs := []ConfigTask{
+		{Name: "a", Handler: "config_task_handler", Expression: "0 * * * *", Enable: true},
+		{Name: "b", Handler: "config_task_handler", Expression: "0 3 * * *", Enable: true, Timezone: "Asia/Shanghai", Concurrency: -1},
+	}
+	if err := c.ApplyConfigTasks(confs); err != nil {
+		t.Fatal(err)
+	}
+	a, b := c.GetTask("a"), c.GetTask("b")
+	if a == nil || b == nil {
+		t.Fatalf("配置的任务没有添加: %v", c.TaskNames())
+	}
+	if b.GetTrigger().Location().String() != "Asia/Shanghai" {
+		t.Fatalf("任务时区错误: %s", b.GetTrigger().Location())
+	}
+
+	// 只修改启用状态时会停用任务, 修改表达式时会替换触发器并保留执行记录
+	if err := c.TriggerTask("b"); err != nil {
+		t.Fatal(err)
+	}
+	fireTime := time.Now().Add(-time.Hour)
+	c.fireTimes["b"] = fireTime
+	confs[0].Enable = false
+	confs[1].Expression = "0 4 * * *"
+	if err := c.ApplyConfigTasks(confs); err != nil {
+		t.Fatal(err)
+	}
+	if c.GetTask("a") != a || a.IsEnable() {
+		t.Fatal("修改启用状态后任务应该被停用且不会重新创建")
+	}
+	if nb := c.GetTask("b"); nb != b || nb.GetTrigger().Expression() != "CRON_TZ=Asia/Shanghai 0 4 * * *" {
+		t.Fatalf("修改表达式后任务应该替换触发器: %s", nb.GetTrigger().Expression())
+	}
+	if len(c.History("b", 0)) != 1 || !c.fireTimes["b"].Equal(fireTime) {
+		t.Fatal("修改表达式后应该保留执行记录和补偿的起点")
+	}
+
+	// 任务被移除后修改配置不会记录这个任务, 下次应用时重新添加
+	c.RemoveTask("b")
+	confs[1].Expression = "0 5 * * *"
+	if err := c.ApplyConfigTasks(confs); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.configTasks["b"]; ok || c.GetTask("b") != nil {
+		t.Fatal("修改不存在的任务时不应该记录任务配置")
+	}
+	if err := c.ApplyConfigTasks(confs); err != nil {
+		t.Fatal(err)
+	}
+	if nb := c.GetTask("b"); nb == nil || nb.GetTrigger().Expression() != "CRON_TZ=Asia/Shanghai 0 5 * * *" {
+		t.Fatal("下次应用配置时应该重新添加任务")
+	}
+
+	// 配置错误时不会修改任何任务
+	for _, bad := range [][]ConfigTask{
+		{{Name: "c", Handler: "not_found", Expression: "* * * * *"}},
+		{{Name: "c", Handler: "config_task_handler", Expression: "bad"}},
+		{{Name: "c", Handler: "config_task_handler", Expression: "* * * * *", Timezone: "Bad/Zone"}},
+		{{Name: "c", Handler: "config_task_handler", Expression: "* * * * *"}, {Name: "c", Handler: "config_task_handler", Expression: "* * * * *"}},
+		{{Name: "code_task", Handler: "config_task_handler", Expression: "* * * * *"}},
+	} {
+		if err := c.ApplyConfigTasks(bad); err == nil {
+			t.Fatalf("配置 %+v 应该返回错误", bad)
+		}
+		if c.GetTask("a") == nil || c.GetTask("c") != nil {
+			t.Fatal("配置错误时不应该修改任务")
+		}
+	}
+
+	// 删除的任务会被移除, 代码注册的任务不受影响
+	if err := c.ApplyConfigTasks(confs[1:]); err != nil {
+		t.Fatal(err)
+	}
+	names := c.TaskNames()
+	if len(names) != 2 || names[0] != "b" || names[1] != "code_task" {
+		t.Fatalf("任务列表错误: %v", names)
+	}
+}
+
+func TestMergeConfigTasks(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	RegistryNamedHandler("config_task_handler", func(ctx IContext) error { return nil })
+
+	static := []ConfigTask{
+		{Name: "merge_a", Handler: "config_task_handler", Expression: "0 * * * *", Enable: true},
+		{Name: "merge_b", Handler: "config_task_handler", Expression: "0 3 * * *", Enable: true},
+	}
+	if err := c.ApplyConfigTasks(static); err != nil {
+		t.Fatal(err)
+	}
+	a := c.GetTask("merge_a")
+
+	// 观察key中的同名任务替换配置文件中的任务, 不会被认为重复
+	watched := []ConfigTask{
+		{Name: "merge_a", Handler: "config_task_handler", Expression: "0 2 * * *", Enable: true},
+		{Name: "merge_c", Handler: "config_task_handler", Expression: "0 4 * * *", Enable: true},
+	}
+	if err := c.ApplyConfigTasks(mergeConfigTasks(static, watched)); err != nil {
+		t.Fatal(err)
+	}
+	if na := c.GetTask("merge_a"); na != a || na.GetTrigger().Expression() != "0 2 * * *" {
+		t.Fatalf("观察key中的配置应该替换配置文件中的同名任务: %s", na.GetTrigger().Expression())
+	}
+	if c.GetTask("merge_b") == nil || c.GetTask("merge_c") == nil {
+		t.Fatalf("任务列表错误: %v", c.TaskNames())
+	}
+
+	// 观察key中删除后恢复配置文件中的配置
+	if err := c.ApplyConfigTasks(mergeConfigTasks(static, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if na := c.GetTask("merge_a"); na == nil || na.GetTrigger().Expression() != "0 * * * *" || c.GetTask("merge_c") != nil {
+		t.Fatalf("应该恢复配置文件中的任务: %v", c.TaskNames())
+	}
+}
+
+func TestReconfigureTask(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	task := NewTask("reconfigure", "0 * * * *", true, func(ctx IContext) error { return nil })
+	c.AddTask(task)
+	if err := c.TriggerTask("reconfigure"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.ReconfigureTask(NewTask("reconfigure", "0 2 * * *", false, func(ctx IContext) error { return nil })) {
+		t.Fatal("替换存在的任务应该返回 true")
+	}
+	if c.GetTask("reconfigure") != task || task.GetTrigger().Expression() != "0 2 * * *" || task.IsEnable() {
+		t.Fatal("任务的触发器和启用状态应该被替换")
+	}
+	if len(c.History("reconfigure", 0)) != 1 {
+		t.Fatal("替换配置后应该保留执行记录")
+	}
+	if c.ReconfigureTask(NewTask("not_found", "0 2 * * *", true, func(ctx IContext) error { return nil })) {
+		t.Fatal("替换不存在的任务应该返回 false")
+	}
+}
@@ -1,52 +1,88 @@
-package cron
-
-import (
-	"github.com/zly-app/zapp/core"
-	"go.uber.org/zap"
-)
-
-type Handler func(ctx IContext) (err error)
-
-type IContext interface {
-	// 获取task
-	Task() ITask
-	// 获取handler
-	Handler() Handler
-	// 获取元数据
-	Meta() interface{}
-	// 设置元数据
-	SetMeta(meta interface{})
-
-	core.ILogger
-}
-
-type Context struct {
-	task    ITask
-	handler Handler
-	meta    interface{}
-	core.ILogger
-}
-
-func newContext(app core.IApp, task ITask) IContext {
-	return &Context{
-		task:    task,
-		handler: task.Handler(),
-		meta:    nil,
-		ILogger: app.NewSessionLogger(zap.String("task_name", task.Name())),
-	}
-}
-
-func (ctx *Context) Task() ITask {
-	return ctx.task
-}
-
-func (ctx *Context) Handler() Handler {
-	return ctx.handler
-}
-
-func (ctx *Context) Meta() interface{} {
-	return ctx.meta
-}
-func (ctx *Context) SetMeta(meta interface{}) {
-	ctx.meta = meta
-}
+package cron
+
+import (
+	"context"
+
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+)
+
+type Handler func(ctx IContext) (err error)
+
+type IContext interface {
+	// 获取 context.Context, 服务关闭或执行超时时会被取消
+	Ctx() context.Context
+	// 获取task
+	Task() ITask
+	// 获取handler
+	Handler() Handler
+	// 返回执行id, 上游任务触发的执行和上游任务的执行id相同
+	RunID() string
+	// 返回手动触发时传入的数据, 其它触发方式返回nil
+	Payload() []byte
+	// 返回当前是第几次执行, 从1开始, 重试时递增
+	Attempt() int
+	// 获取元数据
+	Meta() interface{}
+	// 设置元数据
+	SetMeta(meta interface{})
+
+	core.ILogger
+}
+
+type Context struct {
+	ctx     context.Context
+	task    ITask
+	handler Handler
+	runID   string
+	payload []byte
+	attempt int
+	meta    interface{}
+	core.ILogger
+}
+
+func newContext(ctx context.Context, app core.IApp, task ITask, runID string, payload []byte) IContext {
+	return &Context{
+		ctx:     ctx,
+		task:    task,
+		handler: task.Handler(),
+		runID:   runID,
+		payload: payload,
+		meta:    nil,
+		ILogger: app.NewSessionLogger(zap.String("task_name", task.Name()), zap.String("run_id", runID)),
+	}
+}
+
+func (ctx *Context) Ctx() context.Context {
+	return ctx.ctx
+}
+
+func (ctx *Context) Task() ITask {
+	return ctx.task
+}
+
+func (ctx *Context) Handler() Handler {
+	return ctx.handler
+}
+
+func (ctx *Context) RunID() string {
+	return ctx.runID
+}
+
+func (ctx *Context) Payload() []byte {
+	return ctx.payload
+}
+
+func (ctx *Context) Attempt() int {
+	return ctx.attempt
+}
+func (ctx *Context) setAttempt(attempt int) {
+	ctx.attempt = attempt
+}
+
+func (ctx *Context) Meta() interface{} {
+	return ctx.meta
+}
+func (ctx *Context) SetMeta(meta interface{}) {
+	ctx.meta = meta
+}
@@ -0,0 +1,92 @@
+package cron
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestContextTimeout(t *testing.T) {
+	task := NewTaskOfConfig("timeout", TaskConfig{
+		Trigger:  NewCronTrigger("@every 1h"),
+		Executor: NewExecutor(0, 0, 1),
+		Handler: func(ctx IContext) error {
+			<-ctx.Ctx().Done()
+			return ctx.Ctx().Err()
+		},
+		Enable:  true,
+		Timeout: 50 * time.Millisecond,
+	})
+	c := NewCronService(getTestApp()).(*CronService)
+	c.AddTask(task)
+
+	start := time.Now()
+	err := c.TriggerTask("timeout")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("应该返回 context.DeadlineExceeded, 实际为 %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("超时时间无效")
+	}
+}
+
+func TestCloseWaitTasks(t *testing.T) {
+	var finished, started int32
+	task := NewTaskOfConfig("close", TaskConfig{
+		Trigger:  NewCronTrigger("@every 1h"),
+		Executor: NewExecutor(0, 0, 1),
+		Handler: func(ctx IContext) error {
+			atomic.StoreInt32(&started, 1)
+			<-ctx.Ctx().Done() // 关闭时取消
+			time.Sleep(50 * time.Millisecond)
+			atomic.StoreInt32(&finished, 1)
+			return nil
+		},
+		Enable: true,
+	})
+	c := NewCronService(getTestApp()).(*CronService)
+	c.AddTask(task)
+	_ = c.Start()
+
+	go c.execute(task, taskFire{fireTime: time.Now(), source: RunBySchedule})
+	for atomic.LoadInt32(&started) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_ = c.Close()
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Fatal("关闭时应该等待任务执行结束")
+	}
+}
+
+func TestCloseGracePeriod(t *testing.T) {
+	var started int32
+	release := make(chan struct{})
+	defer close(release)
+	task := NewTaskOfConfig("grace", TaskConfig{
+		Trigger:  NewCronTrigger("@every 1h"),
+		Executor: NewExecutor(0, 0, 1),
+		Handler: func(ctx IContext) error {
+			atomic.StoreInt32(&started, 1)
+			<-release // 忽略取消
+			return nil
+		},
+		Enable: true,
+	})
+	c := NewCronService(getTestApp()).(*CronService)
+	c.conf.CloseGracePeriod = 100
+	c.AddTask(task)
+	_ = c.Start()
+
+	go c.execute(task, taskFire{fireTime: time.Now(), source: RunBySchedule})
+	for atomic.LoadInt32(&started) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	_ = c.Close()
+	if d := time.Since(start); d < 100*time.Millisecond || d > time.Second {
+		t.Fatalf("应该等待 100ms 后关闭, 实际等待了 %s", d)
+	}
+}
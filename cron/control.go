@@ -0,0 +1,317 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+)
+
+// 控制面需要的cron接口, ICron 和 *CronService 都实现了这个接口
+type IControlCron interface {
+	RunState() RunState
+	Pause()
+	Resume()
+	EnableTask(task ITask, enable bool)
+	Tasks() []ITask
+	GetTask(name string) ITask
+	TriggerTaskWithPayload(name string, payload []byte) error
+	History(name string, limit int) []RunRecord
+	NextFireTime(name string) (time.Time, bool)
+}
+
+// 控制面鉴权, 返回错误时拒绝请求
+type ControlAuthFunc func(r *http.Request) error
+
+// 不做鉴权, 只能在外层已经鉴权时使用, 如通过 api.MountStd 挂载到设置了 api.WithAdminAuth 的api管理接口上
+var TrustedControlAuth ControlAuthFunc = func(r *http.Request) error { return nil }
+
+const (
+	// 默认手动触发时传入数据的最大大小
+	defaultControlMaxPayloadSize = 1 << 20
+	// 默认任务详情返回的执行记录数
+	defaultControlHistoryLimit = 10
+	// 默认手动触发时等待执行结束的最长时间
+	defaultControlTriggerWaitTimeout = 30 * time.Second
+)
+
+// 控制面配置
+type ControlConfig struct {
+	// 路由前缀, 如 /admin/cron, 请求路径去掉前缀后匹配路由, 通过 api.MountStd 挂载时不需要设置
+	Prefix string
+	// 鉴权, 为nil时拒绝所有请求
+	Auth ControlAuthFunc
+	// 手动触发时传入数据的最大大小, 单位字节, 默认为1M
+	MaxPayloadSize int64
+	// 手动触发并等待执行结束时最多等待的时间, 超时后返回202, 任务会继续执行, 默认为30秒
+	TriggerWaitTimeout time.Duration
+	// 记录修改操作的日志, 为nil时不记录
+	Logger core.ILogger
+}
+
+// 控制面返回的任务信息
+type ControlTaskInfo struct {
+	Name         string      `json:"name"`
+	Enable       bool        `json:"enable"`
+	TriggerType  string      `json:"trigger_type"`
+	Expression   string      `json:"expression"`
+	Location     string      `json:"location"`
+	NextFireTime *time.Time  `json:"next_fire_time,omitempty"` // 下次触发时间, 定时器未运行, 未启用或没有下次触发时为空
+	Running      bool        `json:"running"`                  // 是否正在执行
+	LastRun      *RunRecord  `json:"last_run,omitempty"`       // 最近一次执行记录
+	History      []RunRecord `json:"history,omitempty"`        // 最近的执行记录, 只有获取单个任务时返回
+}
+
+type controlStateResult struct {
+	State string `json:"state"`
+}
+
+type controlTasksResult struct {
+	State string            `json:"state"`
+	Tasks []ControlTaskInfo `json:"tasks"`
+}
+
+type controlTriggerResult struct {
+	Name  string `json:"name"`
+	Async bool   `json:"async,omitempty"` // 没有等待执行结束
+	Err   string `json:"err,omitempty"`   // 任务执行失败的错误
+}
+
+type controlErrorResult struct {
+	Err string `json:"err"`
+}
+
+// 控制面
+type controlHandler struct {
+	cron IControlCron
+	conf ControlConfig
+}
+
+// 创建cron控制面, 返回的 http.Handler 可以通过 api.MountStd 挂载到api服务或api管理接口上
+//
+//	GET  {prefix}/state                  获取运行状态
+//	POST {prefix}/pause                  暂停定时器
+//	POST {prefix}/resume                 恢复定时器
+//	GET  {prefix}/tasks                  获取任务列表
+//	GET  {prefix}/tasks/{name}           获取任务详情和最近的执行记录
+//	POST {prefix}/tasks/{name}/enable    启用任务
+//	POST {prefix}/tasks/{name}/disable   停用任务
+//	POST {prefix}/tasks/{name}/trigger   立即执行任务, body 为传入的数据, 默认不等待执行结束, 加上 ?wait=true 时等待执行结束
+func NewControlHandler(cron IControlCron, conf ControlConfig) http.Handler {
+	conf.Prefix = strings.TrimRight(conf.Prefix, "/")
+	if conf.MaxPayloadSize <= 0 {
+		conf.MaxPayloadSize = defaultControlMaxPayloadSize
+	}
+	if conf.TriggerWaitTimeout <= 0 {
+		conf.TriggerWaitTimeout = defaultControlTriggerWaitTimeout
+	}
+	return &controlHandler{cron: cron, conf: conf}
+}
+
+func (h *controlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.conf.Auth == nil {
+		h.writeError(w, http.StatusForbidden, errors.New("cron控制面没有设置鉴权"))
+		return
+	}
+	if err := h.conf.Auth(r); err != nil {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	path := r.URL.EscapedPath()
+	if !strings.HasPrefix(path, h.conf.Prefix+"/") {
+		http.NotFound(w, r)
+		return
+	}
+	path = strings.TrimPrefix(path, h.conf.Prefix)
+
+	switch {
+	case path == "/state":
+		h.route(w, r, http.MethodGet, h.state)
+	case path == "/pause":
+		h.route(w, r, http.MethodPost, h.pause)
+	case path == "/resume":
+		h.route(w, r, http.MethodPost, h.resume)
+	case path == "/tasks":
+		h.route(w, r, http.MethodGet, h.tasks)
+	case strings.HasPrefix(path, "/tasks/"):
+		h.taskRoute(w, r, strings.TrimPrefix(path, "/tasks/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// 检查请求方法后处理
+func (h *controlHandler) route(w http.ResponseWriter, r *http.Request, method string, fn http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		h.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	fn(w, r)
+}
+
+// 任务路由, path 为 {name} 或 {name}/{action}
+func (h *controlHandler) taskRoute(w http.ResponseWriter, r *http.Request, path string) {
+	rawName, action := path, ""
+	if r.Method != http.MethodGet {
+		if i := strings.LastIndexByte(path, '/'); i > 0 {
+			rawName, action = path[:i], path[i+1:]
+		}
+	}
+	name, err := url.PathUnescape(rawName)
+	if err != nil || name == "" {
+		http.NotFound(w, r)
+		return
+	}
+	task := h.cron.GetTask(name)
+	if task == nil {
+		h.writeError(w, http.StatusNotFound, TaskNotFound)
+		return
+	}
+
+	switch action {
+	case "":
+		h.route(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			info := h.taskInfo(task)
+			info.History = h.cron.History(name, defaultControlHistoryLimit)
+			h.writeJSON(w, http.StatusOK, info)
+		})
+	case "enable", "disable":
+		h.route(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			h.cron.EnableTask(task, action == "enable")
+			h.log(r, action, name)
+			h.writeJSON(w, http.StatusOK, h.taskInfo(task))
+		})
+	case "trigger":
+		h.route(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			h.trigger(w, r, name)
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *controlHandler) state(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, controlStateResult{State: h.cron.RunState().String()})
+}
+
+func (h *controlHandler) pause(w http.ResponseWriter, r *http.Request) {
+	h.cron.Pause()
+	h.log(r, "pause", "")
+	h.state(w, r)
+}
+
+func (h *controlHandler) resume(w http.ResponseWriter, r *http.Request) {
+	h.cron.Resume()
+	h.log(r, "resume", "")
+	h.state(w, r)
+}
+
+func (h *controlHandler) tasks(w http.ResponseWriter, r *http.Request) {
+	tasks := h.cron.Tasks()
+	result := controlTasksResult{
+		State: h.cron.RunState().String(),
+		Tasks: make([]ControlTaskInfo, len(tasks)),
+	}
+	for i, task := range tasks {
+		result.Tasks[i] = h.taskInfo(task)
+	}
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+func (h *controlHandler) trigger(w http.ResponseWriter, r *http.Request, name string) {
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, h.conf.MaxPayloadSize+1))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if int64(len(payload)) > h.conf.MaxPayloadSize {
+		h.writeError(w, http.StatusRequestEntityTooLarge, errors.New("payload too large"))
+		return
+	}
+	if len(payload) == 0 {
+		payload = nil
+	}
+	h.log(r, "trigger", name)
+
+	// 执行结果会写入执行记录, 不等待时通过任务详情查看
+	done := make(chan error, 1)
+	go func() { done <- h.cron.TriggerTaskWithPayload(name, payload) }()
+
+	if wait, _ := strconv.ParseBool(r.URL.Query().Get("wait")); !wait {
+		h.writeJSON(w, http.StatusAccepted, controlTriggerResult{Name: name, Async: true})
+		return
+	}
+
+	timer := time.NewTimer(h.conf.TriggerWaitTimeout)
+	defer timer.Stop()
+	select {
+	case err = <-done:
+	case <-timer.C: // 等待超时, 任务继续执行
+		h.writeJSON(w, http.StatusAccepted, controlTriggerResult{Name: name, Async: true})
+		return
+	case <-r.Context().Done(): // 客户端断开
+		return
+	}
+
+	result := controlTriggerResult{Name: name}
+	if err != nil {
+		switch err {
+		case TaskNotFound:
+			h.writeError(w, http.StatusNotFound, err)
+			return
+		case LockedByOtherInstance:
+			h.writeError(w, http.StatusConflict, err)
+			return
+		}
+		result.Err = err.Error()
+	}
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+func (h *controlHandler) taskInfo(task ITask) ControlTaskInfo {
+	trigger := task.GetTrigger()
+	info := ControlTaskInfo{
+		Name:        task.Name(),
+		Enable:      task.IsEnable(),
+		TriggerType: trigger.TriggerType().String(),
+		Expression:  trigger.Expression(),
+		Location:    zoneName(time.Now().In(trigger.Location())),
+		Running:     task.GetExecutor().IsRunning(),
+	}
+	if next, ok := h.cron.NextFireTime(task.Name()); ok {
+		info.NextFireTime = &next
+	}
+	if records := h.cron.History(task.Name(), 1); len(records) > 0 {
+		info.LastRun = &records[0]
+	}
+	return info
+}
+
+// 记录修改操作
+func (h *controlHandler) log(r *http.Request, action, taskName string) {
+	if h.conf.Logger == nil {
+		return
+	}
+	h.conf.Logger.Warn("cron控制面操作", zap.String("action", action), zap.String("task_name", taskName),
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+func (h *controlHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *controlHandler) writeError(w http.ResponseWriter, status int, err error) {
+	h.writeJSON(w, status, controlErrorResult{Err: err.Error()})
+}
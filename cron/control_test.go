@@ -0,0 +1,153 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControlHandler(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	payloads := make(chan []byte, 1)
+	c.AddTask(NewTask("a", "0 0 * * *", true, func(ctx IContext) error {
+		payloads <- ctx.Payload()
+		return nil
+	}))
+	c.AddTask(NewTask("b", "0 0 * * *", false, func(ctx IContext) error { return errors.New("failed") }))
+	release := make(chan struct{})
+	c.AddTask(NewTask("c", "0 0 * * *", false, func(ctx IContext) error {
+		<-release
+		return nil
+	}))
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	h := NewControlHandler(c, ControlConfig{
+		Prefix:             "/admin/cron/",
+		TriggerWaitTimeout: 100 * time.Millisecond,
+		Auth: func(r *http.Request) error {
+			if r.Header.Get("Token") != "secret" {
+				return errors.New("invalid token")
+			}
+			return nil
+		},
+	})
+	do := func(method, path, body string, out interface{}) int {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Token", "secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if out != nil {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("%s %s 解析结果失败: %s, %s", method, path, err, w.Body.String())
+			}
+		}
+		return w.Code
+	}
+
+	// 鉴权
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/cron/tasks", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("没有鉴权的请求应该返回403, 实际为%d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	NewControlHandler(c, ControlConfig{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("没有设置鉴权时应该拒绝所有请求, 实际为%d", w.Code)
+	}
+
+	// 任务列表
+	var tasks controlTasksResult
+	if code := do(http.MethodGet, "/admin/cron/tasks", "", &tasks); code != http.StatusOK {
+		t.Fatalf("获取任务列表失败: %d", code)
+	}
+	if tasks.State != "started" || len(tasks.Tasks) != 3 {
+		t.Fatalf("任务列表错误: %+v", tasks)
+	}
+	if tasks.Tasks[0].NextFireTime == nil || tasks.Tasks[1].NextFireTime != nil {
+		t.Fatalf("只有启用的任务有下次触发时间: %+v", tasks.Tasks)
+	}
+
+	// 启用和停用
+	var info ControlTaskInfo
+	if code := do(http.MethodPost, "/admin/cron/tasks/b/enable", "", &info); code != http.StatusOK || !info.Enable || !c.GetTask("b").IsEnable() {
+		t.Fatalf("启用任务失败: %d, %+v", code, info)
+	}
+	if code := do(http.MethodPost, "/admin/cron/tasks/a/disable", "", &info); code != http.StatusOK || info.Enable || c.GetTask("a").IsEnable() {
+		t.Fatalf("停用任务失败: %d, %+v", code, info)
+	}
+
+	// 立即执行并等待执行结束, 停用的任务也可以执行
+	var result controlTriggerResult
+	if code := do(http.MethodPost, "/admin/cron/tasks/a/trigger?wait=true", `{"id":1}`, &result); code != http.StatusOK || result.Async || result.Err != "" {
+		t.Fatalf("执行任务失败: %d, %+v", code, result)
+	}
+	if p := <-payloads; string(p) != `{"id":1}` {
+		t.Fatalf("传入的数据错误: %s", p)
+	}
+	if code := do(http.MethodPost, "/admin/cron/tasks/b/trigger?wait=true", "", &result); code != http.StatusOK || result.Err != "failed" {
+		t.Fatalf("执行失败的任务应该返回错误: %d, %+v", code, result)
+	}
+	if code := do(http.MethodGet, "/admin/cron/tasks/a", "", &info); code != http.StatusOK || len(info.History) != 1 || info.History[0].Source != RunByManual {
+		t.Fatalf("获取任务详情失败: %d, %+v", code, info)
+	}
+
+	// 默认不等待执行结束, 等待超时后也不再等待
+	result = controlTriggerResult{}
+	if code := do(http.MethodPost, "/admin/cron/tasks/a/trigger", `{"id":2}`, &result); code != http.StatusAccepted || !result.Async {
+		t.Fatalf("默认应该不等待执行结束: %d, %+v", code, result)
+	}
+	if p := <-payloads; string(p) != `{"id":2}` {
+		t.Fatalf("传入的数据错误: %s", p)
+	}
+	result = controlTriggerResult{}
+	if code := do(http.MethodPost, "/admin/cron/tasks/c/trigger?wait=true", "", &result); code != http.StatusAccepted || !result.Async {
+		t.Fatalf("等待超时后应该返回202: %d, %+v", code, result)
+	}
+	close(release)
+	for i := 0; len(c.History("c", 0)) != 1 || c.History("c", 1)[0].IsRunning(); i++ {
+		if i == 100 {
+			t.Fatalf("任务没有执行结束: %+v", c.History("c", 0))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 暂停和恢复, 暂停时没有下次触发时间
+	var state controlStateResult
+	if do(http.MethodGet, "/admin/cron/tasks/b", "", &info); info.NextFireTime == nil {
+		t.Fatalf("启用的任务应该有下次触发时间: %+v", info)
+	}
+	if do(http.MethodPost, "/admin/cron/pause", "", &state); state.State != "paused" {
+		t.Fatalf("暂停失败: %+v", state)
+	}
+	info = ControlTaskInfo{}
+	if do(http.MethodGet, "/admin/cron/tasks/b", "", &info); info.NextFireTime != nil {
+		t.Fatalf("暂停时不应该有下次触发时间: %+v", info)
+	}
+	if do(http.MethodPost, "/admin/cron/resume", "", &state); state.State != "started" {
+		t.Fatalf("恢复失败: %+v", state)
+	}
+
+	// 错误的路由
+	for _, test := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/admin/cron/tasks/not_found", http.StatusNotFound},
+		{http.MethodPost, "/admin/cron/tasks/not_found/trigger", http.StatusNotFound},
+		{http.MethodGet, "/admin/cron/pause", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/cron/tasks/a/unknown", http.StatusNotFound},
+		{http.MethodGet, "/other/tasks", http.StatusNotFound},
+	} {
+		if code := do(test.method, test.path, "", nil); code != test.code {
+			t.Fatalf("%s %s 应该返回%d, 实际为%d", test.method, test.path, test.code, code)
+		}
+	}
+}
@@ -9,8 +9,11 @@
 package cron
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -36,10 +39,12 @@ type ICron interface {
 	// 恢复所有任务
 	Resume()
 
-	// 添加任务, 如果任务名重复返回 false
+	// 添加任务, 如果任务名重复, 存在循环依赖或设置了分布式锁时添加依赖触发器的任务返回 false
 	AddTask(task ITask) bool
 	// 移除任务
 	RemoveTask(name string)
+	// 使用 task 的触发器, 执行器, handler 和启用状态替换同名任务的配置, 保留执行记录和补偿的起点, 任务不存在时返回 false
+	ReconfigureTask(task ITask) bool
 	// 启用任务
 	EnableTask(task ITask, enable bool)
 	// 获取任务名列表, 按名称排序
@@ -48,6 +53,14 @@ type ICron interface {
 	Tasks() []ITask
 	// 获取任务, 如果不存在返回nil
 	GetTask(name string) ITask
+	// 立即执行任务, 阻塞等待执行结束, 任务未启用时也会执行. 设置了分布式锁时会获取任务粒度的锁, 被其它实例锁定时返回 LockedByOtherInstance
+	TriggerTask(name string) error
+	// 立即执行任务并传入数据, 可以通过 IContext.Payload 获取, 阻塞等待执行结束, 任务未启用时也会执行
+	TriggerTaskWithPayload(name string, payload []byte) error
+	// 获取任务最近的执行记录, 按开始时间从新到旧排序, limit <= 0 表示获取所有记录
+	History(name string, limit int) []RunRecord
+	// 获取任务的下次触发时间, 定时器未运行, 任务不存在, 未启用或没有下次触发时返回 false
+	NextFireTime(name string) (time.Time, bool)
 }
 
 // 运行状态
@@ -91,7 +104,9 @@ func (r RunState) String() string {
 const heapsCount = 64 // 任务堆数量
 
 type CronService struct {
-	app core.IApp
+	app  core.IApp
+	conf *Config
+	opts *options
 
 	tasks map[string]ITask // 任务
 	heaps []ITaskHeap      // 任务堆列表, 根据触发时间取模将任务分配到不同的任务堆
@@ -99,27 +114,64 @@ type CronService struct {
 	runState  RunState
 	closeChan chan struct{}
 
+	ctx    context.Context // 服务运行期间的 context, 关闭时取消
+	cancel context.CancelFunc
+	ctxMx  sync.Mutex // 锁 ctx, cancel
+
 	gpool core.IGPool // 协程池
 
+	lockMode   LockMode      // 默认加锁模式
+	lockTTL    time.Duration // 锁租约时间
+	lockPrefix string        // 锁key前缀
+	lockOwner  string        // 当前实例的锁持有者名
+
+	histories map[string]*taskHistory // 执行记录
+	historyMx sync.Mutex              // 锁 histories
+
+	fireTimes        map[string]time.Time // 任务最后一次执行过的定时触发时间, 用于查找错过的触发, 使用 mx 锁
+	pendingFireTimes map[string]time.Time // 任务已经分发但还没有执行结束的最后一次定时触发时间, 使用 mx 锁
+
+	dependStates map[string]*dependState // 下游任务的汇合状态
+	dependMx     sync.Mutex              // 锁 dependStates
+
+	configTasks    map[string]ConfigTask // 上次应用的配置声明的任务
+	configMx       sync.Mutex            // 锁 configTasks, 同时只能应用一次配置
+	configTaskOnce sync.Once             // 只在第一次启动时加载配置声明的任务
+
 	mx sync.Mutex // 锁 tasks, heaps
 }
 
-func NewCronService(app core.IApp) core.IService {
+func NewCronService(app core.IApp, opts ...Option) core.IService {
 	conf := newConfig()
-	vi := app.GetConfig().GetViper()
-	confKey := "servicec." + string(nowServiceType)
-	if vi.IsSet(confKey) {
-		if err := vi.UnmarshalKey(confKey, conf); err != nil {
-			app.Fatal(fmt.Errorf("无法解析<%s>服务配置: %s", nowServiceType, err))
-		}
+	if err := app.GetConfig().ParseServiceConfig(nowServiceType, conf, true); err != nil {
+		app.Fatal("获取cron服务配置失败", zap.Error(err))
+	}
+	if err := conf.check(); err != nil {
+		app.Fatal(fmt.Errorf("<%s>服务配置错误: %s", nowServiceType, err))
 	}
-	conf.check()
 
 	c := &CronService{
 		app:       app,
+		conf:      conf,
+		opts:      newOptions(opts...),
 		tasks:     make(map[string]ITask),
 		runState:  StoppedState,
 		closeChan: make(chan struct{}),
+
+		lockTTL:    time.Duration(conf.LockTTL) * time.Millisecond,
+		lockPrefix: conf.LockKeyPrefix,
+		lockOwner:  makeLockOwner(app.Name()),
+
+		histories:        make(map[string]*taskHistory),
+		fireTimes:        make(map[string]time.Time),
+		pendingFireTimes: make(map[string]time.Time),
+
+		dependStates: make(map[string]*dependState),
+		configTasks:  make(map[string]ConfigTask),
+	}
+	c.lockMode, _ = ParseLockMode(conf.LockMode)
+	if c.lockPrefix == "" {
+		c.lockPrefix = app.Name() + ":cron:"
 	}
 	c.remakeHeaps()
 	if conf.ThreadCount > 0 {
@@ -139,7 +191,7 @@ func (c *CronService) Inject(a ...interface{}) {
 		}
 
 		if ok := c.AddTask(task); !ok {
-			c.app.Fatal("添加Cron任务失败, 可能是名称重复", zap.String("name", task.Name()))
+			c.app.Fatal("添加Cron任务失败, 可能是名称重复或存在循环依赖", zap.String("name", task.Name()))
 		}
 	}
 }
@@ -149,6 +201,17 @@ func (c *CronService) Start() error {
 		return nil
 	}
 	c.app.Debug("cron服务正在启动")
+	c.configTaskOnce.Do(c.loadConfigTasks)
+
+	// 任务可能在设置分布式锁之前添加
+	if err := c.checkDependLocker(); err != nil {
+		atomic.StoreInt32((*int32)(&c.runState), int32(StoppedState))
+		return err
+	}
+
+	c.ctxMx.Lock()
+	c.ctx, c.cancel = context.WithCancel(c.app.BaseContext())
+	c.ctxMx.Unlock()
 
 	c.resetClock()
 	go c.start()
@@ -172,6 +235,12 @@ func (c *CronService) Close() error {
 	c.closeChan <- struct{}{}
 	<-c.closeChan
 
+	// 取消正在执行的任务并等待结束
+	c.ctxMx.Lock()
+	c.cancel()
+	c.ctxMx.Unlock()
+	c.waitTasks(time.Duration(c.conf.CloseGracePeriod) * time.Millisecond)
+
 	atomic.StoreInt32((*int32)(&c.runState), int32(StoppedState))
 	c.app.Warn("cron服务已关闭")
 	return nil
@@ -192,7 +261,7 @@ func (c *CronService) Pause() {
 }
 
 func (c *CronService) Resume() {
-	if c.RunState() != StartedState {
+	if c.RunState() != PausedState {
 		return
 	}
 
@@ -213,10 +282,21 @@ func (c *CronService) AddTask(task ITask) bool {
 		c.mx.Unlock()
 		return false
 	}
+	if locker != nil && taskUpstreams(task) != nil {
+		c.mx.Unlock()
+		c.app.Error("添加cron任务失败", zap.String("task_name", task.Name()), zap.Error(DependTriggerWithLocker))
+		return false
+	}
+	if cycle := c.findDependCycle(task); cycle != nil {
+		c.mx.Unlock()
+		c.app.Error("cron任务存在循环依赖", zap.String("task_name", task.Name()), zap.Strings("cycle", cycle))
+		return false
+	}
 
 	c.tasks[task.Name()] = task
 
 	if task.IsEnable() && c.RunState() == StartedState {
+		c.fireTimes[task.Name()] = time.Now() // 运行时添加的任务不需要补偿之前错过的触发
 		task.resetClock()
 		_, ok := task.MakeNextTriggerTime(time.Now())
 		if ok {
@@ -237,11 +317,21 @@ func (c *CronService) RemoveTask(name string) {
 	}
 
 	delete(c.tasks, name)
+	delete(c.fireTimes, name)
+	delete(c.pendingFireTimes, name)
 
 	heap := c.getHeapOfTime(task.TriggerTime().Unix())
 	heap.Remove(task)
 
 	c.mx.Unlock()
+
+	c.historyMx.Lock()
+	delete(c.histories, name)
+	c.historyMx.Unlock()
+
+	c.dependMx.Lock()
+	delete(c.dependStates, name)
+	c.dependMx.Unlock()
 }
 
 func (c *CronService) EnableTask(task ITask, enable bool) {
@@ -257,6 +347,7 @@ func (c *CronService) EnableTask(task ITask, enable bool) {
 
 	task.setEnable(enable)
 	if enable && c.RunState() == StartedState {
+		c.fireTimes[task.Name()] = time.Now() // 不需要补偿未启用期间错过的触发
 		task.resetClock()
 		_, ok := task.MakeNextTriggerTime(time.Now())
 		if ok {
@@ -266,6 +357,37 @@ func (c *CronService) EnableTask(task ITask, enable bool) {
 	c.mx.Unlock()
 }
 
+// 使用 task 的触发器, 执行器, handler 和启用状态替换同名任务的配置, 任务不存在时返回 false
+//
+// 会保留执行记录和补偿的起点, 正在执行的触发会在原来的执行器中执行完毕
+func (c *CronService) ReconfigureTask(task ITask) bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	rawTask, ok := c.tasks[task.Name()]
+	if !ok {
+		return false
+	}
+
+	heap := c.getHeapOfTime(rawTask.TriggerTime().Unix())
+	heap.Remove(rawTask)
+
+	wasEnable := rawTask.IsEnable()
+	rawTask.reconfigure(task.GetTrigger(), task.GetExecutor(), task.Handler())
+	rawTask.setEnable(task.IsEnable())
+	if rawTask.IsEnable() && c.RunState() == StartedState {
+		if !wasEnable {
+			c.fireTimes[rawTask.Name()] = time.Now() // 不需要补偿未启用期间错过的触发
+		}
+		rawTask.resetClock()
+		_, ok := rawTask.MakeNextTriggerTime(time.Now())
+		if ok {
+			c.pushTaskToHeap(rawTask)
+		}
+	}
+	return true
+}
+
 func (c *CronService) TaskNames() []string {
 	c.mx.Lock()
 	names := make([]string, len(c.tasks))
@@ -303,6 +425,49 @@ func (c *CronService) GetTask(name string) ITask {
 	return task
 }
 
+func (c *CronService) TriggerTask(name string) error {
+	task := c.GetTask(name)
+	if task == nil {
+		return TaskNotFound
+	}
+	return c.execute(task, taskFire{source: RunByManual})
+}
+
+func (c *CronService) TriggerTaskWithPayload(name string, payload []byte) error {
+	task := c.GetTask(name)
+	if task == nil {
+		return TaskNotFound
+	}
+	return c.execute(task, taskFire{source: RunByManual, payload: payload})
+}
+
+func (c *CronService) History(name string, limit int) []RunRecord {
+	if c.GetTask(name) == nil {
+		return nil
+	}
+	return c.taskHistory(name).list(limit)
+}
+
+func (c *CronService) NextFireTime(name string) (time.Time, bool) {
+	if !c.isStarted() {
+		return time.Time{}, false
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	task, ok := c.tasks[name]
+	if !ok || !task.IsEnable() {
+		return time.Time{}, false
+	}
+	tt := task.TriggerTime()
+	tasks := c.getHeapOfTime(tt.Unix()).Tasks()
+	if index := task.getHeapIndex(); index >= len(tasks) || tasks[index] != task { // 不在任务堆中, 没有下次触发
+		return time.Time{}, false
+	}
+	return tt, true
+}
+
 // 开始
 func (c *CronService) start() {
 	timer := time.NewTicker(time.Second)
@@ -364,7 +529,7 @@ func (c *CronService) heartBeat(t time.Time) {
 		}
 
 		task = heap.Pop()
-		c.triggerTask(task) // 触发
+		c.fire(task, task.TriggerTime()) // 触发
 
 		// 获取下一次触发时间
 		_, ok := task.MakeNextTriggerTime(t)
@@ -374,46 +539,125 @@ func (c *CronService) heartBeat(t time.Time) {
 	}
 }
 
-// 触发一个任务
-func (c *CronService) triggerTask(t ITask) {
+// 一次触发
+type taskFire struct {
+	fireTime time.Time
+	source   RunSource
+	runID    string // 上游传递的执行id, 为空时生成新的
+	payload  []byte // 手动触发时传入的数据
+}
+
+// 定时触发一个任务, 会按错过触发的处理策略先补偿上次触发之后错过的触发
+func (c *CronService) fire(task ITask, fireTime time.Time) {
+	missed := c.missedFireTimes(task, fireTime, false)
+	if len(missed) > 0 {
+		c.app.Info("cron.misfire", zap.String("task_name", task.Name()), zap.Int("count", len(missed)))
+	}
+	fires := append(makeMisfires(missed), taskFire{fireTime: fireTime, source: RunBySchedule})
+	if c.triggerTask(task, fires) {
+		c.pendingFireTimes[task.Name()] = fireTime
+	}
+}
+
+// 补偿错过的触发
+func makeMisfires(fireTimes []time.Time) []taskFire {
+	fires := make([]taskFire, 0, len(fireTimes)+1)
+	for _, t := range fireTimes {
+		fires = append(fires, taskFire{fireTime: t, source: RunByMisfire})
+	}
+	return fires
+}
+
+// 触发一个任务, 多次触发会按顺序执行, 任务队列已满时返回 false
+func (c *CronService) triggerTask(t ITask, fires []taskFire) bool {
+	fn := func() {
+		for _, f := range fires {
+			_ = c.execute(t, f)
+		}
+	}
 	if c.gpool == nil {
-		go c.execute(t)
-		return
+		go fn()
+		return true
 	}
 
 	ok := c.gpool.TryGo(func() error {
-		c.execute(t)
+		fn()
 		return nil
 	}, nil)
 	if !ok {
 		c.app.Warn("cron.error", zap.String("task_name", t.Name()), zap.String("err", "tasks queue is full"))
 	}
+	return ok
 }
 
 // 执行一个任务
-func (c *CronService) execute(task ITask) {
-	if !task.IsEnable() {
-		return
+//
+// 手动触发的任务不检查启用状态, 执行结束后会触发下游任务
+func (c *CronService) execute(task ITask, f taskFire) error {
+	source, fireTime, runID := f.source, f.fireTime, f.runID
+	fired := false // 定时触发是否已经执行过, 没有执行的触发会按错过触发的处理策略补偿
+	if source == RunBySchedule || source == RunByMisfire {
+		defer func() { c.finishFire(task, fireTime, fired) }()
+	}
+	if source != RunByManual && !task.IsEnable() {
+		fired = true
+		return nil
+	}
+
+	baseCtx, cancel := c.taskContext(task)
+	defer cancel()
+	if source != RunByManual && baseCtx.Err() != nil { // 服务已关闭
+		return nil
+	}
+	if runID == "" {
+		runID = newRunID()
+	}
+	ctx := newContext(baseCtx, c.app, task, runID, f.payload)
+	unlock, err := c.lock(ctx, task, f, cancel)
+	if err != nil {
+		if source == RunByManual {
+			return err
+		}
+		fired = err == LockedByOtherInstance // 其它实例执行了这次触发
+		return nil
 	}
 
-	ctx := newContext(c.app, task)
-	ctx.Debug("cron.start")
+	ctx.Debug("cron.start", zap.String("source", string(source)))
+	record := c.beginRecord(task, source, fireTime, runID)
 
-	err := task.Trigger(ctx, func(ctx IContext, err error) {
+	attempts := 1
+	err = task.Trigger(ctx, func(ctx IContext, err error) {
+		attempts++
 		ctx.Warn("cron.error! try retry", zap.String("err", utils.Recover.GetRecoverErrorDetail(err)))
 	})
+	if unlock() { // 执行期间失去了锁
+		err = makeLockLostError(err)
+	}
+	if err != nil && c.isClosing() { // 执行期间服务关闭
+		err = makeCanceledByShutdownError(err)
+	}
+	// 执行过的触发无论结果如何都已经完成, 只有服务关闭时被取消的需要补偿
+	fired = !errors.Is(err, CanceledByShutdown)
+	c.endRecord(ctx, record, attempts, err)
+	if baseCtx.Err() == context.DeadlineExceeded {
+		ctx.Warn("cron.timeout", zap.Duration("timeout", task.Timeout()))
+	}
 	if err != nil {
 		ctx.Error("cron.error!\n" + utils.Recover.GetRecoverErrorDetail(err))
+		reportError(ctx.Ctx(), c.opts.Reporter, err, map[string]string{"task_name": task.Name()})
 	} else {
 		ctx.Debug("cron.success")
 	}
+	c.triggerDownstream(task, runID, err)
+	return err
 }
 
 // 重置定时器
 //
-// 会重新创建任务堆列表并重新将所有任务加入堆中.
+// 会重新创建任务堆列表并重新将所有任务加入堆中, 有错过的触发时按任务的处理策略补偿.
 // 这里不要做任何耗时操作, 否则可能会错过下一秒的时间导致任务会延迟64秒后执行
 func (c *CronService) resetClock() {
+	c.loadHistories() // 查找错过的触发需要执行记录, 在加锁之前从存储中加载
 	c.mx.Lock()
 	c.remakeHeaps()
 
@@ -423,6 +667,14 @@ func (c *CronService) resetClock() {
 			continue
 		}
 
+		// 补偿暂停期间或服务停止期间错过的触发
+		if missed := c.missedFireTimes(task, now, true); len(missed) > 0 {
+			c.app.Info("cron.misfire", zap.String("task_name", task.Name()), zap.Int("count", len(missed)))
+			if c.triggerTask(task, makeMisfires(missed)) {
+				c.pendingFireTimes[task.Name()] = missed[len(missed)-1]
+			}
+		}
+
 		task.resetClock()
 		_, ok := task.MakeNextTriggerTime(now)
 		if ok {
@@ -431,3 +683,130 @@ func (c *CronService) resetClock() {
 	}
 	c.mx.Unlock()
 }
+
+// 获取执行任务的 context, 服务关闭时取消, 任务设置了超时时间时超时后取消
+func (c *CronService) taskContext(task ITask) (context.Context, context.CancelFunc) {
+	c.ctxMx.Lock()
+	ctx := c.ctx
+	c.ctxMx.Unlock()
+	if ctx == nil { // 服务未启动时手动触发
+		ctx = c.app.BaseContext()
+	}
+
+	if timeout := task.Timeout(); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// 服务是否正在关闭
+func (c *CronService) isClosing() bool {
+	c.ctxMx.Lock()
+	defer c.ctxMx.Unlock()
+	return c.ctx != nil && c.ctx.Err() != nil
+}
+
+// 等待所有任务执行结束, 超过 timeout 后不再等待, 返回是否所有任务都已结束
+func (c *CronService) waitTasks(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		for _, task := range c.Tasks() {
+			task.GetExecutor().Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		var running []string
+		for _, task := range c.Tasks() {
+			if task.GetExecutor().IsRunning() {
+				running = append(running, task.Name())
+			}
+		}
+		c.app.Warn("等待cron任务结束超时", zap.Strings("running", running))
+		return false
+	}
+}
+
+// 获取任务的分布式锁, 返回错误表示这次触发不执行, 被其它实例锁定时返回 LockedByOtherInstance
+//
+// 手动触发时不管加锁模式都获取任务粒度的锁, 这样不会和以任务加锁的定时触发同时执行.
+// 持有锁期间会定时续约, 续约失败说明锁已经被其它实例获取, 这时会调用 cancel 取消执行.
+// 调用 unlock 后停止续约, 以任务为粒度的锁会被释放, unlock 返回执行期间是否失去了锁
+func (c *CronService) lock(ctx IContext, task ITask, f taskFire, cancel context.CancelFunc) (unlock func() (lost bool), err error) {
+	l := locker
+	mode := task.LockMode()
+	if mode == LockDefault {
+		mode = c.lockMode
+	}
+	if l == nil || mode == LockNone {
+		return func() bool { return false }, nil
+	}
+	if f.source == RunByManual {
+		mode = LockPerTask
+	}
+
+	key := c.lockPrefix + task.Name()
+	if mode == LockPerFireTime {
+		key += ":" + strconv.FormatInt(f.fireTime.Unix(), 10)
+	}
+
+	ok, err := l.TryLock(c.app.BaseContext(), key, c.lockOwner, c.lockTTL)
+	if err != nil {
+		ctx.Error("cron.skip! lock error", zap.String("lock_key", key), zap.Error(err))
+		return nil, err
+	}
+	if !ok {
+		ctx.Info("cron.skip", zap.String("reason", "locked by other instance"), zap.String("lock_key", key))
+		return nil, LockedByOtherInstance
+	}
+
+	done := make(chan struct{})
+	var lost int32
+	go c.renewLock(ctx, l, key, done, func() {
+		atomic.StoreInt32(&lost, 1)
+		cancel()
+	})
+	return func() bool {
+		close(done)
+		lost := atomic.LoadInt32(&lost) == 1
+		if mode != LockPerTask || lost {
+			return lost
+		}
+		if err := l.Unlock(c.app.BaseContext(), key, c.lockOwner); err != nil {
+			ctx.Warn("cron.unlock error", zap.String("lock_key", key), zap.Error(err))
+		}
+		return false
+	}, nil
+}
+
+// 定时续约直到 done 被关闭, 失去锁时调用 onLost
+func (c *CronService) renewLock(ctx IContext, l ILocker, key string, done chan struct{}, onLost func()) {
+	interval := c.lockTTL / 3
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ok, err := l.Renew(c.app.BaseContext(), key, c.lockOwner, c.lockTTL)
+			if err != nil {
+				ctx.Warn("cron.renew lock error", zap.String("lock_key", key), zap.Error(err))
+				continue
+			}
+			if !ok {
+				ctx.Error("cron.lock lost! cancel execution", zap.String("lock_key", key))
+				onLost()
+				return
+			}
+		}
+	}
+}
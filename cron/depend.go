@@ -0,0 +1,288 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 设置了分布式锁时添加依赖触发器的任务会失败, 上游可能在不同的实例上执行, 各实例分别汇合会导致下游不执行或重复执行
+var DependTriggerWithLocker = errors.New("设置了分布式锁时不支持依赖触发器")
+
+// 依赖的汇合模式
+type DependMode int
+
+const (
+	// 所有上游都执行结束后触发
+	DependAll DependMode = iota
+	// 任意一个上游执行成功后触发
+	DependAny
+)
+
+func (m DependMode) String() string {
+	switch m {
+	case DependAll:
+		return "all"
+	case DependAny:
+		return "any"
+	}
+	return fmt.Sprintf("undefined depend mode: %d", m)
+}
+
+// 上游执行失败时的处理方式
+type UpstreamFailurePolicy int
+
+const (
+	// 跳过下游任务, 下游任务的下游也不会执行
+	UpstreamFailureSkip UpstreamFailurePolicy = iota
+	// 仍然执行下游任务
+	UpstreamFailureRun
+)
+
+func (p UpstreamFailurePolicy) String() string {
+	switch p {
+	case UpstreamFailureSkip:
+		return "skip"
+	case UpstreamFailureRun:
+		return "run"
+	}
+	return fmt.Sprintf("undefined upstream failure policy: %d", p)
+}
+
+// 依赖触发器配置
+type DependConfig struct {
+	// 上游任务名
+	Upstreams []string
+	// 汇合模式, 默认所有上游都执行结束后触发
+	Mode DependMode
+	// 上游执行失败时的处理方式, 默认跳过
+	OnFailure UpstreamFailurePolicy
+}
+
+// 依赖触发器, 没有自己的定时, 在上游任务执行结束后触发
+//
+// 所有上游模式: 一轮中每个上游都执行结束时触发, 如果有上游失败按 OnFailure 处理
+//
+// 任意上游模式: 一轮中第一个执行成功的上游结束时触发, 如果一轮中所有上游都失败了按 OnFailure 处理
+//
+// 一轮从任意上游执行结束开始, 到所有上游都执行结束为止, 下游使用触发它的上游的执行id.
+// 汇合状态只保存在当前实例中, 所以设置了分布式锁时不能使用
+type DependTrigger struct {
+	conf DependConfig
+}
+
+// 创建一个依赖触发器
+func NewDependTrigger(conf DependConfig) (ITrigger, error) {
+	if len(conf.Upstreams) == 0 {
+		return nil, errors.New("依赖触发器没有上游任务")
+	}
+	seen := make(map[string]struct{}, len(conf.Upstreams))
+	for _, name := range conf.Upstreams {
+		if name == "" {
+			return nil, errors.New("上游任务名为空")
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("上游任务 %s 重复", name)
+		}
+		seen[name] = struct{}{}
+	}
+	conf.Upstreams = append([]string(nil), conf.Upstreams...)
+	return &DependTrigger{conf: conf}, nil
+}
+
+func (d *DependTrigger) TriggerType() TriggerType {
+	return DependTriggerType
+}
+
+// 返回依赖表达式, 如 all(export, compress)
+func (d *DependTrigger) Expression() string {
+	return d.conf.Mode.String() + "(" + strings.Join(d.conf.Upstreams, ", ") + ")"
+}
+func (d *DependTrigger) Location() *time.Location {
+	return time.Local
+}
+
+func (d *DependTrigger) ResetClock() {
+}
+func (d *DependTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
+	return t, false
+}
+func (d *DependTrigger) NextTriggerTime(t time.Time) (time.Time, bool) {
+	return t, false
+}
+
+// 返回上游任务名
+func (d *DependTrigger) Upstreams() []string {
+	return append([]string(nil), d.conf.Upstreams...)
+}
+
+// 返回汇合模式
+func (d *DependTrigger) Mode() DependMode {
+	return d.conf.Mode
+}
+
+// 返回上游执行失败时的处理方式
+func (d *DependTrigger) OnFailure() UpstreamFailurePolicy {
+	return d.conf.OnFailure
+}
+
+func (d *DependTrigger) hasUpstream(name string) bool {
+	for _, up := range d.conf.Upstreams {
+		if up == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取任务的上游任务名, 不是依赖触发器时返回nil
+func taskUpstreams(task ITask) []string {
+	if d, ok := task.GetTrigger().(*DependTrigger); ok {
+		return d.conf.Upstreams
+	}
+	return nil
+}
+
+// 检查设置了分布式锁时是否有使用依赖触发器的任务
+func (c *CronService) checkDependLocker() error {
+	if locker == nil {
+		return nil
+	}
+	for _, task := range c.Tasks() {
+		if taskUpstreams(task) != nil {
+			return fmt.Errorf("%w: %s", DependTriggerWithLocker, task.Name())
+		}
+	}
+	return nil
+}
+
+// 检查添加任务后是否存在循环依赖, 存在时返回循环的路径, 需要在 mx 中调用
+//
+// 添加之前的依赖图是无环的, 所以如果存在循环一定经过新添加的任务
+func (c *CronService) findDependCycle(task ITask) []string {
+	visited := make(map[string]bool)
+	var path []string
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		path = append(path, name)
+		var upstreams []string
+		if name == task.Name() {
+			upstreams = taskUpstreams(task)
+		} else if t, ok := c.tasks[name]; ok {
+			upstreams = taskUpstreams(t)
+		}
+		for _, up := range upstreams {
+			if up == task.Name() {
+				path = append(path, up)
+				return true
+			}
+			if visited[up] {
+				continue
+			}
+			visited[up] = true
+			if visit(up) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(task.Name()) {
+		// path 是从下游到上游的顺序, 反转为执行顺序
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+		return path
+	}
+	return nil
+}
+
+// 下游任务的汇合状态, 记录一轮中各上游的执行结果, 所有上游都执行结束后开始新的一轮
+type dependState struct {
+	results map[string]bool // 上游任务名 -> 是否成功
+	fired   bool            // 这一轮是否已经触发
+}
+
+// 任务执行结束后触发下游任务
+func (c *CronService) triggerDownstream(task ITask, runID string, err error) {
+	for _, down := range c.Tasks() {
+		d, ok := down.GetTrigger().(*DependTrigger)
+		if !ok || !d.hasUpstream(task.Name()) {
+			continue
+		}
+
+		run, skip := c.updateDependState(down.Name(), d, task.Name(), err == nil)
+		switch {
+		case run:
+			c.triggerTask(down, []taskFire{{fireTime: time.Now(), source: RunByUpstream, runID: runID}})
+		case skip:
+			c.app.Warn("cron.skip", zap.String("task_name", down.Name()), zap.String("reason", "upstream failed"),
+				zap.String("upstream", task.Name()), zap.String("run_id", runID))
+		}
+	}
+}
+
+// 更新下游任务的汇合状态, 返回是否需要触发或跳过, 都为 false 表示继续等待其它上游
+func (c *CronService) updateDependState(name string, d *DependTrigger, upstream string, success bool) (run, skip bool) {
+	c.dependMx.Lock()
+	defer c.dependMx.Unlock()
+
+	state, ok := c.dependStates[name]
+	if !ok {
+		state = &dependState{results: make(map[string]bool)}
+		c.dependStates[name] = state
+	}
+
+	if d.conf.Mode == DependAny {
+		if _, ok := state.results[upstream]; ok && state.fired { // 已触发的一轮中同一个上游再次执行, 开始新的一轮
+			state = &dependState{results: make(map[string]bool)}
+			c.dependStates[name] = state
+		}
+		state.results[upstream] = success
+		if success && !state.fired {
+			state.fired = true
+			run = true
+		}
+		if len(state.results) < len(d.conf.Upstreams) {
+			return run, false
+		}
+		delete(c.dependStates, name)
+		if state.fired {
+			return run, false
+		}
+		return c.upstreamFailed(d)
+	}
+
+	// 一轮中同一个上游多次执行时以最后一次的结果为准
+	state.results[upstream] = success
+	if len(state.results) < len(d.conf.Upstreams) {
+		return false, false
+	}
+	delete(c.dependStates, name)
+	for _, ok := range state.results {
+		if !ok {
+			return c.upstreamFailed(d)
+		}
+	}
+	return true, false
+}
+
+// 上游失败时按策略返回是否触发或跳过
+func (c *CronService) upstreamFailed(d *DependTrigger) (run, skip bool) {
+	if d.conf.OnFailure == UpstreamFailureRun {
+		return true, false
+	}
+	return false, true
+}
+
+var runIDSeq uint64
+
+// 生成执行id
+func newRunID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatUint(atomic.AddUint64(&runIDSeq, 1)%1296, 36)
+}
@@ -0,0 +1,202 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newDependTask(t *testing.T, name string, conf DependConfig, handler Handler) ITask {
+	trigger, err := NewDependTrigger(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTaskOfConfig(name, TaskConfig{
+		Trigger:  trigger,
+		Executor: NewExecutor(0, 0, 1),
+		Handler:  handler,
+		Enable:   true,
+	})
+}
+
+func newRootTask(name string, handler Handler) ITask {
+	return NewTask(name, "0 0 0 1 1 *", true, handler)
+}
+
+// 记录执行id的handler
+func runIDHandler(ch chan string, err error) Handler {
+	return func(ctx IContext) error {
+		ch <- ctx.RunID()
+		return err
+	}
+}
+
+func waitRunID(t *testing.T, ch chan string, name string) string {
+	select {
+	case id := <-ch:
+		return id
+	case <-time.After(time.Second):
+		t.Fatalf("%s 没有执行", name)
+	}
+	return ""
+}
+
+func expectNotRun(t *testing.T, ch chan string, name string) {
+	select {
+	case <-ch:
+		t.Fatalf("%s 不应该执行", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewDependTrigger(t *testing.T) {
+	for _, conf := range []DependConfig{
+		{},
+		{Upstreams: []string{""}},
+		{Upstreams: []string{"a", "a"}},
+	} {
+		if _, err := NewDependTrigger(conf); err == nil {
+			t.Fatalf("配置 %v 应该返回错误", conf)
+		}
+	}
+
+	trigger, err := NewDependTrigger(DependConfig{Upstreams: []string{"a", "b"}, Mode: DependAny})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trigger.Expression() != "any(a, b)" {
+		t.Fatalf("表达式错误: %s", trigger.Expression())
+	}
+	if _, ok := trigger.MakeNextTriggerTime(time.Now()); ok {
+		t.Fatal("依赖触发器不应该有定时")
+	}
+}
+
+func TestDependCycle(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	handler := func(ctx IContext) error { return nil }
+
+	if c.AddTask(newDependTask(t, "self", DependConfig{Upstreams: []string{"self"}}, handler)) {
+		t.Fatal("依赖自己的任务应该添加失败")
+	}
+
+	// 上游可以在下游之后添加
+	if !c.AddTask(newDependTask(t, "b", DependConfig{Upstreams: []string{"a"}}, handler)) {
+		t.Fatal("添加任务b失败")
+	}
+	if !c.AddTask(newDependTask(t, "c", DependConfig{Upstreams: []string{"b"}}, handler)) {
+		t.Fatal("添加任务c失败")
+	}
+	a := newDependTask(t, "a", DependConfig{Upstreams: []string{"c"}}, handler)
+	cycle := c.findDependCycle(a)
+	if len(cycle) != 4 || cycle[0] != "a" || cycle[3] != "a" {
+		t.Fatalf("循环路径错误: %v", cycle)
+	}
+	if c.AddTask(a) {
+		t.Fatal("存在循环依赖的任务应该添加失败")
+	}
+	if !c.AddTask(newRootTask("a", handler)) {
+		t.Fatal("添加任务a失败")
+	}
+}
+
+func TestDependWithLocker(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	handler := func(ctx IContext) error { return nil }
+	if !c.AddTask(newDependTask(t, "b", DependConfig{Upstreams: []string{"a"}}, handler)) {
+		t.Fatal("添加任务b失败")
+	}
+
+	SetLocker(NewMemoryLocker())
+	defer SetLocker(nil)
+
+	if c.AddTask(newDependTask(t, "c", DependConfig{Upstreams: []string{"a"}}, handler)) {
+		t.Fatal("设置了分布式锁时添加依赖触发器的任务应该失败")
+	}
+	if !c.AddTask(newRootTask("a", handler)) {
+		t.Fatal("添加任务a失败")
+	}
+
+	// 设置分布式锁之前添加的依赖任务会导致启动失败
+	if err := c.Start(); !errors.Is(err, DependTriggerWithLocker) {
+		t.Fatalf("启动应该返回 DependTriggerWithLocker, 实际为 %v", err)
+	}
+	if c.RunState() != StoppedState {
+		t.Fatalf("启动失败后应该是停止状态, 实际为 %s", c.RunState())
+	}
+}
+
+func TestDependChain(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	aCh, bCh, cCh := make(chan string, 1), make(chan string, 1), make(chan string, 1)
+	c.AddTask(newRootTask("a", runIDHandler(aCh, nil)))
+	c.AddTask(newDependTask(t, "b", DependConfig{Upstreams: []string{"a"}}, runIDHandler(bCh, nil)))
+	c.AddTask(newDependTask(t, "c", DependConfig{Upstreams: []string{"b"}}, runIDHandler(cCh, nil)))
+
+	if err := c.TriggerTask("a"); err != nil {
+		t.Fatal(err)
+	}
+	runID := waitRunID(t, aCh, "a")
+	if runID == "" {
+		t.Fatal("执行id为空")
+	}
+	if id := waitRunID(t, bCh, "b"); id != runID {
+		t.Fatalf("b 的执行id应该为 %s, 实际为 %s", runID, id)
+	}
+	if id := waitRunID(t, cCh, "c"); id != runID {
+		t.Fatalf("c 的执行id应该为 %s, 实际为 %s", runID, id)
+	}
+
+	time.Sleep(50 * time.Millisecond) // 等待执行记录结束
+	records := c.History("c", 1)
+	if len(records) != 1 || records[0].Source != RunByUpstream || records[0].RunID != runID {
+		t.Fatalf("执行记录错误: %+v", records)
+	}
+}
+
+func TestDependFanIn(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name      string
+		mode      DependMode
+		onFailure UpstreamFailurePolicy
+		aErr      error
+		bErr      error
+		afterA    bool // a 执行后下游是否执行
+		afterB    bool // b 执行后下游是否执行
+	}{
+		{"all", DependAll, UpstreamFailureSkip, nil, nil, false, true},
+		{"all_fail_skip", DependAll, UpstreamFailureSkip, failed, nil, false, false},
+		{"all_fail_run", DependAll, UpstreamFailureRun, failed, nil, false, true},
+		{"any", DependAny, UpstreamFailureSkip, nil, nil, true, false},
+		{"any_fail", DependAny, UpstreamFailureSkip, failed, nil, false, true},
+		{"any_all_fail_skip", DependAny, UpstreamFailureSkip, failed, failed, false, false},
+		{"any_all_fail_run", DependAny, UpstreamFailureRun, failed, failed, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCronService(getTestApp()).(*CronService)
+			ch := make(chan string, 2)
+			c.AddTask(newRootTask("a", func(ctx IContext) error { return test.aErr }))
+			c.AddTask(newRootTask("b", func(ctx IContext) error { return test.bErr }))
+			c.AddTask(newDependTask(t, "c", DependConfig{
+				Upstreams: []string{"a", "b"},
+				Mode:      test.mode,
+				OnFailure: test.onFailure,
+			}, runIDHandler(ch, nil)))
+
+			_ = c.TriggerTask("a")
+			if test.afterA {
+				waitRunID(t, ch, "c")
+			} else {
+				expectNotRun(t, ch, "c")
+			}
+			_ = c.TriggerTask("b")
+			if test.afterB {
+				waitRunID(t, ch, "c")
+			} else {
+				expectNotRun(t, ch, "c")
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package cron
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 夏令时策略
+//
+// 只影响小时字段为固定值的表达式, 如 30 2 * * *, @daily. 小时字段为 * 的表达式按墙上时间执行,
+// 夏令时开始时跳过的时间不会执行, 夏令时结束时重复的时间会再执行一次.
+//
+// 夏令时结束时重复的时间里的触发对于所有策略都只执行第一次
+type DSTPolicy int
+
+const (
+	// 跳过, 落在夏令时开始时跳过的时间里的触发不会执行
+	DSTSkip DSTPolicy = iota
+	// 执行一次, 落在夏令时开始时跳过的时间里的触发会在夏令时开始时执行一次
+	DSTRunOnce
+)
+
+func (p DSTPolicy) String() string {
+	switch p {
+	case DSTSkip:
+		return "skip"
+	case DSTRunOnce:
+		return "run_once"
+	}
+	return fmt.Sprintf("undefined dst policy: %d", p)
+}
+
+// 解析夏令时策略
+func ParseDSTPolicy(s string) (DSTPolicy, error) {
+	switch s {
+	case "", "skip":
+		return DSTSkip, nil
+	case "run_once":
+		return DSTRunOnce, nil
+	}
+	return DSTSkip, fmt.Errorf("未定义的夏令时策略: %s", s)
+}
+
+// 小时字段为 * 的标记, 和 github.com/robfig/cron/v3 相同
+const cronStarBit = 1 << 63
+
+// 按夏令时策略修正触发时间的 cron.Schedule
+type dstSchedule struct {
+	spec   *cron.SpecSchedule
+	policy DSTPolicy
+}
+
+// 包装表达式, 只有小时字段为固定值时需要修正
+func wrapDSTSchedule(schedule cron.Schedule, policy DSTPolicy) cron.Schedule {
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok || spec.Hour&cronStarBit > 0 {
+		return schedule
+	}
+	return &dstSchedule{spec: spec, policy: policy}
+}
+
+func (d *dstSchedule) Next(t time.Time) time.Time {
+	next := d.spec.Next(t)
+	for !next.IsZero() && d.isRepeated(next) {
+		next = d.spec.Next(next)
+	}
+	if d.policy == DSTRunOnce && !next.IsZero() {
+		if gap, ok := d.skippedGap(t, next); ok {
+			return gap
+		}
+	}
+	return next
+}
+
+// 检查触发时间是否是夏令时结束时重复的墙上时间的第二次
+func (d *dstSchedule) isRepeated(next time.Time) bool {
+	_, offset := next.Zone()
+	_, before := next.Add(-3 * time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+	earlier := next.Add(-time.Duration(before-offset) * time.Second)
+	_, earlierOffset := earlier.Zone()
+	return earlierOffset == before && wallClock(earlier) == wallClock(next)
+}
+
+// 查找 (t, next) 之间夏令时开始时跳过的时间里是否有触发, 如果有返回夏令时开始的时间
+func (d *dstSchedule) skippedGap(t, next time.Time) (time.Time, bool) {
+	for start := t; start.Before(next); start = start.Add(24 * time.Hour) {
+		end := start.Add(24 * time.Hour)
+		if end.After(next) {
+			end = next
+		}
+
+		_, startOffset := start.Zone()
+		_, endOffset := end.Zone()
+		if endOffset <= startOffset {
+			continue
+		}
+
+		// 二分查找夏令时开始的时间
+		lo, hi := start, end
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, offset := mid.Zone(); offset == startOffset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transition := hi.Truncate(time.Second)
+		if !transition.After(t) {
+			continue
+		}
+
+		// 跳过的墙上时间在旧的偏移量下对应 [transition, transition+gap)
+		spec := *d.spec
+		spec.Location = time.FixedZone("", startOffset)
+		gap := time.Duration(endOffset-startOffset) * time.Second
+		if c := spec.Next(transition.Add(-time.Second)); !c.IsZero() && c.Before(transition.Add(gap)) {
+			return transition, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// 墙上时间
+func wallClock(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,94 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDSTSchedule(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("没有时区数据", err)
+	}
+	// 2021-03-14 02:00 EST 跳到 03:00 EDT, 2021-11-07 02:00 EDT 回到 01:00 EST
+	spring := time.Date(2021, 3, 13, 23, 0, 0, 0, ny)
+	fall := time.Date(2021, 11, 6, 23, 0, 0, 0, ny)
+
+	tests := []struct {
+		name       string
+		expression string
+		policy     DSTPolicy
+		start      time.Time
+		expect     []string
+	}{
+		{"跳过的时间不执行", "30 2 * * *", DSTSkip, spring, []string{
+			"2021-03-15 02:30:00 EDT", "2021-03-16 02:30:00 EDT",
+		}},
+		{"跳过的时间在夏令时开始时执行一次", "30 2 * * *", DSTRunOnce, spring, []string{
+			"2021-03-14 03:00:00 EDT", "2021-03-15 02:30:00 EDT", "2021-03-16 02:30:00 EDT",
+		}},
+		{"没有落在跳过的时间里", "30 1 * * *", DSTRunOnce, spring, []string{
+			"2021-03-14 01:30:00 EST", "2021-03-15 01:30:00 EDT",
+		}},
+		{"重复的时间只执行一次", "30 1 * * *", DSTSkip, fall, []string{
+			"2021-11-07 01:30:00 EDT", "2021-11-08 01:30:00 EST",
+		}},
+		{"重复的时间只执行一次", "30 1 * * *", DSTRunOnce, fall, []string{
+			"2021-11-07 01:30:00 EDT", "2021-11-08 01:30:00 EST",
+		}},
+		{"小时为*时跳过的时间不执行", "0 * * * *", DSTRunOnce, time.Date(2021, 3, 14, 1, 0, 0, 0, ny), []string{
+			"2021-03-14 03:00:00 EDT", "2021-03-14 04:00:00 EDT",
+		}},
+		{"小时为*时重复的时间会执行", "0 * * * *", DSTSkip, time.Date(2021, 11, 7, 0, 0, 0, 0, ny), []string{
+			"2021-11-07 01:00:00 EDT", "2021-11-07 01:00:00 EST", "2021-11-07 02:00:00 EST",
+		}},
+	}
+
+	for _, test := range tests {
+		trigger, err := NewCronTriggerOfConfig(test.expression, CronTriggerConfig{Location: ny, DST: test.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schedule := trigger.(*CronTrigger).schedule
+		next := test.start
+		for i, expect := range test.expect {
+			next = schedule.Next(next)
+			if got := next.Format("2006-01-02 15:04:05 MST"); got != expect {
+				t.Fatalf("%s: %q 策略 %s 第%d次触发应该是 %s, 实际为 %s", test.name, test.expression, test.policy, i+1, expect, got)
+			}
+		}
+	}
+}
+
+func TestTriggerLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("没有时区数据", err)
+	}
+
+	trigger, err := NewCronTriggerOfConfig("0 8 * * *", CronTriggerConfig{Location: ny})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trigger.Expression() != "CRON_TZ=America/New_York 0 8 * * *" || trigger.Location() != ny {
+		t.Fatalf("时区错误: %s, %s", trigger.Expression(), trigger.Location())
+	}
+
+	// 表达式的时区前缀优先
+	trigger, err = NewCronTriggerOfConfig("CRON_TZ=Asia/Shanghai 0 8 * * *", CronTriggerConfig{Location: ny})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trigger.Expression() != "CRON_TZ=Asia/Shanghai 0 8 * * *" || trigger.Location().String() != "Asia/Shanghai" {
+		t.Fatalf("时区错误: %s, %s", trigger.Expression(), trigger.Location())
+	}
+	next := trigger.(*CronTrigger).schedule.Next(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !next.Equal(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("下次触发时间错误: %s", next)
+	}
+
+	once := NewOnceTrigger(time.Date(2021, 1, 1, 8, 0, 0, 0, ny))
+	if once.Expression() != "2021-01-01 08:00:00 America/New_York" || once.Location() != ny {
+		t.Fatalf("一次性触发器时区错误: %s", once.Expression())
+	}
+}
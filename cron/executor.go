@@ -1,95 +1,146 @@
-package cron
-
-import (
-	"errors"
-	"sync"
-	"sync/atomic"
-	"time"
-
-	"github.com/zly-app/zapp/pkg/utils"
-)
-
-var OutOfMaxConcurrentExecuteCount = errors.New("超出最大并发执行数")
-
-// 错误回调, 只有会被重试时才会调用
-type ErrCallback func(ctx IContext, err error)
-
-type IExecutor interface {
-	// 执行
-	Do(ctx IContext, errCallback ErrCallback) error
-	// 等待任务执行完毕
-	Wait()
-	// 返回是否正在执行任务
-	IsRunning() bool
-}
-
-type Executor struct {
-	maxConcurrentExecuteCount int64         // 最大并发执行数
-	concurrentExecuteCount    int64         // 当前并发执行数
-	maxRetryCount             int64         // 重试次数
-	retryInterval             time.Duration // 重试间隔
-	wg                        sync.WaitGroup
-}
-
-// 创建一个执行器, 任务失败会重试
-//
-// maxRetryCount: 任务失败重试次数
-// retryInterval: 失败重试间隔时间
-// maxConcurrentExecuteCount: 最大并发执行任务数, 如果为0则不限制
-func NewExecutor(retryCount int64, retryInterval time.Duration, maxConcurrentExecuteCount int64) IExecutor {
-	return &Executor{
-		maxConcurrentExecuteCount: maxConcurrentExecuteCount,
-		concurrentExecuteCount:    0,
-		maxRetryCount:             retryCount,
-		retryInterval:             retryInterval,
-	}
-}
-
-// 执行, 如果已经达到最大并发执行任务数则会返回错误
-func (w *Executor) Do(ctx IContext, errCallback ErrCallback) error {
-	if w.maxConcurrentExecuteCount > 0 && atomic.LoadInt64(&w.concurrentExecuteCount) >= w.maxConcurrentExecuteCount {
-		return OutOfMaxConcurrentExecuteCount
-	}
-
-	w.wg.Add(1)
-	atomic.AddInt64(&w.concurrentExecuteCount, 1)
-
-	err := w.doRetry(ctx, w.retryInterval, w.maxRetryCount, errCallback)
-
-	atomic.AddInt64(&w.concurrentExecuteCount, -1)
-	w.wg.Done()
-	return err
-}
-
-// 等待所有任务执行完毕
-func (w *Executor) Wait() {
-	w.wg.Wait()
-}
-
-// 返回是否正在执行任务
-func (w *Executor) IsRunning() bool {
-	return atomic.LoadInt64(&w.concurrentExecuteCount) > 0
-}
-
-// 执行一个函数
-func (w *Executor) doRetry(ctx IContext, interval time.Duration, retryCount int64, errCallback ErrCallback) (err error) {
-	for {
-		err = utils.Recover.WrapCall(func() error {
-			return ctx.Handler()(ctx)
-		})
-		if err == nil || retryCount == 0 {
-			// 这里不需要错误回调, 如果有err交给调用者处理
-			return
-		}
-
-		retryCount--
-
-		if errCallback != nil {
-			errCallback(ctx, err)
-		}
-
-		if interval > 0 {
-			time.Sleep(interval)
-		}
-	}
-}
+package cron
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zly-app/zapp/pkg/utils"
+)
+
+var OutOfMaxConcurrentExecuteCount = errors.New("超出最大并发执行数")
+
+var TaskNotFound = errors.New("任务不存在")
+
+// 错误回调, 只有会被重试时才会调用
+type ErrCallback func(ctx IContext, err error)
+
+type IExecutor interface {
+	// 执行
+	Do(ctx IContext, errCallback ErrCallback) error
+	// 等待任务执行完毕
+	Wait()
+	// 返回是否正在执行任务
+	IsRunning() bool
+}
+
+type Executor struct {
+	maxConcurrentExecuteCount int64          // 最大并发执行数
+	concurrentExecuteCount    int64          // 当前并发执行数
+	maxRetryCount             int64          // 重试次数
+	backoff                   IBackoff       // 重试退避策略
+	retryIf                   RetryPredicate // 判断错误是否需要重试
+	wg                        sync.WaitGroup
+}
+
+// 执行器配置
+type ExecutorConfig struct {
+	// 任务失败重试次数
+	RetryCount int64
+	// 重试退避策略, 默认不等待
+	Backoff IBackoff
+	// 判断错误是否需要重试, 默认所有错误都重试. 通过 Permanent 包装的错误一定不会重试
+	RetryIf RetryPredicate
+	// 最大并发执行任务数, 如果为0则不限制
+	MaxConcurrentExecuteCount int64
+}
+
+// 创建一个执行器, 任务失败会重试
+//
+// maxRetryCount: 任务失败重试次数
+// retryInterval: 失败重试间隔时间
+// maxConcurrentExecuteCount: 最大并发执行任务数, 如果为0则不限制
+func NewExecutor(retryCount int64, retryInterval time.Duration, maxConcurrentExecuteCount int64) IExecutor {
+	return NewExecutorOfConfig(ExecutorConfig{
+		RetryCount:                retryCount,
+		Backoff:                   ConstantBackoff(retryInterval),
+		MaxConcurrentExecuteCount: maxConcurrentExecuteCount,
+	})
+}
+
+// 根据配置创建一个执行器
+func NewExecutorOfConfig(config ExecutorConfig) IExecutor {
+	backoff := config.Backoff
+	if backoff == nil {
+		backoff = ConstantBackoff(0)
+	}
+	return &Executor{
+		maxConcurrentExecuteCount: config.MaxConcurrentExecuteCount,
+		concurrentExecuteCount:    0,
+		maxRetryCount:             config.RetryCount,
+		backoff:                   backoff,
+		retryIf:                   config.RetryIf,
+	}
+}
+
+// 执行, 如果已经达到最大并发执行任务数则会返回错误
+func (w *Executor) Do(ctx IContext, errCallback ErrCallback) error {
+	if w.maxConcurrentExecuteCount > 0 && atomic.LoadInt64(&w.concurrentExecuteCount) >= w.maxConcurrentExecuteCount {
+		return OutOfMaxConcurrentExecuteCount
+	}
+
+	w.wg.Add(1)
+	atomic.AddInt64(&w.concurrentExecuteCount, 1)
+
+	err := w.doRetry(ctx, w.maxRetryCount, errCallback)
+
+	atomic.AddInt64(&w.concurrentExecuteCount, -1)
+	w.wg.Done()
+	return err
+}
+
+// 等待所有任务执行完毕
+func (w *Executor) Wait() {
+	w.wg.Wait()
+}
+
+// 返回是否正在执行任务
+func (w *Executor) IsRunning() bool {
+	return atomic.LoadInt64(&w.concurrentExecuteCount) > 0
+}
+
+// 执行一个函数
+//
+// 重试等待期间 ctx.Ctx() 被取消时不再重试, 返回最后一次的错误
+func (w *Executor) doRetry(ctx IContext, retryCount int64, errCallback ErrCallback) (err error) {
+	for attempt := 1; ; attempt++ {
+		if c, ok := ctx.(interface{ setAttempt(attempt int) }); ok {
+			c.setAttempt(attempt)
+		}
+
+		err = utils.Recover.WrapCall(func() error {
+			return ctx.Handler()(ctx)
+		})
+		if err == nil || retryCount == 0 || !w.retryable(err) {
+			// 这里不需要错误回调, 如果有err交给调用者处理
+			return
+		}
+
+		retryCount--
+
+		if errCallback != nil {
+			errCallback(ctx, err)
+		}
+
+		if interval := w.backoff.Next(attempt); interval > 0 {
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-ctx.Ctx().Done():
+				timer.Stop()
+				return
+			}
+		} else if ctx.Ctx().Err() != nil {
+			return
+		}
+	}
+}
+
+// 错误是否需要重试
+func (w *Executor) retryable(err error) bool {
+	if IsPermanent(err) {
+		return false
+	}
+	return w.retryIf == nil || w.retryIf(err)
+}
@@ -0,0 +1,283 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 执行来源
+type RunSource string
+
+const (
+	// 定时触发
+	RunBySchedule RunSource = "schedule"
+	// 手动触发
+	RunByManual RunSource = "manual"
+	// 补偿错过的触发
+	RunByMisfire RunSource = "misfire"
+	// 上游任务执行结束后触发
+	RunByUpstream RunSource = "upstream"
+)
+
+// 执行记录
+type RunRecord struct {
+	TaskName  string    `json:"task_name"`
+	RunID     string    `json:"run_id,omitempty"`   // 执行id, 下游任务和触发它的上游任务相同
+	Source    RunSource `json:"source"`             // 执行来源
+	FireTime  time.Time `json:"fire_time"`          // 计划触发时间, 手动触发时等于开始时间
+	StartTime time.Time `json:"start_time"`         // 开始时间
+	EndTime   time.Time `json:"end_time"`           // 结束时间, 正在执行时为零值
+	Attempts  int       `json:"attempts"`           // 执行次数, 包含重试
+	Err       string    `json:"err,omitempty"`      // 最终的错误
+	Instance  string    `json:"instance,omitempty"` // 执行的实例
+	Location  string    `json:"location,omitempty"` // 任务的时区, 记录中的时间都使用这个时区
+}
+
+// 是否正在执行
+func (r *RunRecord) IsRunning() bool {
+	return r.EndTime.IsZero()
+}
+
+// 执行耗时, 正在执行时返回已经执行的时间
+func (r *RunRecord) Duration() time.Duration {
+	if r.IsRunning() {
+		return time.Since(r.StartTime)
+	}
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// 执行记录存储, 用于在重启后保留执行记录
+type IHistoryStore interface {
+	// 保存一条执行结束的记录
+	Save(record *RunRecord) error
+	// 加载任务最近的执行记录, 按开始时间从旧到新排序
+	Load(taskName string, limit int) ([]*RunRecord, error)
+}
+
+var historyStore IHistoryStore
+
+// 设置执行记录存储, 这个函数应该在服务启动之前调用
+func SetHistoryStore(s IHistoryStore) {
+	historyStore = s
+}
+
+// -------------- 执行记录环形缓冲 --------------------
+
+// 任务的执行记录, 只保留最近的记录
+type taskHistory struct {
+	records []*RunRecord
+	next    int  // 下一条记录写入的位置
+	full    bool // 缓冲是否已写满
+	mx      sync.Mutex
+}
+
+func newTaskHistory(size int) *taskHistory {
+	return &taskHistory{records: make([]*RunRecord, size)}
+}
+
+// 添加记录
+func (h *taskHistory) add(record *RunRecord) {
+	h.mx.Lock()
+	h.records[h.next] = record
+	h.next++
+	if h.next == len(h.records) {
+		h.next = 0
+		h.full = true
+	}
+	h.mx.Unlock()
+}
+
+// 修改记录, 在锁中修改避免和读取冲突
+func (h *taskHistory) update(fn func()) {
+	h.mx.Lock()
+	fn()
+	h.mx.Unlock()
+}
+
+// 获取最近的记录, 按开始时间从新到旧排序, limit <= 0 表示获取所有记录
+func (h *taskHistory) list(limit int) []RunRecord {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	n := h.next
+	if h.full {
+		n = len(h.records)
+	}
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+
+	out := make([]RunRecord, 0, limit)
+	for i := 1; i <= limit; i++ {
+		index := (h.next - i + len(h.records)) % len(h.records)
+		out = append(out, *h.records[index])
+	}
+	return out
+}
+
+// 获取任务的执行记录, 不存在时创建, 创建时会从存储中加载
+func (c *CronService) taskHistory(name string) *taskHistory {
+	c.historyMx.Lock()
+	defer c.historyMx.Unlock()
+
+	h, ok := c.histories[name]
+	if ok {
+		return h
+	}
+
+	h = newTaskHistory(c.conf.HistorySize)
+	if store := historyStore; store != nil {
+		records, err := store.Load(name, c.conf.HistorySize)
+		if err != nil {
+			c.app.Error("加载cron任务执行记录失败", zap.String("task_name", name), zap.Error(err))
+		}
+		for _, r := range records {
+			h.add(r)
+		}
+	}
+	c.histories[name] = h
+	return h
+}
+
+// 获取已经加载的任务执行记录, 不会从存储中加载, 没有加载时返回空的记录
+func (c *CronService) loadedHistory(name string) *taskHistory {
+	c.historyMx.Lock()
+	h, ok := c.histories[name]
+	c.historyMx.Unlock()
+	if !ok {
+		return newTaskHistory(1)
+	}
+	return h
+}
+
+// 从存储中加载所有任务的执行记录, 不能在 mx 中调用
+func (c *CronService) loadHistories() {
+	for _, name := range c.TaskNames() {
+		c.taskHistory(name)
+	}
+}
+
+// 开始记录一次执行
+func (c *CronService) beginRecord(task ITask, source RunSource, fireTime time.Time, runID string) *RunRecord {
+	loc := task.GetTrigger().Location()
+	now := time.Now().In(loc)
+	if fireTime.IsZero() {
+		fireTime = now
+	}
+	record := &RunRecord{
+		TaskName:  task.Name(),
+		RunID:     runID,
+		Source:    source,
+		FireTime:  fireTime.In(loc),
+		StartTime: now,
+		Instance:  c.lockOwner,
+		Location:  zoneName(now),
+	}
+	c.taskHistory(task.Name()).add(record)
+	return record
+}
+
+// 结束记录一次执行
+func (c *CronService) endRecord(ctx IContext, record *RunRecord, attempts int, err error) {
+	c.taskHistory(record.TaskName).update(func() {
+		record.EndTime = time.Now().In(record.StartTime.Location())
+		record.Attempts = attempts
+		if err != nil {
+			record.Err = err.Error()
+		}
+	})
+
+	if store := historyStore; store != nil {
+		r := *record
+		if err := store.Save(&r); err != nil {
+			ctx.Error("保存cron任务执行记录失败", zap.Error(err))
+		}
+	}
+}
+
+// -------------- 文件存储 --------------------
+
+// 文件执行记录存储, 每个任务的记录保存为目录中的一个json文件
+type FileHistoryStore struct {
+	dir   string
+	limit int // 每个任务最多保留的记录数
+	mx    sync.Mutex
+}
+
+// 创建一个文件执行记录存储, 每个任务最多保留 limit 条记录, dir 不存在时会自动创建
+func NewFileHistoryStore(dir string, limit int) (*FileHistoryStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建执行记录目录失败: %s", err)
+	}
+	if limit <= 0 {
+		limit = defaultHistorySize
+	}
+	return &FileHistoryStore{dir: dir, limit: limit}, nil
+}
+
+func (f *FileHistoryStore) Save(record *RunRecord) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	records, err := f.load(record.TaskName)
+	if err != nil {
+		return err
+	}
+	records = append(records, record)
+	if len(records) > f.limit {
+		records = records[len(records)-f.limit:]
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	file := f.file(record.TaskName)
+	tmp := file + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func (f *FileHistoryStore) Load(taskName string, limit int) ([]*RunRecord, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	records, err := f.load(taskName)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	return records, nil
+}
+
+func (f *FileHistoryStore) load(taskName string) ([]*RunRecord, error) {
+	data, err := ioutil.ReadFile(f.file(taskName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*RunRecord
+	if err = json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("解析执行记录文件失败: %s", err)
+	}
+	return records, nil
+}
+
+func (f *FileHistoryStore) file(taskName string) string {
+	return filepath.Join(f.dir, url.QueryEscape(taskName)+".json")
+}
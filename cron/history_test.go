@@ -0,0 +1,95 @@
+package cron
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTaskHistory(t *testing.T) {
+	h := newTaskHistory(3)
+	if len(h.list(0)) != 0 {
+		t.Fatal("应该没有记录")
+	}
+	for i := 1; i <= 5; i++ {
+		h.add(&RunRecord{Attempts: i})
+	}
+
+	records := h.list(0)
+	if len(records) != 3 {
+		t.Fatalf("应该只保留3条记录, 实际为%d条", len(records))
+	}
+	for i, r := range records {
+		if r.Attempts != 5-i {
+			t.Fatalf("第%d条记录应该是 %d, 实际为 %d", i, 5-i, r.Attempts)
+		}
+	}
+	if records = h.list(2); len(records) != 2 || records[0].Attempts != 5 {
+		t.Fatalf("limit 无效: %+v", records)
+	}
+}
+
+func TestCronServiceHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cron_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileHistoryStore(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetHistoryStore(store)
+	defer SetHistoryStore(nil)
+
+	var fail bool
+	task := NewTaskOfConfig("history", TaskConfig{
+		Trigger:  NewCronTrigger("@every 1s"),
+		Executor: NewExecutor(2, 0, 1),
+		Handler: func(ctx IContext) error {
+			if fail {
+				return errors.New("fail")
+			}
+			return nil
+		},
+		Enable: true,
+	})
+
+	c := NewCronService(getTestApp()).(*CronService)
+	c.AddTask(task)
+
+	fireTime := time.Unix(time.Now().Unix(), 0)
+	if err = c.execute(task, taskFire{fireTime: fireTime, source: RunBySchedule}); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if err = c.TriggerTask("history"); err == nil {
+		t.Fatal("应该返回错误")
+	}
+	if err = c.TriggerTask("not_found"); err != TaskNotFound {
+		t.Fatalf("应该返回 TaskNotFound, 实际为 %v", err)
+	}
+
+	records := c.History("history", 0)
+	if len(records) != 2 {
+		t.Fatalf("应该有2条记录, 实际为%d条", len(records))
+	}
+	r := records[0]
+	if r.Source != RunByManual || r.Attempts != 3 || r.Err != "fail" || r.IsRunning() {
+		t.Fatalf("手动触发的记录错误: %+v", r)
+	}
+	r = records[1]
+	if r.Source != RunBySchedule || !r.FireTime.Equal(fireTime) || r.Attempts != 1 || r.Err != "" || r.IsRunning() {
+		t.Fatalf("定时触发的记录错误: %+v", r)
+	}
+
+	// 模拟重启后从存储中加载
+	c = NewCronService(getTestApp()).(*CronService)
+	c.AddTask(task)
+	loaded := c.History("history", 0)
+	if len(loaded) != 2 || loaded[0].Source != RunByManual || !loaded[1].FireTime.Equal(fireTime) {
+		t.Fatalf("从存储加载的记录错误: %+v", loaded)
+	}
+}
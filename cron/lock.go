@@ -0,0 +1,150 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 任务被其它实例锁定, 手动触发时返回
+var LockedByOtherInstance = errors.New("任务被其它实例锁定")
+
+// 执行期间失去了锁, 锁可能已经被其它实例获取, 执行的 context 会被取消
+var LockLost = errors.New("执行期间失去了锁")
+
+// 包装失去锁时的执行错误
+func makeLockLostError(err error) error {
+	if err == nil {
+		return LockLost
+	}
+	return fmt.Errorf("%w: %s", LockLost, err)
+}
+
+// 分布式锁, 用于多个实例部署时保证同一个任务只在一个实例上执行
+//
+// 锁是有租约的, 超过ttl没有续约会自动失效, 这样持有锁的实例崩溃后其它实例可以重新获取锁
+type ILocker interface {
+	// 尝试获取锁, 锁已被其它持有者持有时返回 false
+	TryLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// 续约, 锁已经不属于这个持有者时返回 false
+	Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// 释放锁, 锁不属于这个持有者时忽略
+	Unlock(ctx context.Context, key, owner string) error
+}
+
+// 加锁模式
+type LockMode int
+
+const (
+	// 使用服务配置的加锁模式
+	LockDefault LockMode = iota
+	// 不加锁, 每个实例都会执行
+	LockNone
+	// 以任务为粒度加锁, 同一时间只有一个实例在执行这个任务, 执行结束后释放锁
+	LockPerTask
+	// 以触发时间为粒度加锁, 每次触发只有一个实例执行, 执行结束后不释放锁, 等待租约过期
+	LockPerFireTime
+)
+
+func (m LockMode) String() string {
+	switch m {
+	case LockDefault:
+		return "default"
+	case LockNone:
+		return "none"
+	case LockPerTask:
+		return "task"
+	case LockPerFireTime:
+		return "fire_time"
+	}
+	return fmt.Sprintf("undefined lock mode: %d", m)
+}
+
+// 解析加锁模式
+func ParseLockMode(s string) (LockMode, error) {
+	switch s {
+	case "", "default":
+		return LockDefault, nil
+	case "none":
+		return LockNone, nil
+	case "task":
+		return LockPerTask, nil
+	case "fire_time":
+		return LockPerFireTime, nil
+	}
+	return LockDefault, fmt.Errorf("未定义的加锁模式: %s", s)
+}
+
+var locker ILocker
+
+// 设置分布式锁, 设置后任务执行前需要获取锁, 这个函数应该在服务启动之前调用
+func SetLocker(l ILocker) {
+	locker = l
+}
+
+// 生成当前实例的锁持有者名
+func makeLockOwner(appName string) string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s:%d:%s", appName, hostname, os.Getpid(), strconv.FormatInt(time.Now().UnixNano(), 36))
+}
+
+// -------------- 内存锁 --------------------
+
+// 内存锁, 只在当前进程内有效, 可用于测试或同一个进程中运行多个cron服务
+type MemoryLocker struct {
+	locks map[string]*memoryLock
+	mx    sync.Mutex
+}
+
+type memoryLock struct {
+	owner    string
+	expireAt time.Time
+}
+
+// 创建一个内存锁
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]*memoryLock)}
+}
+
+func (m *MemoryLocker) TryLock(_ context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	now := time.Now()
+	if l, ok := m.locks[key]; ok && l.owner != owner && now.Before(l.expireAt) {
+		return false, nil
+	}
+	// 清理过期的锁, 以触发时间为粒度的锁不会主动释放
+	for k, l := range m.locks {
+		if !now.Before(l.expireAt) {
+			delete(m.locks, k)
+		}
+	}
+	m.locks[key] = &memoryLock{owner: owner, expireAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *MemoryLocker) Renew(_ context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	l, ok := m.locks[key]
+	if !ok || l.owner != owner || !time.Now().Before(l.expireAt) {
+		return false, nil
+	}
+	l.expireAt = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (m *MemoryLocker) Unlock(_ context.Context, key, owner string) error {
+	m.mx.Lock()
+	if l, ok := m.locks[key]; ok && l.owner == owner {
+		delete(m.locks, key)
+	}
+	m.mx.Unlock()
+	return nil
+}
@@ -0,0 +1,168 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 文件锁守卫超时时间, 超过这个时间的守卫认为是崩溃的进程留下的
+	fileLockGuardTimeout = 10 * time.Second
+	// 获取文件锁守卫的等待时间
+	fileLockGuardWait = time.Second
+	// 清理过期锁文件的间隔
+	fileLockSweepInterval = time.Minute
+)
+
+// 文件锁, 锁保存在目录中, 多个实例共享同一个目录(如nfs)时可以互斥
+//
+// 每个锁是一个文件, 内容为持有者和过期时间, 修改锁文件时通过创建守卫目录保证原子性
+type FileLocker struct {
+	dir       string
+	lastSweep int64 // 上次清理的时间, 纳秒
+}
+
+// 创建一个文件锁, dir 不存在时会自动创建
+func NewFileLocker(dir string) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建锁目录失败: %s", err)
+	}
+	return &FileLocker{dir: dir}, nil
+}
+
+func (f *FileLocker) TryLock(ctx context.Context, key, owner string, ttl time.Duration) (ok bool, err error) {
+	f.sweep(ctx)
+	err = f.withGuard(ctx, f.lockFile(key), func(file string) error {
+		holder, expireAt, err := readFileLock(file)
+		if err != nil {
+			return err
+		}
+		if holder != "" && holder != owner && time.Now().Before(expireAt) {
+			return nil
+		}
+		ok = true
+		return writeFileLock(file, owner, time.Now().Add(ttl))
+	})
+	return ok, err
+}
+
+func (f *FileLocker) Renew(ctx context.Context, key, owner string, ttl time.Duration) (ok bool, err error) {
+	err = f.withGuard(ctx, f.lockFile(key), func(file string) error {
+		holder, expireAt, err := readFileLock(file)
+		if err != nil {
+			return err
+		}
+		if holder != owner || !time.Now().Before(expireAt) {
+			return nil
+		}
+		ok = true
+		return writeFileLock(file, owner, time.Now().Add(ttl))
+	})
+	return ok, err
+}
+
+func (f *FileLocker) Unlock(ctx context.Context, key, owner string) error {
+	return f.withGuard(ctx, f.lockFile(key), func(file string) error {
+		holder, _, err := readFileLock(file)
+		if err != nil || holder != owner {
+			return err
+		}
+		return os.Remove(file)
+	})
+}
+
+// 获取锁文件路径
+func (f *FileLocker) lockFile(key string) string {
+	return filepath.Join(f.dir, url.QueryEscape(key)+".lock")
+}
+
+// 在守卫中操作锁文件
+func (f *FileLocker) withGuard(ctx context.Context, file string, fn func(file string) error) error {
+	guard := file + ".guard"
+
+	deadline := time.Now().Add(fileLockGuardWait)
+	for {
+		err := os.Mkdir(guard, 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		if info, err := os.Stat(guard); err == nil && time.Since(info.ModTime()) > fileLockGuardTimeout {
+			_ = os.Remove(guard)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return errors.New("等待文件锁守卫超时")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	defer os.Remove(guard)
+
+	return fn(file)
+}
+
+// 清理过期的锁文件, 以触发时间为粒度的锁不会主动释放
+func (f *FileLocker) sweep(ctx context.Context) {
+	last := atomic.LoadInt64(&f.lastSweep)
+	now := time.Now().UnixNano()
+	if time.Duration(now-last) < fileLockSweepInterval || !atomic.CompareAndSwapInt64(&f.lastSweep, last, now) {
+		return
+	}
+
+	files, _ := filepath.Glob(filepath.Join(f.dir, "*.lock"))
+	for _, file := range files {
+		_ = f.withGuard(ctx, file, func(file string) error {
+			owner, expireAt, err := readFileLock(file)
+			if err == nil && owner != "" && time.Now().After(expireAt) {
+				return os.Remove(file)
+			}
+			return nil
+		})
+	}
+}
+
+// 读取锁文件, 文件不存在时返回空的持有者
+func readFileLock(file string) (owner string, expireAt time.Time, err error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	if len(lines) != 2 {
+		return "", time.Time{}, nil // 损坏的锁文件视为没有持有者
+	}
+	nano, err := strconv.ParseInt(lines[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, nil
+	}
+	return lines[0], time.Unix(0, nano), nil
+}
+
+// 写入锁文件, 先写入临时文件再重命名, 避免读到写了一半的内容
+func writeFileLock(file, owner string, expireAt time.Time) error {
+	tmp := file + ".tmp"
+	data := owner + "\n" + strconv.FormatInt(expireAt.UnixNano(), 10) + "\n"
+	if err := ioutil.WriteFile(tmp, []byte(data), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,113 @@
+package cron
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// 清理过期锁的间隔
+const sqlLockSweepInterval = time.Minute
+
+// 数据库锁, 每个锁是表中的一行, 多个实例使用同一个数据库时可以互斥
+//
+// 表结构如下, 过期时间为毫秒时间戳, 各实例的时钟误差应该远小于ttl
+//
+//	CREATE TABLE cron_lock (
+//		lock_key  VARCHAR(255) NOT NULL PRIMARY KEY,
+//		owner     VARCHAR(255) NOT NULL,
+//		expire_at BIGINT       NOT NULL
+//	);
+type SqlLocker struct {
+	db    *sql.DB
+	table string
+	// 占位符生成函数, n 从1开始
+	placeholder func(n int) string
+	lastSweep   int64 // 上次清理的时间, 纳秒
+}
+
+// 创建一个数据库锁, 使用 ? 作为占位符, 适用于 mysql, sqlite 等数据库
+func NewSqlLocker(db *sql.DB, table string) *SqlLocker {
+	return NewSqlLockerWithPlaceholder(db, table, func(int) string { return "?" })
+}
+
+// 创建一个数据库锁并指定占位符, 如 postgres 使用 $1, $2
+//
+//	cron.NewSqlLockerWithPlaceholder(db, "cron_lock", func(n int) string { return "$" + strconv.Itoa(n) })
+func NewSqlLockerWithPlaceholder(db *sql.DB, table string, placeholder func(n int) string) *SqlLocker {
+	return &SqlLocker{
+		db:          db,
+		table:       table,
+		placeholder: placeholder,
+	}
+}
+
+func (s *SqlLocker) TryLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	s.sweep(ctx, now)
+	expireAt := now.Add(ttl).UnixNano() / 1e6
+
+	// 锁已存在时更新过期的或自己持有的锁
+	query := fmt.Sprintf("UPDATE %s SET owner = %s, expire_at = %s WHERE lock_key = %s AND (expire_at <= %s OR owner = %s)",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4), s.placeholder(5))
+	result, err := s.db.ExecContext(ctx, query, owner, expireAt, key, now.UnixNano()/1e6, owner)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err == nil, err
+	}
+
+	// 锁不存在时插入, 其它实例同时插入时主键冲突
+	query = fmt.Sprintf("INSERT INTO %s (lock_key, owner, expire_at) VALUES (%s, %s, %s)",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3))
+	if _, err = s.db.ExecContext(ctx, query, key, owner, expireAt); err != nil {
+		exists, qErr := s.exists(ctx, key)
+		if qErr == nil && exists {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *SqlLocker) Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	query := fmt.Sprintf("UPDATE %s SET expire_at = %s WHERE lock_key = %s AND owner = %s AND expire_at > %s",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4))
+	result, err := s.db.ExecContext(ctx, query, now.Add(ttl).UnixNano()/1e6, key, owner, now.UnixNano()/1e6)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (s *SqlLocker) Unlock(ctx context.Context, key, owner string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE lock_key = %s AND owner = %s", s.table, s.placeholder(1), s.placeholder(2))
+	_, err := s.db.ExecContext(ctx, query, key, owner)
+	return err
+}
+
+// 清理过期的锁, 以触发时间为粒度的锁不会主动释放
+func (s *SqlLocker) sweep(ctx context.Context, now time.Time) {
+	last := atomic.LoadInt64(&s.lastSweep)
+	if time.Duration(now.UnixNano()-last) < sqlLockSweepInterval || !atomic.CompareAndSwapInt64(&s.lastSweep, last, now.UnixNano()) {
+		return
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE expire_at <= %s", s.table, s.placeholder(1))
+	_, _ = s.db.ExecContext(ctx, query, now.UnixNano()/1e6)
+}
+
+// 检查锁所在的行是否存在
+func (s *SqlLocker) exists(ctx context.Context, key string) (bool, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE lock_key = %s", s.table, s.placeholder(1))
+	var n int
+	if err := s.db.QueryRowContext(ctx, query, key).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,190 @@
+package cron
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 模拟数据库中的锁表, 只支持 SqlLocker 使用的语句
+type fakeLockTable struct {
+	rows map[string]fakeLockRow
+	// 执行 INSERT 之前调用, 用于模拟其它实例同时插入
+	beforeInsert func(t *fakeLockTable)
+	mx           sync.Mutex
+}
+
+type fakeLockRow struct {
+	owner    string
+	expireAt int64
+}
+
+var (
+	fakeLockTables   = make(map[string]*fakeLockTable)
+	fakeLockTablesMx sync.Mutex
+	fakeDriverOnce   sync.Once
+)
+
+// 创建一个使用模拟锁表的数据库
+func newFakeLockDB(t *testing.T) (*sql.DB, *fakeLockTable) {
+	fakeDriverOnce.Do(func() { sql.Register("cron_fake_lock", fakeLockDriver{}) })
+
+	table := &fakeLockTable{rows: make(map[string]fakeLockRow)}
+	fakeLockTablesMx.Lock()
+	fakeLockTables[t.Name()] = table
+	fakeLockTablesMx.Unlock()
+
+	db, err := sql.Open("cron_fake_lock", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, table
+}
+
+type fakeLockDriver struct{}
+
+func (fakeLockDriver) Open(name string) (driver.Conn, error) {
+	fakeLockTablesMx.Lock()
+	defer fakeLockTablesMx.Unlock()
+	return &fakeLockConn{table: fakeLockTables[name]}, nil
+}
+
+type fakeLockConn struct {
+	table *fakeLockTable
+}
+
+func (c *fakeLockConn) Prepare(query string) (driver.Stmt, error) {
+	if !strings.Contains(query, " cron_lock ") {
+		return nil, errors.New("no such table")
+	}
+	return &fakeLockStmt{table: c.table, query: query}, nil
+}
+func (c *fakeLockConn) Close() error              { return nil }
+func (c *fakeLockConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeLockStmt struct {
+	table *fakeLockTable
+	query string
+}
+
+func (s *fakeLockStmt) Close() error  { return nil }
+func (s *fakeLockStmt) NumInput() int { return -1 }
+
+func (s *fakeLockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	t := s.table
+	if strings.HasPrefix(s.query, "INSERT") && t.beforeInsert != nil {
+		t.beforeInsert(t)
+	}
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	var n int64
+	switch {
+	case strings.HasPrefix(s.query, "UPDATE cron_lock SET owner"):
+		owner, expireAt, key, now := args[0].(string), args[1].(int64), args[2].(string), args[3].(int64)
+		if row, ok := t.rows[key]; ok && (row.expireAt <= now || row.owner == owner) {
+			t.rows[key] = fakeLockRow{owner: owner, expireAt: expireAt}
+			n = 1
+		}
+	case strings.HasPrefix(s.query, "UPDATE cron_lock SET expire_at"):
+		expireAt, key, owner, now := args[0].(int64), args[1].(string), args[2].(string), args[3].(int64)
+		if row, ok := t.rows[key]; ok && row.owner == owner && row.expireAt > now {
+			t.rows[key] = fakeLockRow{owner: owner, expireAt: expireAt}
+			n = 1
+		}
+	case strings.HasPrefix(s.query, "INSERT"):
+		key := args[0].(string)
+		if _, ok := t.rows[key]; ok {
+			return nil, errors.New("duplicate key")
+		}
+		t.rows[key] = fakeLockRow{owner: args[1].(string), expireAt: args[2].(int64)}
+		n = 1
+	case strings.HasPrefix(s.query, "DELETE FROM cron_lock WHERE lock_key"):
+		key, owner := args[0].(string), args[1].(string)
+		if row, ok := t.rows[key]; ok && row.owner == owner {
+			delete(t.rows, key)
+			n = 1
+		}
+	case strings.HasPrefix(s.query, "DELETE FROM cron_lock WHERE expire_at"):
+		for key, row := range t.rows {
+			if row.expireAt <= args[0].(int64) {
+				delete(t.rows, key)
+				n++
+			}
+		}
+	default:
+		return nil, errors.New("unsupported query: " + s.query)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s *fakeLockStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT COUNT(*) FROM cron_lock") {
+		return nil, errors.New("unsupported query: " + s.query)
+	}
+	s.table.mx.Lock()
+	_, ok := s.table.rows[args[0].(string)]
+	s.table.mx.Unlock()
+
+	var n int64
+	if ok {
+		n = 1
+	}
+	return &fakeCountRows{n: n}, nil
+}
+
+type fakeCountRows struct {
+	n    int64
+	done bool
+}
+
+func (r *fakeCountRows) Columns() []string { return []string{"count"} }
+func (r *fakeCountRows) Close() error      { return nil }
+func (r *fakeCountRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.n
+	return nil
+}
+
+func TestSqlLocker(t *testing.T) {
+	db, _ := newFakeLockDB(t)
+	defer db.Close()
+	testLocker(t, NewSqlLocker(db, "cron_lock"))
+}
+
+func TestSqlLockerInsertRace(t *testing.T) {
+	db, table := newFakeLockDB(t)
+	defer db.Close()
+	l := NewSqlLocker(db, "cron_lock")
+	ctx := context.Background()
+
+	// UPDATE 没有更新到行之后其它实例插入了锁, INSERT 主键冲突时应该返回未获取到锁
+	table.beforeInsert = func(t *fakeLockTable) {
+		t.mx.Lock()
+		t.rows["race"] = fakeLockRow{owner: "other", expireAt: time.Now().Add(time.Minute).UnixNano() / 1e6}
+		t.mx.Unlock()
+	}
+	ok, err := l.TryLock(ctx, "race", "me", time.Minute)
+	if err != nil || ok {
+		t.Fatalf("其它实例同时插入时应该返回 false, 实际为 %v, %v", ok, err)
+	}
+	if owner := table.rows["race"].owner; owner != "other" {
+		t.Fatalf("锁的持有者应该是 other, 实际为 %s", owner)
+	}
+
+	// INSERT 因为其它原因失败时应该返回错误
+	table.beforeInsert = func(t *fakeLockTable) {}
+	if _, err := NewSqlLocker(db, "not_exists").TryLock(ctx, "key", "me", time.Minute); err == nil {
+		t.Fatal("表不存在时应该返回错误")
+	}
+}
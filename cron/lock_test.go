@@ -0,0 +1,240 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testLocker(t *testing.T, l ILocker) {
+	ctx := context.Background()
+	ttl := 200 * time.Millisecond
+
+	ok, err := l.TryLock(ctx, "a", "o1", ttl)
+	if err != nil || !ok {
+		t.Fatalf("o1 获取锁失败: %v, %v", ok, err)
+	}
+	ok, err = l.TryLock(ctx, "a", "o2", ttl)
+	if err != nil || ok {
+		t.Fatalf("o2 不应该获取到锁: %v, %v", ok, err)
+	}
+	ok, err = l.TryLock(ctx, "b", "o2", ttl)
+	if err != nil || !ok {
+		t.Fatalf("o2 获取其它锁失败: %v, %v", ok, err)
+	}
+
+	// 续约
+	ok, err = l.Renew(ctx, "a", "o1", ttl)
+	if err != nil || !ok {
+		t.Fatalf("o1 续约失败: %v, %v", ok, err)
+	}
+	ok, err = l.Renew(ctx, "a", "o2", ttl)
+	if err != nil || ok {
+		t.Fatalf("o2 不应该续约成功: %v, %v", ok, err)
+	}
+
+	// 释放其它持有者的锁会被忽略
+	if err = l.Unlock(ctx, "a", "o2"); err != nil {
+		t.Fatal(err)
+	}
+	ok, _ = l.TryLock(ctx, "a", "o2", ttl)
+	if ok {
+		t.Fatal("o2 不应该获取到锁")
+	}
+	if err = l.Unlock(ctx, "a", "o1"); err != nil {
+		t.Fatal(err)
+	}
+	ok, _ = l.TryLock(ctx, "a", "o2", ttl)
+	if !ok {
+		t.Fatal("o1 释放后 o2 应该获取到锁")
+	}
+
+	// 过期
+	time.Sleep(ttl + 50*time.Millisecond)
+	ok, err = l.Renew(ctx, "a", "o2", ttl)
+	if err != nil || ok {
+		t.Fatalf("过期的锁不应该续约成功: %v, %v", ok, err)
+	}
+	ok, err = l.TryLock(ctx, "a", "o1", ttl)
+	if err != nil || !ok {
+		t.Fatalf("过期后 o1 获取锁失败: %v, %v", ok, err)
+	}
+}
+
+func TestMemoryLocker(t *testing.T) {
+	testLocker(t, NewMemoryLocker())
+}
+
+func TestFileLocker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cron_lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewFileLocker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLocker(t, l)
+}
+
+func TestParseLockMode(t *testing.T) {
+	for _, mode := range []LockMode{LockDefault, LockNone, LockPerTask, LockPerFireTime} {
+		m, err := ParseLockMode(mode.String())
+		if err != nil || m != mode {
+			t.Fatalf("解析 %s 失败: %v, %v", mode, m, err)
+		}
+	}
+	if _, err := ParseLockMode("xxx"); err == nil {
+		t.Fatal("应该返回错误")
+	}
+}
+
+func TestCronServiceLock(t *testing.T) {
+	SetLocker(NewMemoryLocker())
+	defer SetLocker(nil)
+
+	var count int32
+	handler := func(ctx IContext) error {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+	// 模拟两个实例
+	c1 := NewCronService(getTestApp()).(*CronService)
+	c2 := NewCronService(getTestApp()).(*CronService)
+
+	run := func(task ITask, fireTime time.Time) {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { c1.execute(task, taskFire{fireTime: fireTime, source: RunBySchedule}); wg.Done() }()
+		go func() { c2.execute(task, taskFire{fireTime: fireTime, source: RunBySchedule}); wg.Done() }()
+		wg.Wait()
+	}
+
+	// 以触发时间加锁, 同一个触发时间只执行一次
+	fireTime := time.Unix(time.Now().Unix(), 0)
+	task := NewTask("fire_time", "@every 1s", true, handler)
+	run(task, fireTime)
+	if n := atomic.SwapInt32(&count, 0); n != 1 {
+		t.Fatalf("同一个触发时间应该只执行1次, 实际执行了%d次", n)
+	}
+	run(task, fireTime.Add(time.Second))
+	if n := atomic.SwapInt32(&count, 0); n != 1 {
+		t.Fatalf("新的触发时间应该执行1次, 实际执行了%d次", n)
+	}
+
+	// 以任务加锁, 执行结束后释放锁
+	task = NewTaskOfConfig("task", TaskConfig{
+		Trigger:  NewCronTrigger("@every 1s"),
+		Executor: NewExecutor(0, 0, 1),
+		Handler:  handler,
+		Enable:   true,
+		LockMode: LockPerTask,
+	})
+	run(task, fireTime)
+	run(task, fireTime)
+	if n := atomic.SwapInt32(&count, 0); n != 2 {
+		t.Fatalf("以任务加锁时每次同时触发应该执行1次, 实际共执行了%d次", n)
+	}
+
+	// 不加锁
+	task = NewTaskOfConfig("none", TaskConfig{
+		Trigger:  NewCronTrigger("@every 1s"),
+		Executor: NewExecutor(0, 0, 0),
+		Handler:  handler,
+		Enable:   true,
+		LockMode: LockNone,
+	})
+	run(task, fireTime)
+	if n := atomic.SwapInt32(&count, 0); n != 2 {
+		t.Fatalf("不加锁时每个实例都应该执行, 实际执行了%d次", n)
+	}
+}
+
+// 续约总是失败的锁, 模拟锁被其它实例获取
+type lostLocker struct {
+	*MemoryLocker
+}
+
+func (lostLocker) Renew(context.Context, string, string, time.Duration) (bool, error) {
+	return false, nil
+}
+
+func TestCronServiceLockLost(t *testing.T) {
+	SetLocker(lostLocker{NewMemoryLocker()})
+	defer SetLocker(nil)
+
+	c := NewCronService(getTestApp()).(*CronService)
+	c.lockTTL = 30 * time.Millisecond
+	task := NewTask("lock_lost", "@every 1s", true, func(ctx IContext) error {
+		select {
+		case <-ctx.Ctx().Done():
+			return ctx.Ctx().Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	c.AddTask(task)
+
+	err := c.execute(task, taskFire{fireTime: time.Now(), source: RunBySchedule})
+	if !errors.Is(err, LockLost) {
+		t.Fatalf("失去锁时应该取消执行并返回 LockLost, 实际为 %v", err)
+	}
+	records := c.History("lock_lost", 1)
+	if len(records) != 1 || !strings.HasPrefix(records[0].Err, LockLost.Error()) {
+		t.Fatalf("执行记录中应该记录失去锁: %+v", records)
+	}
+}
+
+func TestManualTriggerLock(t *testing.T) {
+	SetLocker(NewMemoryLocker())
+	defer SetLocker(nil)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	newTask := func() ITask {
+		return NewTaskOfConfig("manual_lock", TaskConfig{
+			Trigger:  NewCronTrigger("@every 1s"),
+			Executor: NewExecutor(0, 0, 0),
+			Handler: func(ctx IContext) error {
+				if ctx.Payload() == nil {
+					close(started)
+					<-release
+				}
+				return nil
+			},
+			LockMode: LockPerTask,
+		})
+	}
+	// 模拟两个实例, 任务在第一个实例上定时执行
+	c1 := NewCronService(getTestApp()).(*CronService)
+	c2 := NewCronService(getTestApp()).(*CronService)
+	task1, task2 := newTask(), newTask()
+	task1.setEnable(true)
+	c1.AddTask(task1)
+	c2.AddTask(task2)
+
+	done := make(chan struct{})
+	go func() {
+		c1.execute(task1, taskFire{fireTime: time.Now(), source: RunBySchedule})
+		close(done)
+	}()
+	<-started
+
+	// 手动触发未启用的任务时也会获取任务粒度的锁
+	if err := c2.TriggerTaskWithPayload("manual_lock", []byte("manual")); err != LockedByOtherInstance {
+		t.Fatalf("任务被其它实例锁定时手动触发应该返回 LockedByOtherInstance, 实际为 %v", err)
+	}
+	close(release)
+	<-done
+	if err := c2.TriggerTaskWithPayload("manual_lock", []byte("manual")); err != nil {
+		t.Fatalf("锁释放后手动触发应该执行成功: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 执行期间服务关闭, 任务被取消, 这次触发会按错过触发的处理策略补偿
+var CanceledByShutdown = errors.New("执行期间服务关闭")
+
+// 包装服务关闭时的执行错误
+func makeCanceledByShutdownError(err error) error {
+	return fmt.Errorf("%w: %s", CanceledByShutdown, err)
+}
+
+// 错过触发的处理策略
+//
+// 暂停期间, 服务停止期间或任务队列已满时会错过触发
+type MisfirePolicy int
+
+const (
+	// 跳过错过的触发
+	MisfireSkip MisfirePolicy = iota
+	// 立即执行一次, 计划触发时间为最后一次错过的触发时间
+	MisfireFireOnce
+	// 按顺序执行所有错过的触发, 最多执行 MisfireLimit 次, 超出时只执行最近的触发
+	MisfireFireAll
+)
+
+func (p MisfirePolicy) String() string {
+	switch p {
+	case MisfireSkip:
+		return "skip"
+	case MisfireFireOnce:
+		return "fire_once"
+	case MisfireFireAll:
+		return "fire_all"
+	}
+	return fmt.Sprintf("undefined misfire policy: %d", p)
+}
+
+// 解析错过触发的处理策略
+func ParseMisfirePolicy(s string) (MisfirePolicy, error) {
+	switch s {
+	case "", "skip":
+		return MisfireSkip, nil
+	case "fire_once":
+		return MisfireFireOnce, nil
+	case "fire_all":
+		return MisfireFireAll, nil
+	}
+	return MisfireSkip, fmt.Errorf("未定义的错过触发处理策略: %s", s)
+}
+
+const (
+	// 默认最多补偿的触发次数
+	defaultMisfireLimit = 10
+	// 查找错过的触发时每次最多计算的触发次数
+	maxMisfireScan = 1000000
+)
+
+// 获取任务最后一次触发的时间, 需要在 mx 中调用
+//
+// 正在执行的触发视为已经触发, 避免重复补偿. 否则为最后一次执行过的触发
+func (c *CronService) lastFireTime(task ITask) time.Time {
+	last := c.lastCompletedFireTime(task)
+	if t, ok := c.pendingFireTimes[task.Name()]; ok && t.After(last) {
+		return t
+	}
+	return last
+}
+
+// 获取任务最后一次执行过的定时触发时间, 执行失败也算执行过, 服务关闭时被取消的不算, 不存在时从执行记录中查找, 需要在 mx 中调用
+//
+// 这里不会从执行记录存储中加载, 需要在加锁之前通过 loadHistories 加载
+func (c *CronService) lastCompletedFireTime(task ITask) time.Time {
+	if t, ok := c.fireTimes[task.Name()]; ok {
+		return t
+	}
+
+	var last time.Time
+	for _, r := range c.loadedHistory(task.Name()).list(0) {
+		if (r.Source == RunBySchedule || r.Source == RunByMisfire) && !r.IsRunning() && !strings.HasPrefix(r.Err, CanceledByShutdown.Error()) && r.FireTime.After(last) {
+			last = r.FireTime
+		}
+	}
+	c.fireTimes[task.Name()] = last
+	return last
+}
+
+// 一次定时触发执行结束, completed 表示这次触发是否已经完成
+//
+// 执行过的触发无论成功失败都已经完成. 没有开始执行或执行期间服务关闭被取消的没有完成, 下次触发时按错过触发的处理策略补偿
+func (c *CronService) finishFire(task ITask, fireTime time.Time, completed bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.tasks[task.Name()] != task { // 任务已经被移除
+		return
+	}
+	if completed && fireTime.After(c.lastCompletedFireTime(task)) {
+		c.fireTimes[task.Name()] = fireTime
+	}
+	if t, ok := c.pendingFireTimes[task.Name()]; ok && !fireTime.Before(t) {
+		delete(c.pendingFireTimes, task.Name())
+	}
+}
+
+// 获取上次触发之后到 until 之间错过的触发时间, inclusive 表示是否包含 until
+//
+// 返回的触发时间已经按策略筛选过, 从旧到新排序
+func (c *CronService) missedFireTimes(task ITask, until time.Time, inclusive bool) []time.Time {
+	policy, limit := task.Misfire()
+	if policy == MisfireSkip {
+		return nil
+	}
+	last := c.lastFireTime(task)
+	if last.IsZero() { // 从来没有执行过
+		return nil
+	}
+	if policy == MisfireFireOnce {
+		limit = 1
+	}
+
+	trigger := task.GetTrigger()
+	first, ok := trigger.NextTriggerTime(last)
+	if !ok || !isMissed(first, until, inclusive) {
+		return nil
+	}
+	second, ok := trigger.NextTriggerTime(first)
+	if !ok || !isMissed(second, until, inclusive) {
+		return []time.Time{first}
+	}
+
+	// 只需要最近的 limit 次触发, 根据触发间隔从 until 往前估算开始查找的时间, 避免间隔很短的任务停止很久后从 last 开始逐个计算.
+	// 间隔不固定时估算的范围内可能不足 limit 次触发, 这时扩大范围重新查找
+	window := time.Duration(limit+1) * second.Sub(first)
+	for {
+		start := until.Add(-window)
+		if !start.After(last) || window <= 0 {
+			start = last
+		}
+		missed := scanFireTimes(trigger, start, until, inclusive, limit)
+		if len(missed) >= limit || start.Equal(last) {
+			return missed
+		}
+		window *= 2
+	}
+}
+
+// 从 start 之后开始查找到 until 之间的触发时间, 只保留最近的 limit 次
+func scanFireTimes(trigger ITrigger, start, until time.Time, inclusive bool, limit int) []time.Time {
+	var missed []time.Time
+	t := start
+	for i := 0; i < maxMisfireScan; i++ {
+		next, ok := trigger.NextTriggerTime(t)
+		if !ok || !isMissed(next, until, inclusive) {
+			break
+		}
+		missed = append(missed, next)
+		if len(missed) > limit {
+			missed = missed[1:]
+		}
+		t = next
+	}
+	return missed
+}
+
+// 触发时间是否在 until 之前
+func isMissed(t, until time.Time, inclusive bool) bool {
+	return t.Before(until) || (inclusive && t.Equal(until))
+}
@@ -0,0 +1,243 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMissedFireTimes(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	newTask := func(name string, policy MisfirePolicy, limit int) ITask {
+		return NewTaskOfConfig(name, TaskConfig{
+			Trigger:      NewCronTrigger("* * * * * *"),
+			Executor:     NewExecutor(0, 0, 1),
+			Handler:      func(ctx IContext) error { return nil },
+			Enable:       true,
+			Misfire:      policy,
+			MisfireLimit: limit,
+		})
+	}
+
+	base := time.Unix(time.Now().Unix(), 0)
+	until := base.Add(10 * time.Second)
+	tests := []struct {
+		task      ITask
+		inclusive bool
+		expect    []time.Duration // 相对于 base 的偏移
+	}{
+		{newTask("skip", MisfireSkip, 0), true, nil},
+		{newTask("once", MisfireFireOnce, 0), true, []time.Duration{10}},
+		{newTask("once_exclusive", MisfireFireOnce, 0), false, []time.Duration{9}},
+		{newTask("all", MisfireFireAll, 3), true, []time.Duration{8, 9, 10}},
+		{newTask("all_default", MisfireFireAll, 0), false, []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+	}
+	for _, test := range tests {
+		c.fireTimes[test.task.Name()] = base
+		missed := c.missedFireTimes(test.task, until, test.inclusive)
+		if len(missed) != len(test.expect) {
+			t.Fatalf("%s 错过的触发应该有%d次, 实际为%v", test.task.Name(), len(test.expect), missed)
+		}
+		for i, d := range test.expect {
+			if !missed[i].Equal(base.Add(d * time.Second)) {
+				t.Fatalf("%s 第%d次错过的触发时间错误: %v", test.task.Name(), i+1, missed)
+			}
+		}
+	}
+
+	// 从来没有执行过的任务不需要补偿
+	task := newTask("never", MisfireFireAll, 0)
+	if missed := c.missedFireTimes(task, until, true); len(missed) != 0 {
+		t.Fatalf("从来没有执行过的任务不应该补偿: %v", missed)
+	}
+}
+
+// 保存后发送通知的执行记录存储
+type notifyHistoryStore struct {
+	IHistoryStore
+	saved chan struct{}
+}
+
+func (n *notifyHistoryStore) Save(record *RunRecord) error {
+	err := n.IHistoryStore.Save(record)
+	n.saved <- struct{}{}
+	return err
+}
+
+func TestMisfireAfterRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cron_misfire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileStore, err := NewFileHistoryStore(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &notifyHistoryStore{IHistoryStore: fileStore, saved: make(chan struct{}, 10)}
+	SetHistoryStore(store)
+	defer SetHistoryStore(nil)
+
+	// 停止前最后一次执行是3分钟前
+	now := time.Now()
+	last := now.Truncate(time.Minute).Add(-3 * time.Minute)
+	err = fileStore.Save(&RunRecord{TaskName: "restart", Source: RunBySchedule, FireTime: last, StartTime: last, EndTime: last.Add(time.Second), Attempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{}, 10)
+	task := NewTaskOfConfig("restart", TaskConfig{
+		Trigger:  NewCronTrigger("* * * * *"),
+		Executor: NewExecutor(0, 0, 1),
+		Handler: func(ctx IContext) error {
+			done <- struct{}{}
+			return nil
+		},
+		Enable:  true,
+		Misfire: MisfireFireOnce,
+	})
+	c := NewCronService(getTestApp()).(*CronService)
+	c.AddTask(task)
+	c.resetClock()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("应该补偿错过的触发")
+	}
+	select {
+	case <-store.saved: // 等待保存执行结果
+	case <-time.After(time.Second):
+		t.Fatal("应该保存执行结果")
+	}
+
+	records := c.History("restart", 0)
+	if len(records) != 2 {
+		t.Fatalf("应该有2条记录, 实际为%d条", len(records))
+	}
+	r := records[0]
+	expect := now.Truncate(time.Minute)
+	if r.Source != RunByMisfire || !r.FireTime.Equal(expect) {
+		t.Fatalf("补偿的记录错误, 计划触发时间应该是 %s: %+v", expect, r)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	_ = c.Start()
+	defer c.Close()
+
+	c.Pause()
+	if c.RunState() != PausedState {
+		t.Fatalf("状态应该是 %s, 实际为 %s", PausedState, c.RunState())
+	}
+	c.Resume()
+	if c.RunState() != StartedState {
+		t.Fatalf("状态应该是 %s, 实际为 %s", StartedState, c.RunState())
+	}
+}
+
+// 记录计算次数的触发器
+type countTrigger struct {
+	ITrigger
+	count int
+}
+
+func (c *countTrigger) NextTriggerTime(t time.Time) (time.Time, bool) {
+	c.count++
+	return c.ITrigger.NextTriggerTime(t)
+}
+
+func TestMissedFireTimesLongPause(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	until := time.Date(2021, 3, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		expression string
+		last       time.Time
+		expect     []time.Time
+	}{
+		// 每秒执行的任务暂停了一天
+		{"every_second", "* * * * * *", until.Add(-24 * time.Hour),
+			[]time.Time{until.Add(-2 * time.Second), until.Add(-time.Second), until}},
+		// 间隔不固定时需要扩大查找范围
+		{"irregular", "0 9,10 * * *", until.Add(-30 * 24 * time.Hour),
+			[]time.Time{
+				time.Date(2021, 2, 28, 10, 0, 0, 0, time.Local),
+				time.Date(2021, 3, 1, 9, 0, 0, 0, time.Local),
+				time.Date(2021, 3, 1, 10, 0, 0, 0, time.Local),
+			}},
+	}
+	for _, test := range tests {
+		trigger := &countTrigger{ITrigger: NewCronTrigger(test.expression)}
+		task := NewTaskOfConfig(test.name, TaskConfig{
+			Trigger:      trigger,
+			Executor:     NewExecutor(0, 0, 1),
+			Handler:      func(ctx IContext) error { return nil },
+			Enable:       true,
+			Misfire:      MisfireFireAll,
+			MisfireLimit: 3,
+		})
+		c.fireTimes[test.name] = test.last
+		missed := c.missedFireTimes(task, until, true)
+		if len(missed) != len(test.expect) {
+			t.Fatalf("%s 错过的触发错误: %v", test.name, missed)
+		}
+		for i := range missed {
+			if !missed[i].Equal(test.expect[i]) {
+				t.Fatalf("%s 错过的触发错误: %v", test.name, missed)
+			}
+		}
+		if trigger.count > 100 {
+			t.Fatalf("%s 计算了%d次触发时间, 应该只计算最近的触发", test.name, trigger.count)
+		}
+	}
+}
+
+func TestMisfireAfterFailure(t *testing.T) {
+	c := NewCronService(getTestApp()).(*CronService)
+	task := NewTaskOfConfig("failure", TaskConfig{
+		Trigger:  NewCronTrigger("* * * * * *"),
+		Executor: NewExecutor(0, 0, 1),
+		Handler: func(ctx IContext) error {
+			return errors.New("failed")
+		},
+		Enable:  true,
+		Misfire: MisfireFireOnce,
+	})
+	c.AddTask(task)
+
+	base := time.Unix(time.Now().Unix(), 0)
+	c.fireTimes[task.Name()] = base
+
+	// 分发之后执行结束之前不会重复补偿
+	fireTime := base.Add(time.Second)
+	c.pendingFireTimes[task.Name()] = fireTime
+	if missed := c.missedFireTimes(task, fireTime.Add(time.Second), false); len(missed) != 0 {
+		t.Fatalf("正在执行的触发不应该补偿: %v", missed)
+	}
+
+	// 执行失败的触发也已经执行过, 更新补偿的起点, 不会重复执行
+	_ = c.execute(task, taskFire{fireTime: fireTime, source: RunBySchedule})
+	if _, ok := c.pendingFireTimes[task.Name()]; ok {
+		t.Fatal("执行结束后应该删除正在执行的触发")
+	}
+	if !c.fireTimes[task.Name()].Equal(fireTime) {
+		t.Fatalf("执行失败后补偿的起点应该是 %s, 实际为 %s", fireTime, c.fireTimes[task.Name()])
+	}
+	if missed := c.missedFireTimes(task, fireTime.Add(time.Second), false); len(missed) != 0 {
+		t.Fatalf("执行失败的触发不应该补偿: %v", missed)
+	}
+
+	// 从执行记录中查找时执行失败的触发也算执行过, 服务关闭时被取消的不算
+	delete(c.fireTimes, task.Name())
+	canceled := c.beginRecord(task, RunBySchedule, fireTime.Add(time.Second), "")
+	c.endRecord(newContext(context.Background(), c.app, task, "", nil), canceled, 1, makeCanceledByShutdownError(context.Canceled))
+	if last := c.lastCompletedFireTime(task); !last.Equal(fireTime) {
+		t.Fatalf("补偿的起点应该是 %s, 实际为 %s", fireTime, last)
+	}
+}
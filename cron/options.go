@@ -0,0 +1,23 @@
+package cron
+
+type options struct {
+	Reporter ErrorReporter // 错误上报器
+}
+
+// 服务选项
+type Option func(o *options)
+
+func newOptions(opts ...Option) *options {
+	o := &options{}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// 设置错误上报器, 任务执行时产生的panic和重试后最终失败的错误会上报到这里
+func WithErrorReporter(r ErrorReporter) Option {
+	return func(o *options) {
+		o.Reporter = r
+	}
+}
@@ -0,0 +1,70 @@
+package cron
+
+import (
+	"fmt"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 秒字段模式
+type SecondsMode int
+
+const (
+	// 秒字段可选, 表达式有6个字段时第一个字段为秒, 5个字段时秒为0
+	SecondsOptional SecondsMode = iota
+	// 必须有秒字段
+	SecondsRequired
+	// 不支持秒字段, 和标准cron表达式相同
+	SecondsNone
+)
+
+func (m SecondsMode) String() string {
+	switch m {
+	case SecondsOptional:
+		return "optional"
+	case SecondsRequired:
+		return "required"
+	case SecondsNone:
+		return "none"
+	}
+	return fmt.Sprintf("undefined seconds mode: %d", m)
+}
+
+// cron表达式解析器配置
+type CronParserConfig struct {
+	// 秒字段模式, 默认秒字段可选
+	Seconds SecondsMode
+	// 禁用描述符, 如 @every 30s, @daily
+	DisableDescriptor bool
+}
+
+// cron表达式解析器
+//
+// 表达式可以使用 CRON_TZ= 或 TZ= 前缀指定时区, 如 CRON_TZ=Asia/Shanghai 0 3 * * *
+type ICronParser = cron.ScheduleParser
+
+var defaultCronParser = NewCronParser(CronParserConfig{})
+
+// 创建一个cron表达式解析器
+func NewCronParser(conf CronParserConfig) ICronParser {
+	options := cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow
+	switch conf.Seconds {
+	case SecondsRequired:
+		options |= cron.Second
+	case SecondsNone:
+	default:
+		options |= cron.SecondOptional
+	}
+	if !conf.DisableDescriptor {
+		options |= cron.Descriptor
+	}
+	return cron.NewParser(options)
+}
+
+// 设置默认的cron表达式解析器, 会影响之后创建的cron触发器
+func SetCronParser(p ICronParser) {
+	if p == nil {
+		panic("cron parser is nil")
+	}
+	defaultCronParser = p
+}
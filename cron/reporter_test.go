@@ -0,0 +1,41 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type testReporter struct {
+	fields chan map[string]string
+}
+
+func (r *testReporter) Report(ctx context.Context, err error, stack string, fields map[string]string) {
+	r.fields <- fields
+}
+
+func TestErrorReporter(t *testing.T) {
+	r := &testReporter{fields: make(chan map[string]string, 1)}
+	c := NewCronService(getTestApp(), WithErrorReporter(r)).(*CronService)
+	c.AddTask(NewTask("a", "0 0 0 1 1 *", true, func(ctx IContext) error { return errors.New("failed") }))
+	c.AddTask(NewTask("b", "0 0 0 1 1 *", true, func(ctx IContext) error { panic("boom") }))
+
+	for _, test := range []struct {
+		name string
+		kind string
+	}{
+		{"a", "error"},
+		{"b", "panic"},
+	} {
+		_ = c.TriggerTask(test.name)
+		select {
+		case fields := <-r.fields:
+			if fields["kind"] != test.kind || fields["task_name"] != test.name || fields["service"] != string(nowServiceType) {
+				t.Fatalf("上报的字段错误: %v", fields)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("任务 %s 的错误没有上报", test.name)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package cron
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// 重试退避策略
+type IBackoff interface {
+	// 返回第 attempt 次执行失败后等待的时间, attempt 从1开始
+	Next(attempt int) time.Duration
+}
+
+// 固定间隔
+type constantBackoff time.Duration
+
+// 创建一个固定间隔的退避策略
+func ConstantBackoff(interval time.Duration) IBackoff {
+	return constantBackoff(interval)
+}
+
+func (c constantBackoff) Next(int) time.Duration {
+	return time.Duration(c)
+}
+
+// 指数退避
+type ExponentialBackoff struct {
+	// 初始间隔
+	Initial time.Duration
+	// 最大间隔, 0表示不限制
+	Max time.Duration
+	// 倍数, 默认为2
+	Multiplier float64
+	// 抖动比例, 范围为 [0, 1], 实际间隔在 [d*(1-Jitter), d*(1+Jitter)] 之间随机, 用于避免多个任务同时重试
+	Jitter float64
+}
+
+// 创建一个指数退避策略, 第n次失败后等待 initial * 2^(n-1), 最大为 max, 并加上 jitter 比例的随机抖动
+func NewExponentialBackoff(initial, max time.Duration, jitter float64) IBackoff {
+	return &ExponentialBackoff{
+		Initial:    initial,
+		Max:        max,
+		Multiplier: 2,
+		Jitter:     jitter,
+	}
+}
+
+func (e *ExponentialBackoff) Next(attempt int) time.Duration {
+	multiplier := e.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := float64(e.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if e.Max > 0 && d > float64(e.Max) {
+		d = float64(e.Max)
+	}
+	if jitter := math.Min(math.Max(e.Jitter, 0), 1); jitter > 0 {
+		d = d * (1 - jitter + 2*jitter*rand.Float64())
+	}
+	if e.Max > 0 && d > float64(e.Max) {
+		d = float64(e.Max)
+	}
+	return time.Duration(d)
+}
+
+// 判断错误是否需要重试
+type RetryPredicate func(err error) bool
+
+// 不会重试的错误
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+// 包装一个不会重试的错误, 如参数错误. err 为nil时返回nil
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// 是否是通过 Permanent 包装的不会重试的错误
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,99 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := NewExponentialBackoff(time.Second, 5*time.Second, 0)
+	expect := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, d := range expect {
+		if got := b.Next(i + 1); got != d {
+			t.Fatalf("第%d次失败后应该等待 %s, 实际为 %s", i+1, d, got)
+		}
+	}
+
+	b = NewExponentialBackoff(time.Second, 10*time.Second, 0.5)
+	for i := 0; i < 100; i++ {
+		if got := b.Next(2); got < time.Second || got > 3*time.Second {
+			t.Fatalf("抖动后的间隔应该在 [1s, 3s] 之间, 实际为 %s", got)
+		}
+		if got := b.Next(10); got > 10*time.Second {
+			t.Fatalf("抖动后的间隔不应该超过最大间隔, 实际为 %s", got)
+		}
+	}
+}
+
+func testRetry(executor IExecutor, ctx context.Context, handler Handler) (attempts []int, err error) {
+	task := NewTaskOfConfig("retry", TaskConfig{
+		Trigger:  NewCronTrigger("@every 1h"),
+		Executor: executor,
+		Handler: func(ctx IContext) error {
+			attempts = append(attempts, ctx.Attempt())
+			return handler(ctx)
+		},
+		Enable: true,
+	})
+	err = task.Trigger(newContext(ctx, getTestApp(), task, "", nil), nil)
+	return attempts, err
+}
+
+func TestExecutorRetry(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errFatal := errors.New("fatal")
+
+	// 重试直到成功, 可以获取到执行次数
+	executor := NewExecutorOfConfig(ExecutorConfig{RetryCount: 5, Backoff: NewExponentialBackoff(time.Millisecond, 0, 0)})
+	attempts, err := testRetry(executor, context.Background(), func(ctx IContext) error {
+		if ctx.Attempt() < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+	if err != nil || len(attempts) != 3 || attempts[2] != 3 {
+		t.Fatalf("应该执行3次后成功: %v, %v", attempts, err)
+	}
+
+	// Permanent 的错误不会重试
+	attempts, err = testRetry(executor, context.Background(), func(ctx IContext) error {
+		return Permanent(errFatal)
+	})
+	if len(attempts) != 1 || !IsPermanent(err) || !errors.Is(err, errFatal) {
+		t.Fatalf("Permanent 的错误不应该重试: %v, %v", attempts, err)
+	}
+
+	// 重试判断函数
+	executor = NewExecutorOfConfig(ExecutorConfig{
+		RetryCount: 5,
+		RetryIf:    func(err error) bool { return err == errTemporary },
+	})
+	attempts, err = testRetry(executor, context.Background(), func(ctx IContext) error {
+		if ctx.Attempt() == 1 {
+			return errTemporary
+		}
+		return errFatal
+	})
+	if len(attempts) != 2 || err != errFatal {
+		t.Fatalf("不需要重试的错误应该直接返回: %v, %v", attempts, err)
+	}
+}
+
+func TestExecutorRetryCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	executor := NewExecutorOfConfig(ExecutorConfig{RetryCount: 5, Backoff: ConstantBackoff(time.Hour)})
+	start := time.Now()
+	attempts, err := testRetry(executor, ctx, func(ctx IContext) error {
+		return errors.New("fail")
+	})
+	if time.Since(start) > time.Second {
+		t.Fatal("取消后应该停止等待重试")
+	}
+	if len(attempts) != 1 || err == nil {
+		t.Fatalf("取消后不应该再重试: %v, %v", attempts, err)
+	}
+}
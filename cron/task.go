@@ -1,136 +1,200 @@
-package cron
-
-import (
-	"sync"
-	"sync/atomic"
-	"time"
-)
-
-type ITask interface {
-	// 返回任务名
-	Name() string
-	// 获取handler
-	Handler() Handler
-	// 返回启用状态
-	IsEnable() bool
-
-	// 获取触发时间
-	TriggerTime() time.Time
-	// 生成下次触发时间, 如果返回了 false 表示没有下一次了, 返回的时间一定>t
-	MakeNextTriggerTime(t time.Time) (time.Time, bool)
-	// 立即触发执行, 阻塞等待执行结束
-	Trigger(ctx IContext, callback ErrCallback) error
-
-	// 重置定时, 发生在被定时器添加任务时和重新设为启用时
-	resetClock()
-	// 设置启用
-	setEnable(enable bool)
-	// 设置堆索引
-	setHeapIndex(index int)
-	// 获取堆索引
-	getHeapIndex() int
-}
-
-type Task struct {
-	name string
-
-	handler Handler
-
-	triggerTime time.Time
-	trigger     ITrigger
-	executor    IExecutor
-
-	enable int32
-	mx     sync.Mutex // 用于锁 triggerTime, trigger, executor
-
-	heapIndex int // 堆索引
-}
-
-type TaskConfig struct {
-	Trigger  ITrigger
-	Executor IExecutor
-	Handler  Handler
-	Enable   bool
-}
-
-// 创建一个任务
-func NewTask(name string, expression string, enable bool, handler Handler) ITask {
-	trigger := NewCronTrigger(expression)
-	executor := NewExecutor(0, 0, 1)
-	return NewTaskOfConfig(name, TaskConfig{
-		Trigger:  trigger,
-		Executor: executor,
-		Handler:  handler,
-		Enable:   enable,
-	})
-}
-
-// 根据任务配置创建一个任务
-func NewTaskOfConfig(name string, config TaskConfig) ITask {
-	t := &Task{
-		name:     name,
-		trigger:  config.Trigger,
-		executor: config.Executor,
-		handler:  config.Handler,
-	}
-	t.setEnable(config.Enable)
-	return t
-}
-
-func (t *Task) Name() string {
-	return t.name
-}
-func (t *Task) Handler() Handler {
-	return t.handler
-}
-func (t *Task) IsEnable() bool {
-	return atomic.LoadInt32(&t.enable) == 1
-}
-func (t *Task) TriggerTime() time.Time {
-	t.mx.Lock()
-	tt := t.triggerTime
-	t.mx.Unlock()
-	return tt
-}
-func (t *Task) MakeNextTriggerTime(tt time.Time) (time.Time, bool) {
-	if !t.IsEnable() {
-		return tt, false
-	}
-
-	tt, ok := t.trigger.MakeNextTriggerTime(tt)
-	t.mx.Lock()
-	t.triggerTime = tt
-	t.mx.Unlock()
-	return tt, ok
-}
-func (t *Task) Trigger(ctx IContext, callback ErrCallback) error {
-	return t.execute(ctx, callback)
-}
-
-// 执行
-func (t *Task) execute(ctx IContext, errCallback ErrCallback) error {
-	t.mx.Lock()
-	executor := t.executor
-	t.mx.Unlock()
-	return executor.Do(ctx, errCallback)
-}
-
-func (t *Task) resetClock() {
-	t.mx.Lock()
-	trigger := t.trigger
-	t.mx.Unlock()
-	trigger.ResetClock()
-}
-func (t *Task) setEnable(enable bool) {
-	if enable {
-		atomic.StoreInt32(&t.enable, 1)
-	} else {
-		atomic.StoreInt32(&t.enable, 0)
-	}
-}
-func (t *Task) setHeapIndex(index int) {
-	t.heapIndex = index
-}
-func (t *Task) getHeapIndex() int {
-	return t.heapIndex
-}
+package cron
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ITask interface {
+	// 返回任务名
+	Name() string
+	// 获取handler
+	Handler() Handler
+	// 返回启用状态
+	IsEnable() bool
+	// 返回加锁模式
+	LockMode() LockMode
+	// 获取触发器
+	GetTrigger() ITrigger
+	// 返回错过触发的处理策略和最多补偿的触发次数
+	Misfire() (MisfirePolicy, int)
+	// 返回执行超时时间, 0表示不限制
+	Timeout() time.Duration
+	// 获取执行器
+	GetExecutor() IExecutor
+
+	// 获取触发时间
+	TriggerTime() time.Time
+	// 生成下次触发时间, 如果返回了 false 表示没有下一次了, 返回的时间一定>t
+	MakeNextTriggerTime(t time.Time) (time.Time, bool)
+	// 立即触发执行, 阻塞等待执行结束
+	Trigger(ctx IContext, callback ErrCallback) error
+
+	// 重置定时, 发生在被定时器添加任务时和重新设为启用时
+	resetClock()
+	// 设置启用
+	setEnable(enable bool)
+	// 替换触发器, 执行器和handler, 发生在修改配置声明的任务时
+	reconfigure(trigger ITrigger, executor IExecutor, handler Handler)
+	// 设置堆索引
+	setHeapIndex(index int)
+	// 获取堆索引
+	getHeapIndex() int
+}
+
+type Task struct {
+	name string
+
+	handler Handler
+
+	triggerTime time.Time
+	trigger     ITrigger
+	executor    IExecutor
+
+	lockMode     LockMode
+	misfire      MisfirePolicy
+	misfireLimit int
+	timeout      time.Duration
+
+	enable int32
+	mx     sync.Mutex // 用于锁 triggerTime, trigger, executor, handler
+
+	heapIndex int // 堆索引
+}
+
+type TaskConfig struct {
+	Trigger  ITrigger
+	Executor IExecutor
+	Handler  Handler
+	Enable   bool
+	// 加锁模式, 默认使用服务配置的加锁模式
+	LockMode LockMode
+	// 错过触发的处理策略, 默认跳过
+	Misfire MisfirePolicy
+	// 策略为 MisfireFireAll 时最多补偿的触发次数, 默认为10
+	MisfireLimit int
+	// 执行超时时间, 包含重试的时间, 超时后 IContext.Ctx() 会被取消, 0表示不限制
+	Timeout time.Duration
+}
+
+// 创建一个任务
+func NewTask(name string, expression string, enable bool, handler Handler) ITask {
+	trigger := NewCronTrigger(expression)
+	executor := NewExecutor(0, 0, 1)
+	return NewTaskOfConfig(name, TaskConfig{
+		Trigger:  trigger,
+		Executor: executor,
+		Handler:  handler,
+		Enable:   enable,
+	})
+}
+
+// 根据任务配置创建一个任务
+func NewTaskOfConfig(name string, config TaskConfig) ITask {
+	t := &Task{
+		name:     name,
+		trigger:  config.Trigger,
+		executor: config.Executor,
+		handler:  config.Handler,
+		lockMode: config.LockMode,
+
+		misfire:      config.Misfire,
+		misfireLimit: config.MisfireLimit,
+		timeout:      config.Timeout,
+	}
+	if t.misfireLimit <= 0 {
+		t.misfireLimit = defaultMisfireLimit
+	}
+	t.setEnable(config.Enable)
+	return t
+}
+
+func (t *Task) Name() string {
+	return t.name
+}
+func (t *Task) Handler() Handler {
+	t.mx.Lock()
+	handler := t.handler
+	t.mx.Unlock()
+	return handler
+}
+func (t *Task) IsEnable() bool {
+	return atomic.LoadInt32(&t.enable) == 1
+}
+func (t *Task) LockMode() LockMode {
+	return t.lockMode
+}
+func (t *Task) Misfire() (MisfirePolicy, int) {
+	return t.misfire, t.misfireLimit
+}
+func (t *Task) Timeout() time.Duration {
+	return t.timeout
+}
+func (t *Task) GetExecutor() IExecutor {
+	t.mx.Lock()
+	executor := t.executor
+	t.mx.Unlock()
+	return executor
+}
+func (t *Task) GetTrigger() ITrigger {
+	t.mx.Lock()
+	trigger := t.trigger
+	t.mx.Unlock()
+	return trigger
+}
+func (t *Task) TriggerTime() time.Time {
+	t.mx.Lock()
+	tt := t.triggerTime
+	t.mx.Unlock()
+	return tt
+}
+func (t *Task) MakeNextTriggerTime(tt time.Time) (time.Time, bool) {
+	if !t.IsEnable() {
+		return tt, false
+	}
+
+	tt, ok := t.trigger.MakeNextTriggerTime(tt)
+	t.mx.Lock()
+	t.triggerTime = tt
+	t.mx.Unlock()
+	return tt, ok
+}
+func (t *Task) Trigger(ctx IContext, callback ErrCallback) error {
+	return t.execute(ctx, callback)
+}
+
+// 执行
+func (t *Task) execute(ctx IContext, errCallback ErrCallback) error {
+	t.mx.Lock()
+	executor := t.executor
+	t.mx.Unlock()
+	return executor.Do(ctx, errCallback)
+}
+
+func (t *Task) resetClock() {
+	t.mx.Lock()
+	trigger := t.trigger
+	t.mx.Unlock()
+	trigger.ResetClock()
+}
+func (t *Task) setEnable(enable bool) {
+	if enable {
+		atomic.StoreInt32(&t.enable, 1)
+	} else {
+		atomic.StoreInt32(&t.enable, 0)
+	}
+}
+func (t *Task) reconfigure(trigger ITrigger, executor IExecutor, handler Handler) {
+	t.mx.Lock()
+	t.trigger = trigger
+	t.executor = executor
+	t.handler = handler
+	t.mx.Unlock()
+}
+func (t *Task) setHeapIndex(index int) {
+	t.heapIndex = index
+}
+func (t *Task) getHeapIndex() int {
+	return t.heapIndex
+}
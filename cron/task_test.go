@@ -1,20 +1,35 @@
-package cron
-
-import (
-	"fmt"
-	"testing"
-
-	"github.com/zly-app/zapp"
-)
-
-func TestTask(t *testing.T) {
-	task := NewTask("test", "@every 1s", true, func(ctx IContext) (err error) {
-		fmt.Println("触发")
-		return nil
-	})
-	app := zapp.NewApp("cron")
-	err := task.Trigger(newContext(app, task), nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-}
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+)
+
+var (
+	testApp     core.IApp
+	testAppOnce sync.Once
+)
+
+// 获取测试用的app, 一个进程只能创建一个app
+func getTestApp() core.IApp {
+	testAppOnce.Do(func() {
+		testApp = zapp.NewApp("cron")
+	})
+	return testApp
+}
+
+func TestTask(t *testing.T) {
+	task := NewTask("test", "@every 1s", true, func(ctx IContext) (err error) {
+		fmt.Println("触发")
+		return nil
+	})
+	err := task.Trigger(newContext(context.Background(), getTestApp(), task, "", nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
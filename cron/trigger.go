@@ -1,121 +1,212 @@
-package cron
-
-import (
-	"fmt"
-	"sync"
-	"time"
-
-	"github.com/robfig/cron/v3"
-)
-
-// 触发器类型
-type TriggerType int
-
-const (
-	// Cron触发器
-	CronTriggerType TriggerType = iota
-	// 一次性触发器
-	OnceTriggerType
-)
-
-func (t TriggerType) String() string {
-	switch t {
-	case CronTriggerType:
-		return "cron"
-	case OnceTriggerType:
-		return "once"
-	}
-	return fmt.Sprintf("undefined trigger type: %d", t)
-}
-
-type ITrigger interface {
-	// 触发器类型
-	TriggerType() TriggerType
-	// 返回触发器表达式
-	Expression() string
-
-	// 重置定时器
-	ResetClock()
-	// 生成下次触发时间, 如果返回了 false 表示没有下一次了, 返回的时间一定>t
-	MakeNextTriggerTime(t time.Time) (time.Time, bool)
-}
-
-// -------------- cron触发器 --------------------
-
-// cron触发器
-type CronTrigger struct {
-	expression      string
-	schedule        cron.Schedule
-	nextExecuteTime time.Time
-	mx              sync.Mutex // 用于锁 nextExecuteTime
-}
-
-// 创建一个cron触发器
-func NewCronTrigger(expression string) ITrigger {
-	schedule, err := cron.ParseStandard(expression)
-	if err != nil {
-		panic(fmt.Errorf("expression syntax error, %s", err))
-	}
-
-	return &CronTrigger{
-		expression:      expression,
-		schedule:        schedule,
-		nextExecuteTime: schedule.Next(time.Now()),
-	}
-}
-
-func (c *CronTrigger) TriggerType() TriggerType {
-	return CronTriggerType
-}
-func (c *CronTrigger) Expression() string {
-	return c.expression
-}
-
-func (c *CronTrigger) ResetClock() {
-	c.mx.Lock()
-	c.nextExecuteTime = c.schedule.Next(time.Now())
-	c.mx.Unlock()
-}
-func (c *CronTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
-	c.mx.Lock()
-	for t.Unix() >= c.nextExecuteTime.Unix() {
-		c.nextExecuteTime = c.schedule.Next(c.nextExecuteTime)
-	}
-	t = c.nextExecuteTime
-	c.mx.Unlock()
-	return t, true
-}
-
-// --------------- 一次性触发器 -------------------
-
-const OnceTriggerTimeLayout = "2006-01-02 15:04:05" // 一次性触发器时间样式
-
-// 一次性触发器
-type OnceTrigger struct {
-	expression  string
-	executeTime time.Time
-}
-
-func NewOnceTrigger(t time.Time) ITrigger {
-	o := &OnceTrigger{
-		expression:  t.Format(OnceTriggerTimeLayout),
-		executeTime: t,
-	}
-	return o
-}
-
-func (o *OnceTrigger) TriggerType() TriggerType {
-	return OnceTriggerType
-}
-func (o *OnceTrigger) Expression() string {
-	return o.expression
-}
-
-func (o *OnceTrigger) ResetClock() {
-}
-func (o *OnceTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
-	if t.Unix() < o.executeTime.Unix() {
-		return o.executeTime, true
-	}
-	return t, false
-}
+package cron
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 触发器类型
+type TriggerType int
+
+const (
+	// Cron触发器
+	CronTriggerType TriggerType = iota
+	// 一次性触发器
+	OnceTriggerType
+	// 依赖触发器
+	DependTriggerType
+)
+
+func (t TriggerType) String() string {
+	switch t {
+	case CronTriggerType:
+		return "cron"
+	case OnceTriggerType:
+		return "once"
+	case DependTriggerType:
+		return "depend"
+	}
+	return fmt.Sprintf("undefined trigger type: %d", t)
+}
+
+type ITrigger interface {
+	// 触发器类型
+	TriggerType() TriggerType
+	// 返回触发器表达式, cron触发器返回创建时的原始表达式, 通过配置设置了时区时会加上 CRON_TZ= 前缀
+	Expression() string
+	// 返回触发器的时区
+	Location() *time.Location
+
+	// 重置定时器
+	ResetClock()
+	// 生成下次触发时间, 如果返回了 false 表示没有下一次了, 返回的时间一定>t
+	MakeNextTriggerTime(t time.Time) (time.Time, bool)
+	// 计算t之后的下次触发时间, 不会修改触发器的状态, 如果返回了 false 表示没有下一次了
+	NextTriggerTime(t time.Time) (time.Time, bool)
+}
+
+// -------------- cron触发器 --------------------
+
+// cron触发器
+type CronTrigger struct {
+	expression      string
+	location        *time.Location
+	schedule        cron.Schedule
+	nextExecuteTime time.Time
+	mx              sync.Mutex // 用于锁 nextExecuteTime
+}
+
+// cron触发器配置
+type CronTriggerConfig struct {
+	// 表达式解析器, 默认使用 SetCronParser 设置的解析器
+	Parser ICronParser
+	// 时区, 表达式有 CRON_TZ= 前缀时使用前缀的时区, 默认为本地时区
+	Location *time.Location
+	// 夏令时策略, 默认跳过
+	DST DSTPolicy
+}
+
+// 创建一个cron触发器, 表达式错误会panic
+func NewCronTrigger(expression string) ITrigger {
+	trigger, err := ParseCronTrigger(expression)
+	if err != nil {
+		panic(err)
+	}
+	return trigger
+}
+
+// 使用默认的解析器解析表达式并创建一个cron触发器
+func ParseCronTrigger(expression string) (ITrigger, error) {
+	return NewCronTriggerOfConfig(expression, CronTriggerConfig{})
+}
+
+// 使用指定的解析器解析表达式并创建一个cron触发器
+func ParseCronTriggerWithParser(expression string, parser ICronParser) (ITrigger, error) {
+	return NewCronTriggerOfConfig(expression, CronTriggerConfig{Parser: parser})
+}
+
+// 根据配置创建一个cron触发器
+func NewCronTriggerOfConfig(expression string, conf CronTriggerConfig) (ITrigger, error) {
+	parser := conf.Parser
+	if parser == nil {
+		parser = defaultCronParser
+	}
+	schedule, err := parser.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("expression syntax error, %s", err)
+	}
+
+	// 表达式没有时区前缀时使用配置的时区
+	location := time.Local
+	hasTZ := hasTZPrefix(expression)
+	if spec, ok := schedule.(*cron.SpecSchedule); ok {
+		if !hasTZ && conf.Location != nil {
+			spec.Location = conf.Location
+		}
+		location = spec.Location
+	} else if conf.Location != nil {
+		location = conf.Location
+	}
+	if !hasTZ && conf.Location != nil {
+		expression = "CRON_TZ=" + conf.Location.String() + " " + expression
+	}
+
+	schedule = wrapDSTSchedule(schedule, conf.DST)
+	return &CronTrigger{
+		expression:      expression,
+		location:        location,
+		schedule:        schedule,
+		nextExecuteTime: schedule.Next(time.Now()),
+	}, nil
+}
+
+// 表达式是否有时区前缀
+func hasTZPrefix(expression string) bool {
+	return strings.HasPrefix(expression, "CRON_TZ=") || strings.HasPrefix(expression, "TZ=")
+}
+
+func (c *CronTrigger) TriggerType() TriggerType {
+	return CronTriggerType
+}
+func (c *CronTrigger) Expression() string {
+	return c.expression
+}
+func (c *CronTrigger) Location() *time.Location {
+	return c.location
+}
+
+func (c *CronTrigger) ResetClock() {
+	c.mx.Lock()
+	c.nextExecuteTime = c.schedule.Next(time.Now())
+	c.mx.Unlock()
+}
+func (c *CronTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
+	c.mx.Lock()
+	for !c.nextExecuteTime.IsZero() && t.Unix() >= c.nextExecuteTime.Unix() {
+		c.nextExecuteTime = c.schedule.Next(c.nextExecuteTime)
+	}
+	if c.nextExecuteTime.IsZero() { // 表达式永远不会触发
+		c.mx.Unlock()
+		return t, false
+	}
+	t = c.nextExecuteTime
+	c.mx.Unlock()
+	return t, true
+}
+func (c *CronTrigger) NextTriggerTime(t time.Time) (time.Time, bool) {
+	next := c.schedule.Next(t)
+	return next, !next.IsZero()
+}
+
+// --------------- 一次性触发器 -------------------
+
+const OnceTriggerTimeLayout = "2006-01-02 15:04:05" // 一次性触发器时间样式
+
+// 一次性触发器
+type OnceTrigger struct {
+	expression  string
+	executeTime time.Time
+}
+
+// 创建一个一次性触发器, 表达式为时间和时区, 如 2021-01-01 08:00:00 Asia/Shanghai
+func NewOnceTrigger(t time.Time) ITrigger {
+	o := &OnceTrigger{
+		expression:  t.Format(OnceTriggerTimeLayout) + " " + zoneName(t),
+		executeTime: t,
+	}
+	return o
+}
+
+func (o *OnceTrigger) TriggerType() TriggerType {
+	return OnceTriggerType
+}
+func (o *OnceTrigger) Expression() string {
+	return o.expression
+}
+func (o *OnceTrigger) Location() *time.Location {
+	return o.executeTime.Location()
+}
+
+func (o *OnceTrigger) ResetClock() {
+}
+func (o *OnceTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
+	if t.Unix() < o.executeTime.Unix() {
+		return o.executeTime, true
+	}
+	return t, false
+}
+func (o *OnceTrigger) NextTriggerTime(t time.Time) (time.Time, bool) {
+	return o.MakeNextTriggerTime(t)
+}
+
+// 获取时间的时区名, 本地时区没有名称时返回偏移量, 如 +08:00
+func zoneName(t time.Time) string {
+	if name := t.Location().String(); name != "Local" {
+		return name
+	}
+	return t.Format("-07:00")
+}
@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronTrigger(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("没有时区数据", err)
+	}
+
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	local := base.In(time.Local)
+	tests := []struct {
+		expression string
+		next       time.Time
+	}{
+		{"TZ=UTC */5 * * * *", base.Add(5 * time.Minute)},
+		{"TZ=UTC */30 * * * * *", base.Add(30 * time.Second)},
+		{"TZ=UTC 10 0 8 * * *", time.Date(2021, 1, 1, 8, 0, 10, 0, time.UTC)},
+		{"CRON_TZ=Asia/Shanghai 0 8 * * *", time.Date(2021, 1, 2, 8, 0, 0, 0, shanghai)},
+		{"CRON_TZ=Asia/Shanghai @daily", time.Date(2021, 1, 2, 0, 0, 0, 0, shanghai)},
+		{"@every 30s", local.Add(30 * time.Second)},
+		{"*/30 * * * * *", local.Truncate(30 * time.Second).Add(30 * time.Second)},
+	}
+	for _, test := range tests {
+		trigger, err := ParseCronTrigger(test.expression)
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %s", test.expression, err)
+		}
+		if trigger.Expression() != test.expression {
+			t.Fatalf("表达式应该是 %q, 实际为 %q", test.expression, trigger.Expression())
+		}
+		next := trigger.(*CronTrigger).schedule.Next(base)
+		if !next.Equal(test.next) {
+			t.Fatalf("%q 的下次触发时间应该是 %s, 实际为 %s", test.expression, test.next, next)
+		}
+	}
+
+	for _, expression := range []string{"", "* * *", "61 * * * *", "@unknown", "CRON_TZ=Unknown/Zone * * * * *"} {
+		if _, err := ParseCronTrigger(expression); err == nil {
+			t.Fatalf("解析 %q 应该返回错误", expression)
+		}
+	}
+}
+
+func TestCronParserSeconds(t *testing.T) {
+	required := NewCronParser(CronParserConfig{Seconds: SecondsRequired})
+	if _, err := ParseCronTriggerWithParser("0 * * * *", required); err == nil {
+		t.Fatal("必须有秒字段时5个字段的表达式应该返回错误")
+	}
+	if _, err := ParseCronTriggerWithParser("0 0 * * * *", required); err != nil {
+		t.Fatal(err)
+	}
+
+	none := NewCronParser(CronParserConfig{Seconds: SecondsNone, DisableDescriptor: true})
+	if _, err := ParseCronTriggerWithParser("0 0 * * * *", none); err == nil {
+		t.Fatal("不支持秒字段时6个字段的表达式应该返回错误")
+	}
+	if _, err := ParseCronTriggerWithParser("@every 1s", none); err == nil {
+		t.Fatal("禁用描述符时应该返回错误")
+	}
+	if _, err := ParseCronTriggerWithParser("0 * * * *", none); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,63 +1,71 @@
-/*
--------------------------------------------------
-   Author :       zlyuancn
-   date：         2021/1/23
-   Description :
--------------------------------------------------
-*/
-
-package cron
-
-import (
-	"time"
-
-	"github.com/zly-app/zapp"
-	"github.com/zly-app/zapp/core"
-	"github.com/zly-app/zapp/service"
-)
-
-// 默认服务类型
-const DefaultServiceType core.ServiceType = "cron"
-
-// 当前服务类型
-var nowServiceType = DefaultServiceType
-
-// 设置服务类型, 这个函数应该在 zapp.NewApp 之前调用
-func SetServiceType(t core.ServiceType) {
-	nowServiceType = t
-}
-
-// 启用cron服务
-func WithService() zapp.Option {
-	service.RegisterCreatorFunc(nowServiceType, func(app core.IApp) core.IService {
-		return NewCronService(app)
-	})
-	return zapp.WithService(nowServiceType)
-}
-
-// 注册cron的Handler
-func RegistryHandler(name string, expression string, enable bool, handler Handler) {
-	task := NewTaskOfConfig(name, TaskConfig{
-		Trigger:  NewCronTrigger(expression),
-		Executor: NewExecutor(0, 0, 1),
-		Handler:  handler,
-		Enable:   enable,
-	})
-	zapp.App().InjectService(nowServiceType, task)
-}
-
-// 注册一次性cron的Handler
-func RegistryOnceHandler(name string, t time.Time, enable bool, handler Handler) {
-	task := NewTaskOfConfig(name, TaskConfig{
-		Trigger:  NewOnceTrigger(t),
-		Executor: NewExecutor(0, 0, 1),
-		Handler:  handler,
-		Enable:   enable,
-	})
-	zapp.App().InjectService(nowServiceType, task)
-}
-
-// 注册自定义task
-func RegistryTask(task ITask) {
-	zapp.App().InjectService(nowServiceType, task)
-}
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2021/1/23
+   Description :
+-------------------------------------------------
+*/
+
+package cron
+
+import (
+	"time"
+
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+	"github.com/zly-app/zapp/service"
+	"go.uber.org/zap"
+)
+
+// 默认服务类型
+const DefaultServiceType core.ServiceType = "cron"
+
+// 当前服务类型
+var nowServiceType = DefaultServiceType
+
+// 设置服务类型, 这个函数应该在 zapp.NewApp 之前调用
+func SetServiceType(t core.ServiceType) {
+	nowServiceType = t
+}
+
+// 启用cron服务
+func WithService(opts ...Option) zapp.Option {
+	service.RegisterCreatorFunc(nowServiceType, func(app core.IApp) core.IService {
+		return NewCronService(app, opts...)
+	})
+	return zapp.WithService(nowServiceType)
+}
+
+// 注册cron的Handler, 表达式错误会结束app
+func RegistryHandler(name string, expression string, enable bool, handler Handler) {
+	trigger, err := ParseCronTrigger(expression)
+	if err != nil {
+		zapp.App().Fatal("注册Cron任务失败, 表达式错误", zap.String("name", name), zap.String("expression", expression), zap.Error(err))
+	}
+	task := NewTaskOfConfig(name, TaskConfig{
+		Trigger:  trigger,
+		Executor: NewExecutor(0, 0, 1),
+		Handler:  handler,
+		Enable:   enable,
+	})
+	zapp.App().InjectService(nowServiceType, task)
+}
+
+// 注册一次性cron的Handler
+func RegistryOnceHandler(name string, t time.Time, enable bool, handler Handler) {
+	task := NewTaskOfConfig(name, TaskConfig{
+		Trigger:  NewOnceTrigger(t),
+		Executor: NewExecutor(0, 0, 1),
+		Handler:  handler,
+		Enable:   enable,
+	})
+	zapp.App().InjectService(nowServiceType, task)
+}
+
+// 注册自定义task
+//
+// 使用依赖触发器(NewDependTrigger)的任务只支持单实例部署, 汇合状态只保存在当前实例中,
+// 设置了分布式锁(SetLocker)时会注册失败, 参考 DependTriggerWithLocker
+func RegistryTask(task ITask) {
+	zapp.App().InjectService(nowServiceType, task)
+}
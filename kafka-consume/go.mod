@@ -1,10 +1,10 @@
-module github.com/zly-app/service/kafka-consume
-
-go 1.16
-
-require (
-	github.com/Shopify/sarama v1.29.1
-	github.com/zly-app/zapp v1.1.1
-	github.com/zlyuancn/zutils v0.0.0-20210818071406-950ed323e3a0
-	go.uber.org/zap v1.16.0
-)
+module github.com/zly-app/service/kafka-consume
+
+go 1.16
+
+require (
+	github.com/Shopify/sarama v1.29.1
+	github.com/zly-app/zapp v1.1.1
+	github.com/zlyuancn/zutils v0.0.0-20210818071406-950ed323e3a0
+	go.uber.org/zap v1.16.0
+)
@@ -1,12 +1,12 @@
-module github.com/zly-app/service/mysql-binlog
-
-go 1.15
-
-require (
-	github.com/go-mysql-org/go-mysql v1.3.0
-	github.com/json-iterator/go v1.1.11
-	github.com/twpayne/go-geom v1.4.0
-	github.com/zly-app/zapp v1.1.1
-	github.com/zlyuancn/zstr v0.0.0-20210820024718-ae79a4fc86c3
-	go.uber.org/zap v1.16.0
-)
+module github.com/zly-app/service/mysql-binlog
+
+go 1.15
+
+require (
+	github.com/go-mysql-org/go-mysql v1.3.0
+	github.com/json-iterator/go v1.1.11
+	github.com/twpayne/go-geom v1.4.0
+	github.com/zly-app/zapp v1.1.1
+	github.com/zlyuancn/zstr v0.0.0-20210820024718-ae79a4fc86c3
+	go.uber.org/zap v1.16.0
+)
@@ -1,10 +1,10 @@
-module github.com/zly-app/service/nsq-consume
-
-go 1.15
-
-require (
-	github.com/nsqio/go-nsq v1.0.8
-	github.com/zly-app/zapp v1.1.1
-	github.com/zlyuancn/zutils v0.0.0-20210122082914-844d7aa8324a
-	go.uber.org/zap v1.16.0
-)
+module github.com/zly-app/service/nsq-consume
+
+go 1.15
+
+require (
+	github.com/nsqio/go-nsq v1.0.8
+	github.com/zly-app/zapp v1.1.1
+	github.com/zlyuancn/zutils v0.0.0-20210122082914-844d7aa8324a
+	go.uber.org/zap v1.16.0
+)
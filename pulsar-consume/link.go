@@ -1,9 +1,9 @@
-package pulsar_consume
-
-import (
-	"github.com/apache/pulsar-client-go/pulsar"
-)
-
-type (
-	Message = pulsar.Message
-)
+package pulsar_consume
+
+import (
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+type (
+	Message = pulsar.Message
+)
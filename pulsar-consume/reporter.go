@@ -0,0 +1,34 @@
+package pulsar_consume
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zly-app/zapp/pkg/utils"
+)
+
+// 错误上报器
+//
+// 方法和 github.com/zly-app/service/api/reporter.IReporter 相同, 可以和api服务使用同一个上报器
+type ErrorReporter interface {
+	Report(ctx context.Context, err error, stack string, fields map[string]string)
+}
+
+// 上报错误, 没有设置上报器时忽略
+func reportError(ctx context.Context, r ErrorReporter, err error, fields map[string]string) {
+	if r == nil || err == nil {
+		return
+	}
+
+	kind, stack := "error", ""
+	if _, ok := utils.Recover.GetRecoverError(err); ok {
+		kind = "panic"
+		detail := utils.Recover.GetRecoverErrorDetail(err)
+		if i := strings.IndexByte(detail, '\n'); i != -1 {
+			stack = detail[i+1:]
+		}
+	}
+	fields["service"] = string(nowServiceType)
+	fields["kind"] = kind
+	r.Report(ctx, err, stack, fields)
+}